@@ -1,19 +1,76 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	
+	"compify-backend/internal/config"
 	"compify-backend/internal/server"
 )
 
 func main() {
-	log.Println("Starting Compify backend...")
+	// Load configuration from the config file, environment and flags
+	cfg, options, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	
+	// Print the configuration with secrets redacted, including any problems
+	if options.PrintConfig {
+		out, yamlErr := cfg.YAML()
+		if yamlErr != nil {
+			fmt.Fprintln(os.Stderr, "Failed to render configuration:", yamlErr)
+			os.Exit(1)
+		}
+		fmt.Printf("# Sources: %v\n%s", options.Sources, out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	
+	// Refuse to start with an invalid configuration
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
 	
-	// Create and start the server
-	srv := server.NewServer()
+	// Cancel the server context on SIGINT or SIGTERM to shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	
+	// Create and start the server; this also configures logging
+	srv := server.New(cfg)
+	
+	// Print the route table, e.g. for the API docs
+	if options.PrintRoutes {
+		if err := server.WriteRouteTable(os.Stdout, srv.Routes()); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to print routes:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	
+	// Import accounts from a legacy system, keeping their password hashes
+	if options.ImportUsers != "" {
+		imported, skipped, err := srv.ImportUsers(ctx, options.ImportUsers)
+		if err != nil {
+			slog.Error("Failed to import users", "file", options.ImportUsers, "imported", imported, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Imported users", "file", options.ImportUsers, "imported", imported, "skipped", skipped)
+	}
 	
-	log.Println("Server created, starting...")
-	if err := srv.Start(); err != nil {
-		log.Fatal("Failed to start server:", err)
+	slog.Info("Server created, starting...", "config_file", options.File, "config_sources", options.Sources)
+	if err := srv.Run(ctx); err != nil {
+		slog.Error("Server stopped with error", "error", err)
+		os.Exit(1)
 	}
 }
\ No newline at end of file
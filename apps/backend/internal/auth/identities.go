@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExternalIdentity is an account at an OpenID Connect provider, as reported
+// by a verified ID token
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// LoginWithIdentity signs a user in with an external identity and creates a
+// session. An identity that is not linked yet is linked to the user with the
+// same email address, or to a new user without a password when there is
+// none. Either requires the provider to have verified the email, since
+// anyone can claim an unverified address at some provider. Registering with
+// a password never verified the address, so such a user is claimed first.
+func (s *Service) LoginWithIdentity(ctx context.Context, external *ExternalIdentity, ipAddress, userAgent string) (*models.User, *models.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.LoginWithIdentity")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	span.SetAttributes(tracing.String("auth.provider", external.Provider))
+	now := time.Now()
+
+	var user *models.User
+	identity, err := repos.Identities.GetByProviderSubject(external.Provider, external.Subject)
+	switch {
+	case err == nil:
+		if user, err = repos.Users.GetByID(identity.UserID); err != nil {
+			return nil, nil, err
+		}
+		identity.Email = external.Email
+		identity.LastLoginAt = now
+		if err := repos.Identities.Update(identity); err != nil {
+			return nil, nil, fmt.Errorf("failed to update identity: %w", err)
+		}
+	case errors.Is(err, models.ErrIdentityNotFound):
+		if !external.EmailVerified {
+			return nil, nil, models.ErrEmailNotVerified
+		}
+		user, err = repos.Users.GetByEmail(strings.ToLower(external.Email))
+		switch {
+		case errors.Is(err, models.ErrUserNotFound):
+			user, err = s.createPasswordlessUser(ctx, external.Email, external.FirstName, external.LastName)
+		case err == nil && user.PasswordHash != "":
+			err = s.claimAccount(ctx, user)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		identity = &models.Identity{
+			UserID:      user.ID,
+			Provider:    external.Provider,
+			Subject:     external.Subject,
+			Email:       external.Email,
+			LinkedAt:    now,
+			LastLoginAt: now,
+		}
+		if err := repos.Identities.Create(identity); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, err
+	}
+
+	session, err := s.passwordlessSession(ctx, user, ipAddress, userAgent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	span.SetAttributes(tracing.String("auth.result", loginSuccess))
+	return user, session, nil
+}
+
+// claimAccount hands a user registered with a password over to the owner of
+// its email address, who has just proved it. Whoever registered the address
+// may not be its owner, so the password is cleared and the user's sessions,
+// access tokens and other linked identities are removed.
+func (s *Service) claimAccount(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "auth.claimAccount")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	user.PasswordHash = ""
+	if err := repos.Users.Update(user); err != nil {
+		return fmt.Errorf("failed to clear password: %w", err)
+	}
+	if err := repos.Sessions.DeleteByUserID(user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	tokens, err := repos.AccessTokens.GetByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := repos.AccessTokens.Delete(token.ID); err != nil && !errors.Is(err, models.ErrAccessTokenNotFound) {
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+
+	identities, err := repos.Identities.GetByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		if err := repos.Identities.Delete(identity.ID); err != nil && !errors.Is(err, models.ErrIdentityNotFound) {
+			return fmt.Errorf("failed to unlink identity: %w", err)
+		}
+	}
+	return nil
+}
+
+// passwordlessSession creates a session for a user who signed in without a
+// password, granting the admin role to configured admins first
+func (s *Service) passwordlessSession(ctx context.Context, user *models.User, ipAddress, userAgent string) (*models.Session, error) {
+	repos := s.repos.WithContext(ctx)
+
+	// Promote configured admins before issuing the session, since role
+	// changes revoke existing sessions
+	if s.isAdminEmail(user.Email) && !user.IsAdmin() {
+		if _, err := s.SetRole(ctx, user.ID, models.RoleAdmin, ""); err != nil {
+			return nil, fmt.Errorf("failed to grant admin role: %w", err)
+		}
+		user.Role = models.RoleAdmin
+	}
+
+	// Create session
+	session, err := models.NewSessionWithPolicy(user.ID, ipAddress, userAgent, s.sessionPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	if err := repos.Sessions.Create(session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	s.metrics.LoginAttempts.Inc(loginSuccess)
+	return session, nil
+}
+
+// createPasswordlessUser registers a user without a password, who signs in
+// with a linked identity or emailed links, deriving the username from the
+// email address
+func (s *Service) createPasswordlessUser(ctx context.Context, email, firstName, lastName string) (*models.User, error) {
+	repos := s.repos.WithContext(ctx)
+
+	username, err := s.availableUsername(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:    strings.ToLower(email),
+		Username: username,
+	}
+	if s.isAdminEmail(user.Email) {
+		user.Role = models.RoleAdmin
+	}
+
+	if err := repos.Users.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	// Creating the user stores an empty profile; fill in the provider's names
+	profile := models.Profile{
+		UserID:    user.ID,
+		FirstName: firstName,
+		LastName:  lastName,
+	}
+	if err := repos.Users.UpdateProfile(&profile); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	user.Profile = profile
+
+	s.metrics.Registrations.Inc()
+	return user, nil
+}
+
+// availableUsername derives an unused username from the local part of an
+// email address, appending a number when it is taken
+func (s *Service) availableUsername(ctx context.Context, email string) (string, error) {
+	repos := s.repos.WithContext(ctx)
+
+	local, _, _ := strings.Cut(email, "@")
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r == '.' || r == '+':
+			return '_'
+		}
+		return -1
+	}, local)
+	for len(base) < 3 {
+		base += "_"
+	}
+	if len(base) > 24 {
+		base = base[:24]
+	}
+
+	for i := 1; i < 1000; i++ {
+		username := base
+		if i > 1 {
+			username += strconv.Itoa(i)
+		}
+		if _, err := repos.Users.GetByUsername(username); errors.Is(err, models.ErrUserNotFound) {
+			return username, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	return "", models.ErrUsernameTaken
+}
+
+// LinkIdentity links an external identity to a signed in user. The email
+// does not need to match the user's, nor be verified, since the user proved
+// control of both accounts.
+func (s *Service) LinkIdentity(ctx context.Context, userID string, external *ExternalIdentity) (*models.Identity, error) {
+	ctx, span := tracing.Start(ctx, "auth.LinkIdentity")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	if existing, err := repos.Identities.GetByProviderSubject(external.Provider, external.Subject); err == nil {
+		if existing.UserID != userID {
+			return nil, models.ErrIdentityLinked
+		}
+		return existing, nil
+	}
+
+	identity := &models.Identity{
+		UserID:   userID,
+		Provider: external.Provider,
+		Subject:  external.Subject,
+		Email:    external.Email,
+		LinkedAt: time.Now(),
+	}
+	if err := repos.Identities.Create(identity); err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+// ListIdentities returns the identities linked to a user, ordered by provider
+func (s *Service) ListIdentities(ctx context.Context, userID string) ([]*models.Identity, error) {
+	ctx, span := tracing.Start(ctx, "auth.ListIdentities")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	identities, err := repos.Identities.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].Provider < identities[j].Provider
+	})
+
+	return identities, nil
+}
+
+// UnlinkIdentity removes one of the user's linked identities by ID. The last
+// identity of a user without a password cannot be unlinked, as that would
+// leave no way to sign in. Identities belonging to other users are reported
+// as not found.
+func (s *Service) UnlinkIdentity(ctx context.Context, userID, identityID string) error {
+	ctx, span := tracing.Start(ctx, "auth.UnlinkIdentity")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	user, err := repos.Users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	identities, err := repos.Identities.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, identity := range identities {
+		if identity.ID != identityID {
+			continue
+		}
+		if user.PasswordHash == "" && len(identities) == 1 {
+			return models.ErrLastSignInMethod
+		}
+		return repos.Identities.Delete(identity.ID)
+	}
+
+	return models.ErrIdentityNotFound
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"bufio"
+	"compify-backend/internal/models"
+	"compify-backend/internal/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ImportedUser is an account carried over from another system, with its
+// password hash as stored there
+type ImportedUser struct {
+	Email        string `json:"email"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"` // Argon2id or bcrypt
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+}
+
+// ImportUser creates a user with an existing password hash. bcrypt hashes
+// are accepted so that users of a legacy system keep their passwords; they
+// are replaced with Argon2id hashes at the first login. Hashes too
+// expensive to verify are refused with ErrPasswordHashTooCostly.
+func (s *Service) ImportUser(ctx context.Context, imported *ImportedUser) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "auth.ImportUser")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	var errs []*models.Error
+	if imported.Email == "" {
+		errs = append(errs, models.ErrEmailRequired)
+	}
+	if imported.Username == "" {
+		errs = append(errs, models.ErrUsernameRequired)
+	}
+	switch _, err := s.parseStoredHash(imported.PasswordHash); {
+	case errors.Is(err, ErrPasswordHashTooCostly):
+		errs = append(errs, ErrPasswordHashTooCostly)
+	case err != nil:
+		errs = append(errs, ErrInvalidPasswordHash)
+	}
+	if err := models.NewValidationError(errs...); err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:        strings.TrimSpace(imported.Email),
+		Username:     strings.TrimSpace(imported.Username),
+		PasswordHash: imported.PasswordHash,
+	}
+	if err := repos.Users.Create(user); err != nil {
+		return nil, err
+	}
+
+	// Update profile with names
+	user.Profile = models.Profile{
+		UserID:    user.ID,
+		FirstName: imported.FirstName,
+		LastName:  imported.LastName,
+	}
+	if err := repos.Users.UpdateProfile(&user.Profile); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return user, nil
+}
+
+// ImportUsers imports users from JSON Lines, one ImportedUser per line.
+// Users whose email address is already registered are skipped, so that an
+// import can be repeated; any other problem stops the import and is
+// reported with its line number.
+func (s *Service) ImportUsers(ctx context.Context, r io.Reader) (imported, skipped int, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ImportUsers")
+	defer span.End()
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var user ImportedUser
+		decoder := json.NewDecoder(strings.NewReader(scanner.Text()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&user); err != nil {
+			return imported, skipped, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		_, err := s.ImportUser(ctx, &user)
+		switch {
+		case errors.Is(err, models.ErrEmailTaken):
+			skipped++
+		case err != nil:
+			return imported, skipped, fmt.Errorf("line %d: %w", line, err)
+		default:
+			imported++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return imported, skipped, err
+	}
+
+	span.SetAttributes(tracing.Int("auth.imported", imported), tracing.Int("auth.skipped", skipped))
+	return imported, skipped, nil
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginLinkPolicy limits how many sign-in links can be requested for one
+// email address, so that the form cannot be used to flood an inbox
+type LoginLinkPolicy struct {
+	MaxRequests int           // Requests allowed within Window; 0 disables the limit
+	Window      time.Duration // Period over which requests are counted
+}
+
+// DefaultLoginLinkPolicy allows 3 sign-in links per address every 15 minutes
+var DefaultLoginLinkPolicy = LoginLinkPolicy{
+	MaxRequests: 3,
+	Window:      15 * time.Minute,
+}
+
+// SetLoginLinkPolicy configures how many sign-in links can be requested for
+// one email address
+func (s *Service) SetLoginLinkPolicy(policy LoginLinkPolicy) {
+	s.linkThrottle = newLinkThrottle(policy)
+}
+
+// RequestLoginLink creates a sign-in link for an email address, bound to the
+// browser holding browserToken. Links are only created for existing users,
+// or for new ones when the competition they were requested for allows
+// signing up this way; otherwise the returned link is nil, and callers
+// should answer as if it had been sent. Every request counts against the
+// address's limit, whether or not a link is created.
+func (s *Service) RequestLoginLink(ctx context.Context, email, competitionID, browserToken string) (*models.LoginLink, error) {
+	ctx, span := tracing.Start(ctx, "auth.RequestLoginLink")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	link, err := models.NewLoginLink(email, browserToken, competitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !s.linkThrottle.allow(link.Email, now) {
+		span.SetAttributes(tracing.String("auth.result", "throttled"))
+		return nil, models.ErrLoginLinkThrottled
+	}
+
+	if _, err := repos.Users.GetByEmail(link.Email); errors.Is(err, models.ErrUserNotFound) {
+		if !s.linkSignupAllowed(ctx, competitionID, now) {
+			span.SetAttributes(tracing.String("auth.result", "no_account"))
+			return nil, nil
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := repos.LoginLinks.Create(link); err != nil {
+		return nil, fmt.Errorf("failed to save sign-in link: %w", err)
+	}
+
+	span.SetAttributes(tracing.String("auth.result", "sent"))
+	return link, nil
+}
+
+// LoginWithLink signs a user in with an emailed link and creates a session.
+// The link must be used in the browser that requested it, which is checked
+// before it is used up, so that a mail scanner following it does not spoil
+// it. A user is created for an address without one when the link's
+// competition still allows signing up.
+func (s *Service) LoginWithLink(ctx context.Context, token, browserToken, ipAddress, userAgent string) (*models.User, *models.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.LoginWithLink")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	now := time.Now()
+	link, err := repos.LoginLinks.GetByToken(token)
+	if err != nil || !link.Usable(now) {
+		s.metrics.LoginAttempts.Inc(loginFailure)
+		return nil, nil, models.ErrLoginLinkInvalid
+	}
+	if !link.MatchesBrowser(browserToken) {
+		s.metrics.LoginAttempts.Inc(loginFailure)
+		return nil, nil, models.ErrLoginLinkOtherBrowser
+	}
+	if err := repos.LoginLinks.MarkUsed(link.ID, now); err != nil {
+		s.metrics.LoginAttempts.Inc(loginFailure)
+		return nil, nil, models.ErrLoginLinkInvalid
+	}
+
+	user, err := repos.Users.GetByEmail(link.Email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		if !s.linkSignupAllowed(ctx, link.CompetitionID, now) {
+			return nil, nil, models.ErrLoginLinkInvalid
+		}
+		user, err = s.createPasswordlessUser(ctx, link.Email, "", "")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, err := s.passwordlessSession(ctx, user, ipAddress, userAgent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	span.SetAttributes(tracing.String("auth.result", loginSuccess))
+	return user, session, nil
+}
+
+// linkSignupAllowed reports whether a sign-in link requested for the
+// competition may create an account
+func (s *Service) linkSignupAllowed(ctx context.Context, competitionID string, now time.Time) bool {
+	if competitionID == "" {
+		return false
+	}
+	competition, err := s.repos.WithContext(ctx).Competitions.GetByID(competitionID)
+	return err == nil && competition.AllowsLinkSignup(now)
+}
+
+// linkThrottle counts recent sign-in link requests per email address
+type linkThrottle struct {
+	policy   LoginLinkPolicy
+	requests map[string][]time.Time
+	pruned   time.Time
+	mutex    sync.Mutex
+}
+
+// newLinkThrottle creates a throttle with the given policy
+func newLinkThrottle(policy LoginLinkPolicy) *linkThrottle {
+	return &linkThrottle{
+		policy:   policy,
+		requests: make(map[string][]time.Time),
+	}
+}
+
+// allow records a request for the address and reports whether it is within
+// the limit. Refused requests are not recorded, so the limit frees up as
+// the earlier requests leave the window.
+func (t *linkThrottle) allow(email string, now time.Time) bool {
+	if t.policy.MaxRequests <= 0 {
+		return true
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// Drop addresses without recent requests once per window, so that the
+	// map does not grow with every address ever entered
+	if now.Sub(t.pruned) >= t.policy.Window {
+		for key, times := range t.requests {
+			if now.Sub(times[len(times)-1]) >= t.policy.Window {
+				delete(t.requests, key)
+			}
+		}
+		t.pruned = now
+	}
+
+	key := normalizeEmail(email)
+	recent := t.requests[key][:0]
+	for _, at := range t.requests[key] {
+		if now.Sub(at) < t.policy.Window {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= t.policy.MaxRequests {
+		t.requests[key] = recent
+		return false
+	}
+	t.requests[key] = append(recent, now)
+	return true
+}
+
+// normalizeEmail returns the key used to track an email address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// Test per-address throttling of sign-in link requests
+func TestLinkThrottle(t *testing.T) {
+	throttle := newLinkThrottle(LoginLinkPolicy{MaxRequests: 2, Window: time.Minute})
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if !throttle.allow("ada@example.com", now) {
+			t.Fatalf("Request %d should be allowed", i+1)
+		}
+	}
+	if throttle.allow(" ADA@example.com", now.Add(30*time.Second)) {
+		t.Error("Expected the address to be throttled regardless of case and spacing")
+	}
+	if !throttle.allow("grace@example.com", now.Add(30*time.Second)) {
+		t.Error("Addresses must be throttled separately")
+	}
+	if !throttle.allow("ada@example.com", now.Add(time.Minute)) {
+		t.Error("Expected requests to be allowed once the earlier ones leave the window")
+	}
+
+	// Addresses without recent requests are forgotten
+	throttle.allow("linus@example.com", now.Add(3*time.Minute))
+	if len(throttle.requests) != 1 {
+		t.Errorf("Expected idle addresses to be pruned, got %d", len(throttle.requests))
+	}
+}
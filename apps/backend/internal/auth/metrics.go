@@ -0,0 +1,35 @@
+package auth
+
+import "compify-backend/internal/metrics"
+
+// Login attempt results recorded in metrics
+const (
+	loginSuccess = "success"
+	loginFailure = "failure"
+)
+
+// Metrics counts authentication outcomes
+type Metrics struct {
+	LoginAttempts *metrics.CounterVec
+	Lockouts      *metrics.CounterVec // Sign-in requests refused by rate limiting
+	Registrations *metrics.CounterVec
+
+	// Password hashes upgraded at login, by the scheme they had
+	PasswordRehashes *metrics.CounterVec
+}
+
+// newMetrics creates the authentication metrics
+func newMetrics() *Metrics {
+	return &Metrics{
+		LoginAttempts: metrics.NewCounterVec("compify_auth_login_attempts_total", "Login attempts by result (success, failure).", "result"),
+		Lockouts:      metrics.NewCounterVec("compify_auth_lockouts_total", "Sign-in requests refused because the client made too many."),
+		Registrations: metrics.NewCounterVec("compify_auth_registrations_total", "Successful user registrations."),
+
+		PasswordRehashes: metrics.NewCounterVec("compify_auth_password_rehashes_total", "Password hashes upgraded at login by previous scheme (argon2id, bcrypt).", "scheme"),
+	}
+}
+
+// Collectors returns the metrics for registration with a metrics registry
+func (m *Metrics) Collectors() []metrics.Collector {
+	return []metrics.Collector{m.LoginAttempts, m.Lockouts, m.Registrations, m.PasswordRehashes}
+}
@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/tracing"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hash schemes, as recorded in metrics
+const (
+	schemeArgon2id = "argon2id"
+	schemeBcrypt   = "bcrypt"
+)
+
+// Password hashing parameters that are not configurable
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// minPepperLength is the shortest pepper key accepted, in bytes
+const minPepperLength = 16
+
+// Limits on the cost of stored hashes, so that an imported hash with huge
+// parameters cannot make a login allocate gigabytes or spin the CPU.
+// Argon2id parameters may be at most maxCostFactor times the configured
+// ones, or the defaults when those are higher, so that lowering the policy
+// does not lock out users with older hashes.
+const (
+	maxCostFactor = 4
+	maxBcryptCost = 14 // Legacy systems typically use 10 to 12
+)
+
+// PasswordPolicy sets the Argon2id cost of new password hashes and the
+// peppers mixed into them. Stored hashes keep the parameters they were made
+// with and are rehashed at the next login when these are raised.
+type PasswordPolicy struct {
+	Time    uint32   // Passes over memory
+	Memory  uint32   // Memory in KiB
+	Threads uint8    // Degree of parallelism
+	Peppers []Pepper // The first hashes new passwords; the others only verify existing hashes
+}
+
+// DefaultPasswordPolicy hashes with Argon2id t=1, m=64 MiB, p=4 and no pepper
+var DefaultPasswordPolicy = PasswordPolicy{
+	Time:    1,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+// Pepper is a server-side secret mixed into password hashes, so that a
+// leaked user store alone is not enough to guess passwords. Hashes record
+// the ID of their pepper, so that it can be rotated: add the new pepper
+// first and keep the old one until its hashes have been upgraded.
+type Pepper struct {
+	ID  string
+	Key []byte
+}
+
+// pepperIDPattern matches IDs that fit in a PHC hash string parameter
+var pepperIDPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,32}$`)
+
+// ParsePepper parses a pepper given as id=base64key. Errors never include
+// the key.
+func ParsePepper(spec string) (Pepper, error) {
+	id, encoded, ok := strings.Cut(spec, "=")
+	if !ok || !pepperIDPattern.MatchString(id) {
+		return Pepper{}, errors.New("invalid pepper: use id=base64key with an ID of up to 32 letters, digits and dashes")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return Pepper{}, fmt.Errorf("pepper %s: key must be base64", id)
+	}
+	if len(key) < minPepperLength {
+		return Pepper{}, fmt.Errorf("pepper %s: key must be at least %d bytes", id, minPepperLength)
+	}
+	return Pepper{ID: id, Key: key}, nil
+}
+
+// SetPasswordPolicy configures how new passwords are hashed, and which
+// stored hashes are upgraded at login
+func (s *Service) SetPasswordPolicy(policy PasswordPolicy) {
+	s.passwordPolicy = policy
+}
+
+// Errors returned when importing a user whose password hash is neither
+// Argon2id nor bcrypt, or too expensive to verify
+var (
+	ErrInvalidPasswordHash   = &models.Error{Kind: models.KindInvalid, Code: "password_hash_invalid", Field: "password_hash", Message: "Password hash must be an Argon2id or bcrypt hash"}
+	ErrPasswordHashTooCostly = &models.Error{Kind: models.KindInvalid, Code: "password_hash_too_costly", Field: "password_hash", Message: "Password hash parameters exceed the cost limit"}
+)
+
+// passwordHash is a parsed stored password hash
+type passwordHash struct {
+	scheme   string
+	cost     int // bcrypt only
+	time     uint32
+	memory   uint32
+	threads  uint8
+	pepperID string
+	salt     []byte
+	key      []byte
+}
+
+// parsePasswordHash parses an Argon2id hash in PHC format, optionally with
+// a keyid parameter naming its pepper, or a bcrypt hash:
+//
+//	$argon2id$v=19$m=65536,t=1,p=4$salt$hash
+//	$argon2id$v=19$m=65536,t=1,p=4,keyid=2024$salt$hash
+//	$2b$10$saltandhash
+func parsePasswordHash(encoded string) (*passwordHash, error) {
+	if strings.HasPrefix(encoded, "$2") {
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return nil, ErrInvalidPasswordHash
+		}
+		return &passwordHash{scheme: schemeBcrypt, cost: cost}, nil
+	}
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != schemeArgon2id || parts[2] != "v=19" {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	h := &passwordHash{scheme: schemeArgon2id}
+	seen := make(map[string]bool)
+	for _, param := range strings.Split(parts[3], ",") {
+		name, value, _ := strings.Cut(param, "=")
+		if seen[name] {
+			return nil, ErrInvalidPasswordHash
+		}
+		seen[name] = true
+
+		var err error
+		var n uint64
+		switch name {
+		case "m":
+			n, err = strconv.ParseUint(value, 10, 32)
+			h.memory = uint32(n)
+		case "t":
+			n, err = strconv.ParseUint(value, 10, 32)
+			h.time = uint32(n)
+		case "p":
+			n, err = strconv.ParseUint(value, 10, 8)
+			h.threads = uint8(n)
+		case "keyid":
+			if !pepperIDPattern.MatchString(value) {
+				err = ErrInvalidPasswordHash
+			}
+			h.pepperID = value
+		default:
+			err = ErrInvalidPasswordHash
+		}
+		if err != nil {
+			return nil, ErrInvalidPasswordHash
+		}
+	}
+	if !seen["m"] || h.time < 1 || h.threads < 1 {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrInvalidPasswordHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, ErrInvalidPasswordHash
+	}
+	return h, nil
+}
+
+// parseStoredHash parses a stored password hash and checks that it is not
+// too expensive to verify
+func (s *Service) parseStoredHash(encoded string) (*passwordHash, error) {
+	h, err := parsePasswordHash(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.scheme == schemeBcrypt {
+		if h.cost > maxBcryptCost {
+			return nil, ErrPasswordHashTooCostly
+		}
+		return h, nil
+	}
+
+	limit := func(configured, fallback uint32) uint64 {
+		return uint64(max(configured, fallback)) * maxCostFactor
+	}
+	policy := s.passwordPolicy
+	if uint64(h.time) > limit(policy.Time, DefaultPasswordPolicy.Time) ||
+		uint64(h.memory) > limit(policy.Memory, DefaultPasswordPolicy.Memory) ||
+		uint64(h.threads) > limit(uint32(policy.Threads), uint32(DefaultPasswordPolicy.Threads)) {
+		return nil, ErrPasswordHashTooCostly
+	}
+	return h, nil
+}
+
+// pepper mixes the pepper with the given ID into a password, reporting
+// false when no such pepper is configured. Passwords hashed without a
+// pepper are used as they are.
+func (s *Service) pepper(id, password string) ([]byte, bool) {
+	if id == "" {
+		return []byte(password), true
+	}
+	for _, pepper := range s.passwordPolicy.Peppers {
+		if pepper.ID == id {
+			mac := hmac.New(sha256.New, pepper.Key)
+			mac.Write([]byte(password))
+			return mac.Sum(nil), true
+		}
+	}
+	return nil, false
+}
+
+// hashPassword hashes a password using Argon2id with the configured
+// parameters and current pepper
+func (s *Service) hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "auth.hashPassword")
+	defer span.End()
+
+	policy := s.passwordPolicy
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", policy.Memory, policy.Time, policy.Threads)
+	var pepperID string
+	if len(policy.Peppers) > 0 {
+		pepperID = policy.Peppers[0].ID
+		params += ",keyid=" + pepperID
+	}
+	input, _ := s.pepper(pepperID, password)
+
+	// Generate salt
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	// Hash password
+	hash := argon2.IDKey(input, salt, policy.Time, policy.Memory, policy.Threads, keyLength)
+
+	// Encode to base64
+	saltB64 := base64.RawStdEncoding.EncodeToString(salt)
+	hashB64 := base64.RawStdEncoding.EncodeToString(hash)
+
+	return fmt.Sprintf("$argon2id$v=19$%s$%s$%s", params, saltB64, hashB64), nil
+}
+
+// verifyPassword verifies a password against an Argon2id or bcrypt hash
+func (s *Service) verifyPassword(ctx context.Context, password, hash string) bool {
+	_, span := tracing.Start(ctx, "auth.verifyPassword")
+	defer span.End()
+
+	parsed, err := s.parseStoredHash(hash)
+	if err != nil {
+		span.RecordError(err)
+		return false
+	}
+	span.SetAttributes(tracing.String("auth.password_scheme", parsed.scheme))
+
+	if parsed.scheme == schemeBcrypt {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	input, ok := s.pepper(parsed.pepperID, password)
+	if !ok {
+		return false
+	}
+
+	// Hash the provided password with the stored parameters
+	actualHash := argon2.IDKey(input, parsed.salt, parsed.time, parsed.memory, parsed.threads, uint32(len(parsed.key)))
+
+	// Compare hashes using constant-time comparison
+	return subtle.ConstantTimeCompare(parsed.key, actualHash) == 1
+}
+
+// needsRehash reports whether a verified hash should be replaced, because
+// it is a bcrypt hash, is cheaper than the configured parameters or uses
+// another pepper than the current one. It also returns the hash's scheme.
+func (s *Service) needsRehash(hash string) (string, bool) {
+	parsed, err := s.parseStoredHash(hash)
+	if err != nil {
+		return "", false
+	}
+	if parsed.scheme != schemeArgon2id {
+		return parsed.scheme, true
+	}
+
+	policy := s.passwordPolicy
+	var pepperID string
+	if len(policy.Peppers) > 0 {
+		pepperID = policy.Peppers[0].ID
+	}
+	weaker := parsed.time < policy.Time || parsed.memory < policy.Memory || len(parsed.key) < keyLength
+	return parsed.scheme, weaker || parsed.pepperID != pepperID
+}
+
+// upgradePasswordHash rehashes a user's password after a successful login
+// when needsRehash asks for it. Failures are recorded on the trace but do
+// not fail the login; the old hash keeps working.
+func (s *Service) upgradePasswordHash(ctx context.Context, user *models.User, password string) {
+	scheme, rehash := s.needsRehash(user.PasswordHash)
+	if !rehash {
+		return
+	}
+
+	ctx, span := tracing.Start(ctx, "auth.upgradePasswordHash")
+	defer span.End()
+	span.SetAttributes(tracing.String("auth.password_scheme", scheme))
+
+	hash, err := s.hashPassword(ctx, password)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	previous := user.PasswordHash
+	user.PasswordHash = hash
+	if err := s.repos.WithContext(ctx).Users.Update(user); err != nil {
+		user.PasswordHash = previous
+		span.RecordError(err)
+		return
+	}
+
+	s.metrics.PasswordRehashes.Inc(scheme)
+}
@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheapPolicy keeps hashing fast in tests
+var cheapPolicy = PasswordPolicy{Time: 1, Memory: 64, Threads: 1}
+
+func mustPepper(t testing.TB, spec string) Pepper {
+	t.Helper()
+	pepper, err := ParsePepper(spec)
+	if err != nil {
+		t.Fatalf("ParsePepper(%q): %v", spec, err)
+	}
+	return pepper
+}
+
+// Test hashing and verifying passwords across parameter changes, pepper
+// rotation and legacy bcrypt hashes
+func TestPasswordHashing(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(repository.NewRepositories())
+	service.SetPasswordPolicy(cheapPolicy)
+	old := mustPepper(t, "2023=b2xkLXBlcHBlci1rZXktMTIzNDU2")
+	current := mustPepper(t, "2024=bmV3LXBlcHBlci1rZXktMTIzNDU2")
+
+	plain, _ := service.hashPassword(ctx, "correct horse")
+	if !strings.HasPrefix(plain, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("Unexpected hash format %q", plain)
+	}
+	if !service.verifyPassword(ctx, "correct horse", plain) || service.verifyPassword(ctx, "wrong horse", plain) {
+		t.Fatal("Expected only the right password to verify")
+	}
+
+	t.Run("parameters", func(t *testing.T) {
+		if _, rehash := service.needsRehash(plain); rehash {
+			t.Error("Expected a hash with the current parameters to be kept")
+		}
+
+		service.SetPasswordPolicy(PasswordPolicy{Time: 2, Memory: 64, Threads: 1})
+		defer service.SetPasswordPolicy(cheapPolicy)
+		if !service.verifyPassword(ctx, "correct horse", plain) {
+			t.Error("Expected a hash to verify with the parameters it was made with")
+		}
+		if scheme, rehash := service.needsRehash(plain); !rehash || scheme != schemeArgon2id {
+			t.Errorf("Expected a cheaper argon2id hash to be upgraded, got %q %v", scheme, rehash)
+		}
+	})
+
+	t.Run("peppers", func(t *testing.T) {
+		service.SetPasswordPolicy(PasswordPolicy{Time: 1, Memory: 64, Threads: 1, Peppers: []Pepper{old}})
+		peppered, _ := service.hashPassword(ctx, "correct horse")
+		if !strings.Contains(peppered, ",keyid=2023$") || !service.verifyPassword(ctx, "correct horse", peppered) {
+			t.Fatalf("Expected a hash peppered with 2023, got %q", peppered)
+		}
+		if _, rehash := service.needsRehash(plain); !rehash {
+			t.Error("Expected an unpeppered hash to be upgraded once a pepper is configured")
+		}
+
+		// Rotating keeps the old pepper for verification only
+		service.SetPasswordPolicy(PasswordPolicy{Time: 1, Memory: 64, Threads: 1, Peppers: []Pepper{current, old}})
+		if !service.verifyPassword(ctx, "correct horse", peppered) {
+			t.Error("Expected a hash with a retired pepper to verify")
+		}
+		if _, rehash := service.needsRehash(peppered); !rehash {
+			t.Error("Expected a hash with a retired pepper to be upgraded")
+		}
+
+		// Without the pepper the hash is useless, like with a leaked store
+		service.SetPasswordPolicy(PasswordPolicy{Time: 1, Memory: 64, Threads: 1, Peppers: []Pepper{current}})
+		if service.verifyPassword(ctx, "correct horse", peppered) {
+			t.Error("Expected a hash with an unknown pepper not to verify")
+		}
+		service.SetPasswordPolicy(cheapPolicy)
+	})
+
+	t.Run("bcrypt", func(t *testing.T) {
+		legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+		for _, hash := range []string{string(legacy), "$2y" + string(legacy)[3:]} {
+			if !service.verifyPassword(ctx, "correct horse", hash) || service.verifyPassword(ctx, "wrong horse", hash) {
+				t.Errorf("Expected only the right password to verify against %q", hash)
+			}
+			if scheme, rehash := service.needsRehash(hash); !rehash || scheme != schemeBcrypt {
+				t.Errorf("Expected bcrypt hashes to be upgraded, got %q %v", scheme, rehash)
+			}
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, hash := range []string{
+			"",
+			"plaintext",
+			"$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=1,m=128$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=1,keyid=a$b$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",
+			"$2b$04$tooshort",
+		} {
+			if _, err := parsePasswordHash(hash); !errors.Is(err, ErrInvalidPasswordHash) {
+				t.Errorf("Expected %q to be refused, got %v", hash, err)
+			}
+			if service.verifyPassword(ctx, "", hash) {
+				t.Errorf("Expected nothing to verify against %q", hash)
+			}
+		}
+	})
+
+	t.Run("cost limits", func(t *testing.T) {
+		legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+		for _, hash := range []string{
+			"$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=4294967295,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=255$c2FsdA$a2V5",
+			strings.Replace(string(legacy), "$04$", "$31$", 1),
+		} {
+			if _, err := service.parseStoredHash(hash); !errors.Is(err, ErrPasswordHashTooCostly) {
+				t.Errorf("Expected %q to be refused as too costly, got %v", hash, err)
+			}
+			if service.verifyPassword(ctx, "correct horse", hash) {
+				t.Errorf("Expected nothing to verify against %q", hash)
+			}
+		}
+
+		// Lowering the policy keeps hashes made with the defaults usable
+		if _, err := service.parseStoredHash("$argon2id$v=19$m=65536,t=1,p=4$c2FsdA$a2V5"); err != nil {
+			t.Errorf("Expected a hash with the default parameters to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("pepper settings", func(t *testing.T) {
+		for _, spec := range []string{"2024", "=a2V5LWtleS1rZXkta2V5LWtleQ==", "has space=a2V5LWtleS1rZXkta2V5LWtleQ==", "2024=c2hvcnQ=", "2024=not base64"} {
+			if _, err := ParsePepper(spec); err == nil {
+				t.Errorf("Expected %q to be refused", spec)
+			}
+		}
+	})
+}
+
+// Test that logging in upgrades imported bcrypt hashes and hashes made with
+// weaker parameters
+func TestLoginUpgradesPasswordHash(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositories()
+	service := NewService(repos)
+	service.SetPasswordPolicy(cheapPolicy)
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	user, err := service.ImportUser(ctx, &ImportedUser{Email: "ada@example.com", Username: "ada", PasswordHash: string(legacy), FirstName: "Ada"})
+	if err != nil {
+		t.Fatalf("ImportUser: %v", err)
+	}
+	if user.Profile.FirstName != "Ada" {
+		t.Errorf("Expected the imported profile, got %+v", user.Profile)
+	}
+
+	login := func(password string) error {
+		_, _, err := service.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: password}, "127.0.0.1", "test")
+		return err
+	}
+	storedHash := func() string {
+		stored, _ := repos.Users.GetByEmail("ada@example.com")
+		return stored.PasswordHash
+	}
+
+	// A failed login leaves the hash alone
+	if err := login("wrong horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Expected invalid credentials, got %v", err)
+	}
+	if storedHash() != string(legacy) {
+		t.Fatal("Expected the hash to be kept after a failed login")
+	}
+
+	if err := login("correct horse"); err != nil {
+		t.Fatalf("Login with the legacy password: %v", err)
+	}
+	upgraded := storedHash()
+	if !strings.HasPrefix(upgraded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("Expected the bcrypt hash to be replaced, got %q", upgraded)
+	}
+
+	// Logging in again with the same settings keeps the hash
+	if err := login("correct horse"); err != nil || storedHash() != upgraded {
+		t.Fatalf("Expected the upgraded hash to be kept, got %q (%v)", storedHash(), err)
+	}
+
+	// Raising the cost upgrades it at the next login
+	service.SetPasswordPolicy(PasswordPolicy{Time: 2, Memory: 128, Threads: 1})
+	if err := login("correct horse"); err != nil {
+		t.Fatalf("Login after raising the cost: %v", err)
+	}
+	if !strings.HasPrefix(storedHash(), "$argon2id$v=19$m=128,t=2,p=1$") {
+		t.Errorf("Expected the hash to use the raised cost, got %q", storedHash())
+	}
+
+	if got := service.Metrics().PasswordRehashes.Value(schemeBcrypt); got != 1 {
+		t.Errorf("Expected 1 bcrypt rehash, got %v", got)
+	}
+	if got := service.Metrics().PasswordRehashes.Value(schemeArgon2id); got != 1 {
+		t.Errorf("Expected 1 argon2id rehash, got %v", got)
+	}
+}
+
+// Test importing users from JSON Lines
+func TestImportUsers(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositories()
+	service := NewService(repos)
+	service.SetPasswordPolicy(cheapPolicy)
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	argon, _ := service.hashPassword(ctx, "battery staple")
+	input := fmt.Sprintf(`{"email":"ada@example.com","username":"ada","password_hash":%q}
+
+{"email":"grace@example.com","username":"grace","password_hash":%q,"first_name":"Grace"}
+{"email":"ada@example.com","username":"ada2","password_hash":%q}
+`, legacy, argon, legacy)
+
+	imported, skipped, err := service.ImportUsers(ctx, strings.NewReader(input))
+	if err != nil || imported != 2 || skipped != 1 {
+		t.Fatalf("Expected 2 imported and 1 skipped, got %d, %d (%v)", imported, skipped, err)
+	}
+	if _, _, err := service.Login(ctx, &LoginRequest{Email: "grace@example.com", Password: "battery staple"}, "127.0.0.1", "test"); err != nil {
+		t.Errorf("Login as an imported user: %v", err)
+	}
+
+	for _, tc := range []struct {
+		input string
+		want  string
+	}{
+		{`{"email":"bob@example.com","username":"bob","password_hash":"plaintext"}`, "line 1: " + ErrInvalidPasswordHash.Message},
+		{`{"email":"bob@example.com","username":"bob","password_hash":"$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdA$a2V5"}`, "line 1: " + ErrPasswordHashTooCostly.Message},
+		{"\n" + `{"email":"bob@example.com","username":"bob","password":"x"}`, `line 2: json: unknown field "password"`},
+		{`{"email":"bob@example.com"`, "line 1: unexpected EOF"},
+	} {
+		_, _, err := service.ImportUsers(ctx, strings.NewReader(tc.input))
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("Expected %q for %s, got %v", tc.want, tc.input, err)
+		}
+	}
+	if _, err := repos.Users.GetByEmail("bob@example.com"); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("Expected no user from invalid lines, got %v", err)
+	}
+}
+
+// BenchmarkHashPassword measures Argon2id at a range of costs, to tune
+// PASSWORD_TIME, PASSWORD_MEMORY and PASSWORD_THREADS to the host. Aim for
+// a few hundred milliseconds per hash at most:
+//
+//	go test ./internal/auth -run '^$' -bench HashPassword -benchmem
+func BenchmarkHashPassword(b *testing.B) {
+	ctx := context.Background()
+	pepper := mustPepper(b, "bench=YmVuY2htYXJrLXBlcHBlci1rZXk=")
+	for _, policy := range []PasswordPolicy{
+		{Time: 1, Memory: 19 * 1024, Threads: 1},
+		{Time: 2, Memory: 19 * 1024, Threads: 1},
+		DefaultPasswordPolicy,
+		{Time: 2, Memory: 64 * 1024, Threads: 4},
+		{Time: 3, Memory: 64 * 1024, Threads: 4},
+		{Time: 1, Memory: 128 * 1024, Threads: 4},
+		{Time: 1, Memory: 64 * 1024, Threads: 4, Peppers: []Pepper{pepper}},
+	} {
+		name := fmt.Sprintf("t=%d,m=%dMiB,p=%d", policy.Time, policy.Memory/1024, policy.Threads)
+		if len(policy.Peppers) > 0 {
+			name += ",pepper"
+		}
+		b.Run(name, func(b *testing.B) {
+			service := NewService(repository.NewRepositories())
+			service.SetPasswordPolicy(policy)
+			for i := 0; i < b.N; i++ {
+				if _, err := service.hashPassword(ctx, "correct horse battery staple"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkVerifyBcrypt measures verifying imported bcrypt hashes at common
+// costs, which logins pay once before the hash is upgraded
+func BenchmarkVerifyBcrypt(b *testing.B) {
+	ctx := context.Background()
+	service := NewService(repository.NewRepositories())
+	for _, cost := range []int{10, 12} {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("correct horse battery staple"), cost)
+		b.Run(fmt.Sprintf("cost=%d", cost), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				service.verifyPassword(ctx, "correct horse battery staple", string(hash))
+			}
+		})
+	}
+}
@@ -3,29 +3,69 @@ package auth
 import (
 	"compify-backend/internal/models"
 	"compify-backend/internal/repository"
-	"crypto/rand"
-	"crypto/subtle"
-	"encoding/base64"
+	"compify-backend/internal/tracing"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
-
-	"golang.org/x/crypto/argon2"
+	"time"
 )
 
 // Service handles authentication operations
 type Service struct {
-	repos *repository.Repositories
+	repos          *repository.Repositories
+	sessionPolicy  models.SessionPolicy
+	passwordPolicy PasswordPolicy
+	adminEmails    map[string]bool
+	linkThrottle   *linkThrottle
+	metrics        *Metrics
 }
 
 // NewService creates a new authentication service
 func NewService(repos *repository.Repositories) *Service {
 	return &Service{
-		repos: repos,
+		repos:          repos,
+		sessionPolicy:  models.DefaultSessionPolicy,
+		passwordPolicy: DefaultPasswordPolicy,
+		linkThrottle:   newLinkThrottle(DefaultLoginLinkPolicy),
+		metrics:        newMetrics(),
+	}
+}
+
+// Metrics returns the service's authentication metrics
+func (s *Service) Metrics() *Metrics {
+	return s.metrics
+}
+
+// SetSessionPolicy configures the idle timeout and absolute lifetime of new
+// sessions. Zero durations keep the current values.
+func (s *Service) SetSessionPolicy(policy models.SessionPolicy) {
+	if policy.IdleTimeout > 0 {
+		s.sessionPolicy.IdleTimeout = policy.IdleTimeout
+	}
+	if policy.AbsoluteLifetime > 0 {
+		s.sessionPolicy.AbsoluteLifetime = policy.AbsoluteLifetime
 	}
 }
 
+// SetAdminEmails configures the email addresses that are granted the admin
+// role when they register or log in
+func (s *Service) SetAdminEmails(emails []string) {
+	s.adminEmails = make(map[string]bool, len(emails))
+	for _, email := range emails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			s.adminEmails[email] = true
+		}
+	}
+}
+
+// isAdminEmail reports whether the email is configured as an admin
+func (s *Service) isAdminEmail(email string) bool {
+	return s.adminEmails[strings.ToLower(email)]
+}
+
 // RegistrationRequest represents a user registration request
 type RegistrationRequest struct {
 	Email           string `json:"email"`
@@ -42,40 +82,36 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-// Validation errors
+// Validation errors. Registering an email or username that is in use fails
+// with models.ErrEmailTaken or models.ErrUsernameTaken.
 var (
-	ErrPasswordTooShort    = errors.New("password must be at least 8 characters long")
-	ErrPasswordsDoNotMatch = errors.New("passwords do not match")
-	ErrInvalidCredentials  = errors.New("invalid email or password")
-	ErrUserAlreadyExists   = errors.New("user already exists")
-)
-
-// Password hashing parameters
-const (
-	saltLength = 16
-	keyLength  = 32
-	time       = 1
-	memory     = 64 * 1024
-	threads    = 4
+	ErrPasswordRequired    = &models.Error{Kind: models.KindInvalid, Code: "password_required", Field: "password", Message: "Password is required"}
+	ErrPasswordTooShort    = &models.Error{Kind: models.KindInvalid, Code: "password_too_short", Field: "password", Message: "Password must be at least 8 characters long"}
+	ErrPasswordsDoNotMatch = &models.Error{Kind: models.KindInvalid, Code: "passwords_do_not_match", Field: "confirm_password", Message: "Passwords do not match"}
+	ErrInvalidCredentials  = &models.Error{Kind: models.KindUnauthorized, Code: "invalid_credentials", Message: "Invalid email or password"}
 )
 
 // Register registers a new user
-func (s *Service) Register(req *RegistrationRequest, ipAddress, userAgent string) (*models.User, *models.Session, error) {
+func (s *Service) Register(ctx context.Context, req *RegistrationRequest, ipAddress, userAgent string) (*models.User, *models.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.Register")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
 	// Validate registration request
 	if err := s.validateRegistrationRequest(req); err != nil {
 		return nil, nil, err
 	}
 
 	// Check if user already exists
-	if _, err := s.repos.Users.GetByEmail(req.Email); err == nil {
-		return nil, nil, ErrUserAlreadyExists
+	if _, err := repos.Users.GetByEmail(req.Email); err == nil {
+		return nil, nil, models.ErrEmailTaken
 	}
-	if _, err := s.repos.Users.GetByUsername(req.Username); err == nil {
-		return nil, nil, ErrUserAlreadyExists
+	if _, err := repos.Users.GetByUsername(req.Username); err == nil {
+		return nil, nil, models.ErrUsernameTaken
 	}
 
 	// Hash password
-	passwordHash, err := s.hashPassword(req.Password)
+	passwordHash, err := s.hashPassword(ctx, req.Password)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -90,92 +126,273 @@ func (s *Service) Register(req *RegistrationRequest, ipAddress, userAgent string
 			LastName:  req.LastName,
 		},
 	}
+	if s.isAdminEmail(req.Email) {
+		user.Role = models.RoleAdmin
+	}
 
 	// Save user
-	if err := s.repos.Users.Create(user); err != nil {
+	if err := repos.Users.Create(user); err != nil {
 		return nil, nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	// Update profile with names
 	user.Profile.UserID = user.ID
-	if err := s.repos.Users.UpdateProfile(&user.Profile); err != nil {
+	if err := repos.Users.UpdateProfile(&user.Profile); err != nil {
 		return nil, nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 
 	// Create session
-	session, err := models.NewSession(user.ID, ipAddress, userAgent)
+	session, err := models.NewSessionWithPolicy(user.ID, ipAddress, userAgent, s.sessionPolicy)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
-	if err := s.repos.Sessions.Create(session); err != nil {
+	if err := repos.Sessions.Create(session); err != nil {
 		return nil, nil, fmt.Errorf("failed to save session: %w", err)
 	}
 
+	s.metrics.Registrations.Inc()
 	return user, session, nil
 }
 
 // Login authenticates a user and creates a session
-func (s *Service) Login(req *LoginRequest, ipAddress, userAgent string) (*models.User, *models.Session, error) {
+func (s *Service) Login(ctx context.Context, req *LoginRequest, ipAddress, userAgent string) (*models.User, *models.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.Login")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
 	// Validate login request
 	if err := s.validateLoginRequest(req); err != nil {
 		return nil, nil, err
 	}
 
 	// Get user by email
-	user, err := s.repos.Users.GetByEmail(req.Email)
+	user, err := repos.Users.GetByEmail(req.Email)
 	if err != nil {
+		s.recordLoginFailure(span)
 		return nil, nil, ErrInvalidCredentials
 	}
 
 	// Verify password
-	if !s.verifyPassword(req.Password, user.PasswordHash) {
+	if !s.verifyPassword(ctx, req.Password, user.PasswordHash) {
+		s.recordLoginFailure(span)
 		return nil, nil, ErrInvalidCredentials
 	}
 
+	// Upgrade legacy or weaker hashes while the password is at hand
+	s.upgradePasswordHash(ctx, user, req.Password)
+
+	// Promote configured admins before issuing the session, since role
+	// changes revoke existing sessions
+	if s.isAdminEmail(user.Email) && !user.IsAdmin() {
+		if _, err := s.SetRole(ctx, user.ID, models.RoleAdmin, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to grant admin role: %w", err)
+		}
+		user.Role = models.RoleAdmin
+	}
+
 	// Create session
-	session, err := models.NewSession(user.ID, ipAddress, userAgent)
+	session, err := models.NewSessionWithPolicy(user.ID, ipAddress, userAgent, s.sessionPolicy)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
-	if err := s.repos.Sessions.Create(session); err != nil {
+	if err := repos.Sessions.Create(session); err != nil {
 		return nil, nil, fmt.Errorf("failed to save session: %w", err)
 	}
 
+	s.metrics.LoginAttempts.Inc(loginSuccess)
+	span.SetAttributes(tracing.String("auth.result", loginSuccess))
 	return user, session, nil
 }
 
+// recordLoginFailure counts a failed login
+func (s *Service) recordLoginFailure(span *tracing.Span) {
+	s.metrics.LoginAttempts.Inc(loginFailure)
+	span.SetAttributes(tracing.String("auth.result", loginFailure))
+}
+
 // Logout invalidates a user session
-func (s *Service) Logout(sessionToken string) error {
+func (s *Service) Logout(ctx context.Context, sessionToken string) error {
+	ctx, span := tracing.Start(ctx, "auth.Logout")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
 	if sessionToken == "" {
 		return nil // Already logged out
 	}
 
-	return s.repos.Sessions.DeleteByToken(sessionToken)
+	return repos.Sessions.DeleteByToken(sessionToken)
+}
+
+// RotateSession issues a new token for an existing session and invalidates the
+// old one. The session keeps its ID, creation time and absolute expiry.
+func (s *Service) RotateSession(ctx context.Context, sessionToken string) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.RotateSession")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	if sessionToken == "" {
+		return nil, models.ErrSessionNotFound
+	}
+
+	newToken, err := models.GenerateSessionToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+
+	return repos.Sessions.ReplaceToken(sessionToken, newToken)
+}
+
+// SetRole changes a user's role so that no token issued under the previous
+// privileges remains usable. When the change is made from one of the user's
+// own sessions, identified by currentToken, that session is rotated and
+// returned so that the caller can reissue its cookie, and every other
+// session is revoked. Otherwise, as at login where currentToken is empty,
+// all of the user's sessions are revoked and no session is returned.
+func (s *Service) SetRole(ctx context.Context, userID string, role models.Role, currentToken string) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.SetRole")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	user, err := repos.Users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role == role {
+		return nil, nil
+	}
+
+	user.Role = role
+	if err := repos.Users.Update(user); err != nil {
+		return nil, err
+	}
+
+	if currentToken != "" {
+		if current, err := repos.Sessions.GetByToken(currentToken); err == nil && current.UserID == userID {
+			if _, err := s.RevokeOtherSessions(ctx, userID, currentToken); err != nil {
+				return nil, err
+			}
+			return s.RotateSession(ctx, currentToken)
+		}
+	}
+
+	return nil, repos.Sessions.DeleteByUserID(userID)
 }
 
 // GetUserFromSession retrieves a user from a session token
-func (s *Service) GetUserFromSession(sessionToken string) (*models.User, error) {
+func (s *Service) GetUserFromSession(ctx context.Context, sessionToken string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "auth.GetUserFromSession")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
 	if sessionToken == "" {
 		return nil, models.ErrSessionNotFound
 	}
 
 	// Get session
-	session, err := s.repos.Sessions.GetByToken(sessionToken)
+	session, err := repos.Sessions.GetByToken(sessionToken)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get user
-	user, err := s.repos.Users.GetByID(session.UserID)
+	user, err := repos.Users.GetByID(session.UserID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Record activity and slide the idle expiry; failures here must not block
+	// the request
+	repos.Sessions.Touch(sessionToken, time.Now())
+
 	return user, nil
 }
 
+// GetSession retrieves the session for a session token
+func (s *Service) GetSession(ctx context.Context, sessionToken string) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.GetSession")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	if sessionToken == "" {
+		return nil, models.ErrSessionNotFound
+	}
+
+	return repos.Sessions.GetByToken(sessionToken)
+}
+
+// ListSessions returns the active sessions of a user, most recently seen first
+func (s *Service) ListSessions(ctx context.Context, userID string) ([]*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.ListSessions")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	sessions, err := repos.Sessions.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession deletes one of the user's sessions by ID. Sessions belonging to
+// other users are reported as not found.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	ctx, span := tracing.Start(ctx, "auth.RevokeSession")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	sessions, err := repos.Sessions.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			return repos.Sessions.Delete(session.ID)
+		}
+	}
+
+	return models.ErrSessionNotFound
+}
+
+// RevokeOtherSessions deletes every session of the user except the one
+// identified by currentToken, returning the number of sessions revoked
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID, currentToken string) (int, error) {
+	ctx, span := tracing.Start(ctx, "auth.RevokeOtherSessions")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	current, err := repos.Sessions.GetByToken(currentToken)
+	if err != nil {
+		return 0, err
+	}
+
+	sessions, err := repos.Sessions.GetByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == current.ID {
+			continue
+		}
+		if err := repos.Sessions.Delete(session.ID); err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+			return revoked, err
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
 // GetSessionFromRequest extracts session token from HTTP request
 func (s *Service) GetSessionFromRequest(r *http.Request) string {
 	// Try cookie first
@@ -192,88 +409,35 @@ func (s *Service) GetSessionFromRequest(r *http.Request) string {
 	return ""
 }
 
-// validateRegistrationRequest validates a registration request
+// validateRegistrationRequest validates a registration request, reporting
+// every invalid field in a *models.ValidationError
 func (s *Service) validateRegistrationRequest(req *RegistrationRequest) error {
+	var errs []*models.Error
 	if req.Email == "" {
-		return errors.New("email is required")
+		errs = append(errs, models.ErrEmailRequired)
 	}
 	if req.Username == "" {
-		return errors.New("username is required")
-	}
-	if req.Password == "" {
-		return errors.New("password is required")
+		errs = append(errs, models.ErrUsernameRequired)
 	}
-	if len(req.Password) < 8 {
-		return ErrPasswordTooShort
+	switch {
+	case req.Password == "":
+		errs = append(errs, ErrPasswordRequired)
+	case len(req.Password) < 8:
+		errs = append(errs, ErrPasswordTooShort)
+	case req.Password != req.ConfirmPassword:
+		errs = append(errs, ErrPasswordsDoNotMatch)
 	}
-	if req.Password != req.ConfirmPassword {
-		return ErrPasswordsDoNotMatch
-	}
-	return nil
+	return models.NewValidationError(errs...)
 }
 
 // validateLoginRequest validates a login request
 func (s *Service) validateLoginRequest(req *LoginRequest) error {
+	var errs []*models.Error
 	if req.Email == "" {
-		return errors.New("email is required")
+		errs = append(errs, models.ErrEmailRequired)
 	}
 	if req.Password == "" {
-		return errors.New("password is required")
+		errs = append(errs, ErrPasswordRequired)
 	}
-	return nil
+	return models.NewValidationError(errs...)
 }
-
-// hashPassword hashes a password using Argon2id
-func (s *Service) hashPassword(password string) (string, error) {
-	// Generate salt
-	salt := make([]byte, saltLength)
-	if _, err := rand.Read(salt); err != nil {
-		return "", err
-	}
-
-	// Hash password
-	hash := argon2.IDKey([]byte(password), salt, time, memory, threads, keyLength)
-
-	// Encode to base64
-	saltB64 := base64.RawStdEncoding.EncodeToString(salt)
-	hashB64 := base64.RawStdEncoding.EncodeToString(hash)
-
-	// Format: $argon2id$v=19$m=65536,t=1,p=4$salt$hash
-	return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s", memory, time, threads, saltB64, hashB64), nil
-}
-
-// verifyPassword verifies a password against a hash
-func (s *Service) verifyPassword(password, hash string) bool {
-	// Parse hash format: $argon2id$v=19$m=65536,t=1,p=4$salt$hash
-	parts := strings.Split(hash, "$")
-	if len(parts) != 6 {
-		return false
-	}
-
-	if parts[1] != "argon2id" || parts[2] != "v=19" {
-		return false
-	}
-
-	// Parse parameters
-	var m, t, p uint32
-	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &m, &t, &p); err != nil {
-		return false
-	}
-
-	// Decode salt and hash
-	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
-	if err != nil {
-		return false
-	}
-
-	expectedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
-	if err != nil {
-		return false
-	}
-
-	// Hash the provided password
-	actualHash := argon2.IDKey([]byte(password), salt, t, m, uint8(p), uint32(len(expectedHash)))
-
-	// Compare hashes using constant-time comparison
-	return subtle.ConstantTimeCompare(expectedHash, actualHash) == 1
-}
\ No newline at end of file
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/tracing"
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CreateAccessToken creates a personal access token for the user. The
+// returned token carries the plaintext secret, which is not stored and
+// cannot be retrieved again.
+func (s *Service) CreateAccessToken(ctx context.Context, userID, name string, scopes []models.Scope, expiresAt time.Time) (*models.AccessToken, error) {
+	ctx, span := tracing.Start(ctx, "auth.CreateAccessToken")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	token, err := models.NewAccessToken(userID, name, scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := repos.AccessTokens.Create(token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// ListAccessTokens returns the user's access tokens, newest first. Expired
+// tokens are included so that users can see why a script stopped working.
+func (s *Service) ListAccessTokens(ctx context.Context, userID string) ([]*models.AccessToken, error) {
+	ctx, span := tracing.Start(ctx, "auth.ListAccessTokens")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	tokens, err := repos.AccessTokens.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+
+	return tokens, nil
+}
+
+// RevokeAccessToken deletes one of the user's access tokens by ID. Tokens
+// belonging to other users are reported as not found.
+func (s *Service) RevokeAccessToken(ctx context.Context, userID, tokenID string) error {
+	ctx, span := tracing.Start(ctx, "auth.RevokeAccessToken")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	tokens, err := repos.AccessTokens.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.ID == tokenID {
+			return repos.AccessTokens.Delete(token.ID)
+		}
+	}
+
+	return models.ErrAccessTokenNotFound
+}
+
+// GetUserFromAccessToken retrieves the user a personal access token belongs
+// to, along with the token, and records that the token was used
+func (s *Service) GetUserFromAccessToken(ctx context.Context, accessToken string) (*models.User, *models.AccessToken, error) {
+	ctx, span := tracing.Start(ctx, "auth.GetUserFromAccessToken")
+	defer span.End()
+	repos := s.repos.WithContext(ctx)
+
+	if !models.IsAccessToken(accessToken) {
+		return nil, nil, models.ErrAccessTokenNotFound
+	}
+
+	token, err := repos.AccessTokens.GetByToken(accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if token.IsExpired() {
+		return nil, nil, models.ErrAccessTokenExpired
+	}
+
+	user, err := repos.Users.GetByID(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Failures to record usage must not block the request
+	repos.AccessTokens.Touch(accessToken, time.Now())
+
+	return user, token, nil
+}
+
+// GetAccessTokenFromRequest extracts a personal access token from the
+// Authorization header, "" when the request does not carry one
+func (s *Service) GetAccessTokenFromRequest(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !models.IsAccessToken(token) {
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources that can contribute settings, lowest precedence first
+const (
+	SourceDefaults = "defaults"
+	SourceFile     = "file"
+	SourceEnv      = "env"
+	SourceFlags    = "flags"
+)
+
+// Options are command-line settings that control loading rather than the
+// server itself
+type Options struct {
+	File        string   // Config file from --config or CONFIG_FILE
+	PrintConfig bool     // Print the redacted configuration and exit
+	PrintRoutes bool     // Print the route table and exit
+	ImportUsers string   // JSON Lines file of users to import before serving
+	Sources     []string // Sources that set at least one value
+}
+
+// setting is one configurable field with its names in each source
+type setting struct {
+	value  reflect.Value
+	key    string   // Config file key
+	env    []string // Environment variables, first set wins
+	flag   string   // Command-line flag
+	usage  string
+	secret bool
+}
+
+// settings lists the configurable fields of c
+func (c *Config) settings() []setting {
+	value := reflect.ValueOf(c).Elem()
+	var settings []setting
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := field.Tag.Get("yaml")
+		if key == "" || key == "-" {
+			continue
+		}
+		settings = append(settings, setting{
+			value:  value.Field(i),
+			key:    key,
+			env:    strings.Split(field.Tag.Get("env"), ","),
+			flag:   field.Tag.Get("flag"),
+			usage:  field.Tag.Get("usage"),
+			secret: field.Tag.Get("secret") == "true",
+		})
+	}
+	return settings
+}
+
+// Load builds the configuration from, in increasing precedence, defaults, a
+// YAML or TOML file, environment variables and command-line flags. Secrets
+// may instead be read from files named by a _FILE variable, a _file key or
+// a -file flag. Every parse and validation problem is reported in the
+// returned error, alongside the configuration as far as it could be built.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, *Options, error) {
+	config := Default()
+	settings := config.settings()
+	options := &Options{Sources: []string{SourceDefaults}}
+
+	// Flags are parsed first to find the config file, and applied last
+	type flagValue struct {
+		setting  setting
+		value    string
+		fromFile bool
+	}
+	var flagValues []flagValue
+	fs := flag.NewFlagSet("compify-backend", flag.ContinueOnError)
+	fs.StringVar(&options.File, "config", "", "YAML or TOML config file")
+	fs.BoolVar(&options.PrintConfig, "print-config", false, "print the configuration with secrets redacted and exit")
+	fs.BoolVar(&options.PrintRoutes, "print-routes", false, "print the route table and exit")
+	fs.StringVar(&options.ImportUsers, "import-users", "", "JSON Lines file of users to import before serving")
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flag, s.usage, func(value string) error {
+			flagValues = append(flagValues, flagValue{setting: s, value: value})
+			return nil
+		})
+		if s.secret {
+			fs.Func(s.flag+"-file", "file containing the "+s.flag, func(path string) error {
+				flagValues = append(flagValues, flagValue{setting: s, value: path, fromFile: true})
+				return nil
+			})
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return config, options, err
+	}
+
+	var errs []error
+	if options.File == "" {
+		options.File, _ = lookupEnv("CONFIG_FILE")
+	}
+	if options.File != "" {
+		if err := loadFile(options.File, settings); err != nil {
+			errs = append(errs, err)
+		}
+		options.Sources = append(options.Sources, SourceFile)
+	}
+
+	fromEnv := false
+	for _, s := range settings {
+		applied, err := loadEnv(s, lookupEnv)
+		fromEnv = fromEnv || applied
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if fromEnv {
+		options.Sources = append(options.Sources, SourceEnv)
+	}
+
+	for _, f := range flagValues {
+		value, name := f.value, "--"+f.setting.flag
+		if f.fromFile {
+			var err error
+			name += "-file"
+			if value, err = readSecret(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+		}
+		if err := setString(f.setting.value, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if len(flagValues) > 0 {
+		options.Sources = append(options.Sources, SourceFlags)
+	}
+
+	config.applyDerivedDefaults()
+	errs = append(errs, config.Validate())
+	return config, options, errors.Join(errs...)
+}
+
+// loadFile applies the settings in a YAML or TOML file
+func loadFile(path string, settings []setting) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	values := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file: unsupported format %q, use .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if raw, ok := values[s.key]; ok {
+			delete(values, s.key)
+			if err := setValue(s.value, raw); err != nil {
+				errs = append(errs, fmt.Errorf("config file %s: %w", s.key, err))
+			}
+		}
+		if !s.secret {
+			continue
+		}
+		if raw, ok := values[s.key+"_file"]; ok {
+			delete(values, s.key+"_file")
+			secret, err := readSecret(fmt.Sprint(raw))
+			if err == nil {
+				err = setString(s.value, secret)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config file %s_file: %w", s.key, err))
+			}
+		}
+	}
+	for key := range values {
+		errs = append(errs, fmt.Errorf("config file: unknown setting %q", key))
+	}
+	return errors.Join(errs...)
+}
+
+// loadEnv applies the first of a setting's environment variables that is
+// set, reporting whether one was
+func loadEnv(s setting, lookupEnv func(string) (string, bool)) (bool, error) {
+	for _, name := range s.env {
+		if value, ok := lookupEnv(name); ok && value != "" {
+			if err := setString(s.value, value); err != nil {
+				return true, fmt.Errorf("%s: %w", name, err)
+			}
+			return true, nil
+		}
+		if !s.secret {
+			continue
+		}
+		if path, ok := lookupEnv(name + "_FILE"); ok && path != "" {
+			secret, err := readSecret(path)
+			if err == nil {
+				err = setString(s.value, secret)
+			}
+			if err != nil {
+				return true, fmt.Errorf("%s_FILE: %w", name, err)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readSecret reads a secret from a file, such as a mounted Docker or
+// Kubernetes secret, without its trailing newline
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// setValue sets a field from a decoded config file value
+func setValue(field reflect.Value, raw any) error {
+	if list, ok := raw.([]any); ok {
+		if field.Kind() != reflect.Slice {
+			return fmt.Errorf("expected a single value, got a list")
+		}
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, fmt.Sprint(item))
+		}
+		field.Set(reflect.ValueOf(values))
+		return nil
+	}
+	return setString(field, fmt.Sprint(raw))
+}
+
+// setString parses a value for a field from its string form; lists are
+// comma-separated
+func setString(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case time.Duration:
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, use a value like 30s or 24h", value)
+		}
+		field.SetInt(int64(d))
+	case int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case []string:
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Package health provides a registry of dependency checks used by the
+// liveness and readiness endpoints.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check or of a whole report
+type Status string
+
+// Check and report statuses
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded" // A non-critical check failed
+	StatusDown     Status = "down"     // A critical check failed
+)
+
+// DefaultTimeout bounds checks that do not set their own timeout
+const DefaultTimeout = 2 * time.Second
+
+// Check describes a named dependency probe
+type Check struct {
+	Name     string
+	Timeout  time.Duration // Limit on a single probe, DefaultTimeout if zero
+	Critical bool          // Failing critical checks make the service unready
+	Liveness bool          // Also run by liveness probes
+	Run      func(ctx context.Context) error
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Status   Status        `json:"status"`
+	Critical bool          `json:"critical"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report aggregates the results of a set of checks
+type Report struct {
+	Status    Status            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Registry errors
+var (
+	ErrInvalidCheck = errors.New("check requires a name and run function")
+	ErrCheckExists  = errors.New("check already registered")
+)
+
+// Registry holds the checks registered by subsystems
+type Registry struct {
+	checks []Check
+	mutex  sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a check to the registry
+func (r *Registry) Register(check Check) error {
+	if check.Name == "" || check.Run == nil {
+		return ErrInvalidCheck
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.checks {
+		if existing.Name == check.Name {
+			return fmt.Errorf("%w: %s", ErrCheckExists, check.Name)
+		}
+	}
+
+	r.checks = append(r.checks, check)
+	sort.Slice(r.checks, func(i, j int) bool {
+		return r.checks[i].Name < r.checks[j].Name
+	})
+
+	return nil
+}
+
+// Liveness runs the checks marked as liveness checks
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, func(check Check) bool { return check.Liveness })
+}
+
+// Readiness runs every registered check
+func (r *Registry) Readiness(ctx context.Context) Report {
+	return r.run(ctx, func(Check) bool { return true })
+}
+
+// run executes the selected checks concurrently and aggregates the results
+func (r *Registry) run(ctx context.Context, include func(Check) bool) Report {
+	r.mutex.RLock()
+	var checks []Check
+	for _, check := range r.checks {
+		if include(check) {
+			checks = append(checks, check)
+		}
+	}
+	r.mutex.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusOK,
+		Checks:    make(map[string]Result, len(checks)),
+		Timestamp: time.Now(),
+	}
+	for i, check := range checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+
+		if result.Status != StatusOK {
+			if check.Critical {
+				report.Status = StatusDown
+			} else if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		}
+	}
+
+	return report
+}
+
+// runCheck runs a single check with its timeout. A check that does not
+// return in time is reported as failed; its goroutine is left to finish on
+// its own.
+func runCheck(ctx context.Context, check Check) Result {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		done <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	result := Result{
+		Status:   StatusOK,
+		Critical: check.Critical,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Summary flattens a report into check name to "ok" or error message, the
+// format used by the legacy /health endpoint
+func (r Report) Summary() map[string]string {
+	summary := make(map[string]string, len(r.Checks))
+	for name, result := range r.Checks {
+		if result.Status == StatusOK {
+			summary[name] = string(StatusOK)
+		} else {
+			summary[name] = result.Error
+		}
+	}
+	return summary
+}
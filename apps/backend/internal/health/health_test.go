@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test aggregation of check results into ok, degraded and down reports
+func TestRegistryReport(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("unreachable") }
+
+	tests := []struct {
+		name   string
+		checks []Check
+		want   Status
+	}{
+		{"no checks", nil, StatusOK},
+		{"all passing", []Check{{Name: "a", Run: ok, Critical: true}, {Name: "b", Run: ok}}, StatusOK},
+		{"non-critical failure", []Check{{Name: "a", Run: ok, Critical: true}, {Name: "b", Run: failing}}, StatusDegraded},
+		{"critical failure", []Check{{Name: "a", Run: failing, Critical: true}, {Name: "b", Run: failing}}, StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			for _, check := range tt.checks {
+				if err := registry.Register(check); err != nil {
+					t.Fatalf("Register failed: %v", err)
+				}
+			}
+
+			report := registry.Readiness(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("Expected status %s, got %s", tt.want, report.Status)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("Expected %d check results, got %d", len(tt.checks), len(report.Checks))
+			}
+		})
+	}
+}
+
+// Test that slow and panicking checks fail without blocking the report
+func TestRegistryTimeoutsAndPanics(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(Check{
+		Name:    "slow",
+		Timeout: 20 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			time.Sleep(time.Second) // Ignores ctx on purpose
+			return nil
+		},
+	})
+	registry.Register(Check{
+		Name:     "panics",
+		Liveness: true,
+		Run:      func(context.Context) error { panic("boom") },
+	})
+
+	if err := registry.Register(Check{Name: "slow", Run: func(context.Context) error { return nil }}); !errors.Is(err, ErrCheckExists) {
+		t.Errorf("Expected ErrCheckExists, got %v", err)
+	}
+	if err := registry.Register(Check{Name: "nameless"}); !errors.Is(err, ErrInvalidCheck) {
+		t.Errorf("Expected ErrInvalidCheck, got %v", err)
+	}
+
+	start := time.Now()
+	report := registry.Readiness(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Report should not wait for a timed-out check, took %v", elapsed)
+	}
+	if report.Status != StatusDegraded {
+		t.Errorf("Expected degraded status, got %s", report.Status)
+	}
+	if report.Checks["slow"].Status != StatusDown || report.Checks["panics"].Error != "panic: boom" {
+		t.Errorf("Unexpected check results: %+v", report.Checks)
+	}
+
+	liveness := registry.Liveness(context.Background())
+	if _, included := liveness.Checks["slow"]; included || len(liveness.Checks) != 1 {
+		t.Errorf("Liveness should only run liveness checks, got %+v", liveness.Checks)
+	}
+
+	summary := report.Summary()
+	if summary["panics"] != "panic: boom" {
+		t.Errorf("Expected error message in summary, got %q", summary["panics"])
+	}
+}
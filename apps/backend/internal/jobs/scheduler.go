@@ -0,0 +1,318 @@
+package jobs
+
+import (
+	"compify-backend/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Func is the work performed by a job. The context is cancelled when the
+// scheduler stops or the job's timeout elapses.
+type Func func(ctx context.Context) error
+
+// Job describes a named periodic task
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Jitter   time.Duration // Random delay of up to this amount added to each run
+	Timeout  time.Duration // Optional limit on a single run
+	Run      Func
+}
+
+// Status is a snapshot of a job's execution history
+type Status struct {
+	Name         string        `json:"name"`
+	Schedule     string        `json:"schedule"`
+	Running      bool          `json:"running"`
+	Runs         uint64        `json:"runs"`
+	Failures     uint64        `json:"failures"`
+	Skipped      uint64        `json:"skipped"` // Ticks skipped because the previous run was still in progress
+	LastStart    time.Time     `json:"last_start"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+	LastErrorAt  time.Time     `json:"last_error_at"`
+	NextRun      time.Time     `json:"next_run"`
+}
+
+// Scheduler errors
+var (
+	ErrJobExists      = errors.New("job already registered")
+	ErrJobNotFound    = errors.New("job not found")
+	ErrInvalidJob     = errors.New("job requires a name, schedule and run function")
+	ErrAlreadyRunning = errors.New("job is already running")
+	ErrStopped        = errors.New("scheduler is stopped")
+)
+
+// entry holds a registered job and its mutable status
+type entry struct {
+	job     Job
+	running atomic.Bool
+
+	mutex  sync.Mutex
+	status Status
+}
+
+// Scheduler runs registered jobs in-process on their schedules. Each job runs
+// at most once at a time, and a panicking job never affects other jobs.
+type Scheduler struct {
+	entries map[string]*entry
+	mutex   sync.RWMutex
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	stopped bool
+	loops   sync.WaitGroup // Per-job scheduling loops
+	runs    sync.WaitGroup // In-flight job executions
+}
+
+// NewScheduler creates a new, stopped scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		entries: make(map[string]*entry),
+	}
+}
+
+// Register adds a job to the scheduler. Jobs registered after Start begin
+// running immediately.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return ErrInvalidJob
+	}
+	if interval, ok := job.Schedule.(intervalSchedule); ok && interval.interval <= 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidSchedule, job.Schedule)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return ErrStopped
+	}
+	if _, exists := s.entries[job.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+
+	e := &entry{
+		job: job,
+		status: Status{
+			Name:     job.Name,
+			Schedule: job.Schedule.String(),
+		},
+	}
+	s.entries[job.Name] = e
+
+	if s.started {
+		s.startLoop(e)
+	}
+
+	return nil
+}
+
+// Start begins running all registered jobs until ctx is cancelled or Stop is
+// called
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.started || s.stopped {
+		return
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.started = true
+
+	for _, e := range s.entries {
+		s.startLoop(e)
+	}
+}
+
+// Stop cancels all jobs and waits for in-flight runs to finish, or for ctx to
+// be done, whichever happens first
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mutex.Lock()
+	if s.stopped {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.stopped = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.loops.Wait()
+		s.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for jobs to stop: %w", ctx.Err())
+	}
+}
+
+// Running reports whether the scheduler has been started and not stopped
+func (s *Scheduler) Running() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.started && !s.stopped
+}
+
+// RunNow triggers an immediate run of the named job outside its schedule
+func (s *Scheduler) RunNow(name string) error {
+	s.mutex.RLock()
+	e, exists := s.entries[name]
+	ctx := s.ctx
+	stopped := s.stopped
+	s.mutex.RUnlock()
+
+	if !exists {
+		return ErrJobNotFound
+	}
+	if stopped {
+		return ErrStopped
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if !s.execute(ctx, e) {
+		return ErrAlreadyRunning
+	}
+	return nil
+}
+
+// Status returns a snapshot of every job, ordered by name
+func (s *Scheduler) Status() []Status {
+	s.mutex.RLock()
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		e.mutex.Lock()
+		status := e.status
+		e.mutex.Unlock()
+		status.Running = e.running.Load()
+		statuses = append(statuses, status)
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// startLoop launches the scheduling loop for a job. Callers must hold s.mutex.
+func (s *Scheduler) startLoop(e *entry) {
+	s.loops.Add(1)
+	go func() {
+		defer s.loops.Done()
+		s.loop(s.ctx, e)
+	}()
+}
+
+// loop waits for each scheduled time and triggers the job
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	for {
+		next := e.job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			slog.Warn("Job has no future runs, stopping its schedule", "job", e.job.Name)
+			return
+		}
+		if e.job.Jitter > 0 {
+			next = next.Add(rand.N(e.job.Jitter))
+		}
+
+		e.mutex.Lock()
+		e.status.NextRun = next
+		e.mutex.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !s.execute(ctx, e) {
+			e.mutex.Lock()
+			e.status.Skipped++
+			e.mutex.Unlock()
+			slog.Warn("Job skipped, previous run still in progress", "job", e.job.Name)
+		}
+	}
+}
+
+// execute starts a run of the job unless one is already in progress. It
+// reports whether a run was started.
+func (s *Scheduler) execute(ctx context.Context, e *entry) bool {
+	if !e.running.CompareAndSwap(false, true) {
+		return false
+	}
+
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		defer e.running.Store(false)
+
+		start := time.Now()
+		e.mutex.Lock()
+		e.status.LastStart = start
+		e.mutex.Unlock()
+
+		runCtx, span := tracing.Start(ctx, "job "+e.job.Name, tracing.String("job.name", e.job.Name))
+		err := runJob(runCtx, e.job)
+		span.RecordError(err)
+		span.End()
+		duration := time.Since(start)
+
+		e.mutex.Lock()
+		e.status.Runs++
+		e.status.LastDuration = duration
+		if err != nil {
+			e.status.Failures++
+			e.status.LastError = err.Error()
+			e.status.LastErrorAt = time.Now()
+		}
+		e.mutex.Unlock()
+
+		if err != nil {
+			slog.Error("Job failed", "job", e.job.Name, "duration", duration, "error", err)
+		}
+	}()
+
+	return true
+}
+
+// runJob runs a job with its timeout, converting panics into errors
+func runJob(ctx context.Context, job Job) (err error) {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Job panicked", "job", job.Name, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return job.Run(ctx)
+}
@@ -0,0 +1,175 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test cron expression parsing and next-run calculation
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, time.March, 15, 10, 7, 30, 0, time.UTC) // Friday
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"@every 5m", base.Add(5 * time.Minute)},
+		{"*/15 * * * *", time.Date(2024, time.March, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2024, time.March, 18, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,20 * *", time.Date(2024, time.March, 20, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0", time.Date(2024, time.March, 17, 0, 0, 0, 0, time.UTC)}, // Day of month OR day of week
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) failed: %v", tt.spec, err)
+			}
+			if got := schedule.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@every -1s", "@yearly"} {
+		if _, err := ParseSchedule(spec); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("ParseSchedule(%q) expected ErrInvalidSchedule, got %v", spec, err)
+		}
+	}
+
+	// Intervals that never move forward would run in a hot loop
+	scheduler := NewScheduler()
+	for _, interval := range []time.Duration{0, -time.Second} {
+		err := scheduler.Register(Job{Name: "loop", Schedule: Every(interval), Run: func(context.Context) error { return nil }})
+		if !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("Register(Every(%s)) expected ErrInvalidSchedule, got %v", interval, err)
+		}
+	}
+}
+
+// Test that jobs run on schedule, record failures and survive panics
+func TestSchedulerRunsJobs(t *testing.T) {
+	scheduler := NewScheduler()
+
+	var okRuns, panicRuns atomic.Int32
+	mustRegister(t, scheduler, Job{
+		Name:     "ok",
+		Schedule: Every(10 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			okRuns.Add(1)
+			return nil
+		},
+	})
+	mustRegister(t, scheduler, Job{
+		Name:     "panics",
+		Schedule: Every(10 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			panicRuns.Add(1)
+			panic("boom")
+		},
+	})
+
+	if err := scheduler.Register(Job{Name: "ok", Schedule: Every(time.Second), Run: func(context.Context) error { return nil }}); !errors.Is(err, ErrJobExists) {
+		t.Errorf("Expected ErrJobExists for duplicate job, got %v", err)
+	}
+
+	scheduler.Start(context.Background())
+	waitFor(t, func() bool { return okRuns.Load() >= 3 && panicRuns.Load() >= 3 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := scheduler.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	statuses := scheduler.Status()
+	if len(statuses) != 2 || statuses[0].Name != "ok" || statuses[1].Name != "panics" {
+		t.Fatalf("Expected statuses ordered by name, got %+v", statuses)
+	}
+	if statuses[0].Failures != 0 || statuses[0].Runs < 3 {
+		t.Errorf("Unexpected status for ok job: %+v", statuses[0])
+	}
+	if statuses[1].Failures != statuses[1].Runs || statuses[1].LastError != "panic: boom" {
+		t.Errorf("Expected panics to be recorded as failures, got %+v", statuses[1])
+	}
+
+	// No runs happen after Stop
+	runs := okRuns.Load()
+	time.Sleep(30 * time.Millisecond)
+	if okRuns.Load() != runs {
+		t.Errorf("Job ran after Stop")
+	}
+}
+
+// Test that a job never overlaps with itself and Stop waits for it
+func TestSchedulerSingleFlight(t *testing.T) {
+	scheduler := NewScheduler()
+
+	var running, overlaps atomic.Int32
+	release := make(chan struct{})
+	mustRegister(t, scheduler, Job{
+		Name:     "slow",
+		Schedule: Every(5 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			if running.Add(1) > 1 {
+				overlaps.Add(1)
+			}
+			defer running.Add(-1)
+			<-release
+			return ctx.Err()
+		},
+	})
+
+	scheduler.Start(context.Background())
+	waitFor(t, func() bool { return scheduler.Status()[0].Skipped >= 2 })
+
+	if err := scheduler.RunNow("slow"); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Expected ErrAlreadyRunning, got %v", err)
+	}
+	if err := scheduler.RunNow("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+
+	// Stop times out while the job is blocked, then completes once released
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := scheduler.Stop(short); err == nil {
+		t.Errorf("Expected Stop to time out while a job is running")
+	}
+	close(release)
+	waitFor(t, func() bool { return !scheduler.Status()[0].Running })
+
+	if overlaps.Load() != 0 {
+		t.Errorf("Job overlapped with itself %d times", overlaps.Load())
+	}
+	if err := scheduler.Register(Job{Name: "late", Schedule: Every(time.Second), Run: func(context.Context) error { return nil }}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected ErrStopped after Stop, got %v", err)
+	}
+}
+
+// mustRegister registers a job or fails the test
+func mustRegister(t *testing.T, scheduler *Scheduler, job Job) {
+	t.Helper()
+	if err := scheduler.Register(job); err != nil {
+		t.Fatalf("Failed to register job %s: %v", job.Name, err)
+	}
+}
+
+// waitFor polls until cond is true or fails the test after a timeout
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Condition not met before timeout")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
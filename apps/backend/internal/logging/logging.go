@@ -0,0 +1,233 @@
+// Package logging configures structured logging and carries per-request
+// log attributes, such as the request ID and user ID, through contexts.
+package logging
+
+import (
+	"compify-backend/internal/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Log output formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New creates a logger writing to w at the given level ("debug", "info",
+// "warn" or "error") and format ("json" or "text"). Records logged with a
+// request context carry that request's attributes.
+func New(w io.Writer, level, format string) *slog.Logger {
+	options := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var handler slog.Handler
+	if format == FormatJSON {
+		handler = slog.NewJSONHandler(w, options)
+	} else {
+		handler = slog.NewTextHandler(w, options)
+	}
+
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// ParseLevel converts a level name to a slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// DefaultFormat returns the log format for an environment: JSON in
+// production, text otherwise
+func DefaultFormat(environment string) string {
+	if environment == "production" {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// contextHandler adds request attributes from the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the request ID, user ID and trace ID, when present, and
+// forwards the record
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info := infoFromContext(ctx); info != nil {
+		record.AddAttrs(slog.String("request_id", info.RequestID))
+		if userID := info.UserID(); userID != "" {
+			record.AddAttrs(slog.String("user_id", userID))
+		}
+	}
+	if sc := tracing.SpanFromContext(ctx).SpanContext(); sc.IsValid() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID.String()), slog.String("span_id", sc.SpanID.String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs wraps the underlying handler so context attributes are kept
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup wraps the underlying handler so context attributes are kept
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// RequestInfo holds log attributes for a single request. The user ID is
+// filled in by handlers after authentication, so it is guarded by a mutex.
+type RequestInfo struct {
+	RequestID string
+
+	mutex  sync.RWMutex
+	userID string
+}
+
+// UserID returns the authenticated user's ID, if any
+func (i *RequestInfo) UserID() string {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	return i.userID
+}
+
+type contextKey struct{}
+
+// WithRequestInfo returns a context carrying info
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// infoFromContext returns the request info stored in ctx, if any
+func infoFromContext(ctx context.Context) *RequestInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(contextKey{}).(*RequestInfo)
+	return info
+}
+
+// RequestID returns the ID of the request that ctx belongs to, if any
+func RequestID(ctx context.Context) string {
+	if info := infoFromContext(ctx); info != nil {
+		return info.RequestID
+	}
+	return ""
+}
+
+// UserID returns the authenticated user recorded for ctx's request, if any
+func UserID(ctx context.Context) string {
+	if info := infoFromContext(ctx); info != nil {
+		return info.UserID()
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user for ctx's request so that later
+// log lines, including the access log, include it
+func SetUserID(ctx context.Context, userID string) {
+	if info := infoFromContext(ctx); info != nil {
+		info.mutex.Lock()
+		info.userID = userID
+		info.mutex.Unlock()
+	}
+}
+
+// maxRequestIDLength bounds request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// NewRequestID generates a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID reports whether a client-supplied request ID is safe to log
+// and echo back
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Redacted replaces sensitive values in logs
+const Redacted = "[REDACTED]"
+
+// sensitiveHeaders are never logged verbatim
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"X-Csrf-Token":        true,
+	"X-Api-Key":           true,
+}
+
+// Headers returns a log attribute for the given headers with credentials
+// redacted. Cookie names are kept so they can be debugged, but their values
+// are removed.
+func Headers(key string, header http.Header) slog.Attr {
+	attrs := make([]any, 0, len(header))
+	for name, values := range header {
+		canonical := http.CanonicalHeaderKey(name)
+		value := strings.Join(values, ", ")
+
+		switch {
+		case sensitiveHeaders[canonical]:
+			value = Redacted
+		case canonical == "Cookie":
+			value = redactCookies(values)
+		case canonical == "Set-Cookie":
+			value = redactSetCookies(values)
+		}
+
+		attrs = append(attrs, slog.String(canonical, value))
+	}
+	return slog.Group(key, attrs...)
+}
+
+// redactCookies keeps cookie names from Cookie headers and drops values
+func redactCookies(values []string) string {
+	var names []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ";") {
+			name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				names = append(names, name+"="+Redacted)
+			}
+		}
+	}
+	return strings.Join(names, "; ")
+}
+
+// redactSetCookies keeps cookie names and attributes from Set-Cookie headers
+// and drops values
+func redactSetCookies(values []string) string {
+	redacted := make([]string, len(values))
+	for i, value := range values {
+		pair, attributes, _ := strings.Cut(value, ";")
+		name, _, _ := strings.Cut(pair, "=")
+		redacted[i] = name + "=" + Redacted
+		if attributes != "" {
+			redacted[i] += ";" + attributes
+		}
+	}
+	return strings.Join(redacted, ", ")
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bytes"
+	"compify-backend/internal/tracing"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Test that records logged with a request context carry its attributes
+func TestContextAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "debug", FormatJSON)
+
+	info := &RequestInfo{RequestID: "req-123"}
+	ctx := WithRequestInfo(context.Background(), info)
+	SetUserID(ctx, "user-456")
+	ctx, span := tracing.Start(ctx, "test")
+	defer span.End()
+
+	logger.With("component", "test").InfoContext(ctx, "hello")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", buf.String(), err)
+	}
+	if record["request_id"] != "req-123" || record["user_id"] != "user-456" || record["component"] != "test" {
+		t.Errorf("Missing attributes in record: %v", record)
+	}
+	if record["trace_id"] != span.SpanContext().TraceID.String() || record["span_id"] != span.SpanContext().SpanID.String() {
+		t.Errorf("Missing trace attributes in record: %v", record)
+	}
+
+	buf.Reset()
+	logger.Info("no context")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("Records without a request context should not have a request ID: %s", buf.String())
+	}
+}
+
+// Test level parsing and filtering
+func TestLevels(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"warn":    slog.LevelWarn,
+		"error":   slog.LevelError,
+		"verbose": slog.LevelInfo,
+		"":        slog.LevelInfo,
+	}
+	for input, want := range tests {
+		if got := ParseLevel(input); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	logger := New(&buf, "warn", FormatText)
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Errorf("Expected only warn records, got %q", buf.String())
+	}
+}
+
+// Test that credentials in headers and cookies are redacted
+func TestHeaderRedaction(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret-token")
+	header.Set("Cookie", "session_token=abc123; theme=dark")
+	header.Add("Set-Cookie", "session_token=abc123; Path=/; HttpOnly")
+	header.Set("Accept", "text/html")
+
+	var buf bytes.Buffer
+	logger := New(&buf, "info", FormatJSON)
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "headers", Headers("headers", header))
+
+	out := buf.String()
+	for _, secret := range []string{"secret-token", "abc123", "dark"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Secret %q leaked into log: %s", secret, out)
+		}
+	}
+	for _, kept := range []string{"session_token=" + Redacted, "theme=" + Redacted, "Path=/; HttpOnly", "text/html"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("Expected %q in log: %s", kept, out)
+		}
+	}
+}
+
+// Test validation of client-supplied request IDs
+func TestValidRequestID(t *testing.T) {
+	for _, id := range []string{"abc-123", "f47ac10b-58cc-4372-a567-0e02b2c3d479", NewRequestID()} {
+		if !ValidRequestID(id) {
+			t.Errorf("Expected %q to be valid", id)
+		}
+	}
+	for _, id := range []string{"", "has space", "line\nbreak", strings.Repeat("a", maxRequestIDLength+1)} {
+		if ValidRequestID(id) {
+			t.Errorf("Expected %q to be invalid", id)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package mail sends transactional email, such as sign-in links, through an
+// SMTP server. Without one, messages are logged for development or kept in
+// an outbox for tests.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain text email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer sends messages. Ping checks that messages can currently be sent,
+// for health checks.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+	Ping(ctx context.Context) error
+}
+
+// ErrInvalidMessage is returned for messages that cannot be sent as given
+var ErrInvalidMessage = errors.New("mail: invalid message")
+
+// format renders a message with its headers, ready for an SMTP DATA command.
+// Headers cannot carry line breaks, so no one can inject their own through
+// an address or subject.
+func format(from string, msg Message, now time.Time) ([]byte, error) {
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return nil, fmt.Errorf("%w: line break in a header", ErrInvalidMessage)
+	}
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("%w: recipient %q: %v", ErrInvalidMessage, msg.To, err)
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	domain := "localhost"
+	if address, err := mail.ParseAddress(from); err == nil {
+		if _, host, ok := strings.Cut(address.Address, "@"); ok {
+			domain = host
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", from},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", "<" + hex.EncodeToString(id) + "@" + domain + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	} {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header[0], header[1])
+	}
+	buf.WriteString("\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	body.Write([]byte(strings.ReplaceAll(msg.Text, "\n", "\r\n")))
+	body.Close()
+	return buf.Bytes(), nil
+}
+
+// LogMailer logs messages instead of sending them, so that links can be
+// followed in development without an SMTP server. Never use it in
+// production, where the logs would hold working sign-in links.
+type LogMailer struct {
+	Logger *slog.Logger
+}
+
+// Send logs the message
+func (m LogMailer) Send(ctx context.Context, msg Message) error {
+	m.Logger.InfoContext(ctx, "Email not sent, no SMTP server is configured", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
+
+// Ping always succeeds
+func (m LogMailer) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Outbox keeps the messages it is given, for tests
+type Outbox struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+// Send adds the message to the outbox
+func (o *Outbox) Send(ctx context.Context, msg Message) error {
+	if _, err := format("outbox@localhost", msg, time.Now()); err != nil {
+		return err
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Ping always succeeds
+func (o *Outbox) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first
+func (o *Outbox) Messages() []Message {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]Message(nil), o.messages...)
+}
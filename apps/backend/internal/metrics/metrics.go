@@ -0,0 +1,437 @@
+// Package metrics implements counters, gauges and histograms exposed in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types as written in # TYPE lines
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefaultBuckets are histogram buckets suited to HTTP latencies in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Collector is a metric family that can be written to the exposition format
+type Collector interface {
+	// Name returns the metric family name
+	Name() string
+	// write writes the family's HELP, TYPE and sample lines
+	write(w io.Writer)
+}
+
+// Registry holds the collectors exposed by an endpoint
+type Registry struct {
+	collectors map[string]Collector
+	mutex      sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]Collector),
+	}
+}
+
+// MustRegister adds collectors to the registry. It panics on duplicate names,
+// which is a programming error.
+func (r *Registry) MustRegister(collectors ...Collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, c := range collectors {
+		if _, exists := r.collectors[c.Name()]; exists {
+			panic(fmt.Sprintf("metrics: duplicate collector %q", c.Name()))
+		}
+		r.collectors[c.Name()] = c
+	}
+}
+
+// Write writes every registered family in name order
+func (r *Registry) Write(w io.Writer) {
+	r.mutex.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]Collector, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		collectors[i] = r.collectors[name]
+	}
+	r.mutex.RUnlock()
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// ServeHTTP serves the registry in the Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var b strings.Builder
+	r.Write(&b)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	io.WriteString(w, b.String())
+}
+
+// desc holds the metadata shared by all metric types
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// Name returns the metric family name
+func (d *desc) Name() string {
+	return d.name
+}
+
+// writeHeader writes the HELP and TYPE lines
+func (d *desc) writeHeader(w io.Writer, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, metricType)
+}
+
+// key joins label values into a map key
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelString formats label pairs, e.g. {method="GET",status="200"}
+func labelString(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// escapeLabel escapes a label value
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp escapes HELP text
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// formatValue formats a sample value
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// series is a single labelled value
+type series struct {
+	labels []string
+	value  float64
+}
+
+// vec stores labelled float values shared by counters and gauges
+type vec struct {
+	desc
+	values map[string]*series
+	mutex  sync.Mutex
+}
+
+// add adds delta to the series with the given label values
+func (v *vec) add(delta float64, values []string) {
+	key := v.key(values)
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	s, exists := v.values[key]
+	if !exists {
+		s = &series{labels: append([]string(nil), values...)}
+		v.values[key] = s
+	}
+	s.value += delta
+}
+
+// set sets the series with the given label values
+func (v *vec) set(value float64, values []string) {
+	key := v.key(values)
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.values[key] = &series{labels: append([]string(nil), values...), value: value}
+}
+
+// get returns the value of the series with the given label values
+func (v *vec) get(values []string) float64 {
+	key := v.key(values)
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if s, exists := v.values[key]; exists {
+		return s.value
+	}
+	return 0
+}
+
+// writeSeries writes all series sorted by label values
+func (v *vec) writeSeries(w io.Writer) {
+	v.mutex.Lock()
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		s := v.values[key]
+		lines[i] = v.name + labelString(v.labels, s.labels) + " " + formatValue(s.value)
+	}
+	v.mutex.Unlock()
+
+	for _, line := range lines {
+		io.WriteString(w, line+"\n")
+	}
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels
+type CounterVec struct {
+	vec
+}
+
+// NewCounterVec creates a counter with the given label names
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{vec{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]*series)}}
+}
+
+// Inc increments the counter for the given label values
+func (c *CounterVec) Inc(values ...string) {
+	c.add(1, values)
+}
+
+// Add adds a non-negative delta to the counter for the given label values
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.add(delta, values)
+}
+
+// Value returns the counter for the given label values
+func (c *CounterVec) Value(values ...string) float64 {
+	return c.get(values)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.writeHeader(w, typeCounter)
+	c.writeSeries(w)
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels
+type GaugeVec struct {
+	vec
+}
+
+// NewGaugeVec creates a gauge with the given label names
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{vec{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]*series)}}
+}
+
+// Set sets the gauge for the given label values
+func (g *GaugeVec) Set(value float64, values ...string) {
+	g.set(value, values)
+}
+
+// Add adds delta, which may be negative, to the gauge
+func (g *GaugeVec) Add(delta float64, values ...string) {
+	g.add(delta, values)
+}
+
+// Inc increments the gauge for the given label values
+func (g *GaugeVec) Inc(values ...string) {
+	g.add(1, values)
+}
+
+// Dec decrements the gauge for the given label values
+func (g *GaugeVec) Dec(values ...string) {
+	g.add(-1, values)
+}
+
+// Value returns the gauge for the given label values
+func (g *GaugeVec) Value(values ...string) float64 {
+	return g.get(values)
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.writeHeader(w, typeGauge)
+	g.writeSeries(w)
+}
+
+// funcMetric computes its samples at scrape time
+type funcMetric struct {
+	desc
+	metricType string
+	collect    func(emit func(value float64, labelValues ...string))
+}
+
+func (f *funcMetric) write(w io.Writer) {
+	samples := NewGaugeVec(f.name, f.help, f.labels...)
+	f.collect(func(value float64, labelValues ...string) {
+		samples.Add(value, labelValues...)
+	})
+
+	f.writeHeader(w, f.metricType)
+	samples.writeSeries(w)
+}
+
+// GaugeFunc is a gauge whose samples are computed at scrape time, such as
+// counts read from a repository
+type GaugeFunc struct {
+	funcMetric
+}
+
+// NewGaugeFunc creates a gauge that calls collect on every scrape. collect
+// reports each sample by calling emit.
+func NewGaugeFunc(name, help string, labels []string, collect func(emit func(value float64, labelValues ...string))) *GaugeFunc {
+	return &GaugeFunc{funcMetric{desc: desc{name: name, help: help, labels: labels}, metricType: typeGauge, collect: collect}}
+}
+
+// CounterFunc is a counter whose samples are read at scrape time from a
+// source that already counts, such as the job scheduler
+type CounterFunc struct {
+	funcMetric
+}
+
+// NewCounterFunc creates a counter that calls collect on every scrape.
+// collect reports each sample by calling emit.
+func NewCounterFunc(name, help string, labels []string, collect func(emit func(value float64, labelValues ...string))) *CounterFunc {
+	return &CounterFunc{funcMetric{desc: desc{name: name, help: help, labels: labels}, metricType: typeCounter, collect: collect}}
+}
+
+// histogramSeries holds the bucket counts for one label combination
+type histogramSeries struct {
+	labels []string
+	counts []uint64 // Cumulative counts are computed when written
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec samples observations into buckets, partitioned by labels
+type HistogramVec struct {
+	desc
+	buckets []float64
+	values  map[string]*histogramSeries
+	mutex   sync.Mutex
+}
+
+// NewHistogramVec creates a histogram with the given upper bucket bounds,
+// which must be sorted, and label names
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramSeries),
+	}
+}
+
+// Observe records a value for the given label values
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	key := h.key(values)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	s, exists := h.values[key]
+	if !exists {
+		s = &histogramSeries{labels: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = s
+	}
+
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+// Count returns the number of observations for the given label values
+func (h *HistogramVec) Count(values ...string) uint64 {
+	key := h.key(values)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if s, exists := h.values[key]; exists {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mutex.Lock()
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	bucketLabels := append(append([]string(nil), h.labels...), "le")
+	for _, key := range keys {
+		s := h.values[key]
+		labelValues := append(append([]string(nil), s.labels...), "")
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			labelValues[len(labelValues)-1] = formatValue(bound)
+			lines = append(lines, fmt.Sprintf("%s_bucket%s %d", h.name, labelString(bucketLabels, labelValues), cumulative))
+		}
+		labelValues[len(labelValues)-1] = "+Inf"
+		lines = append(lines,
+			fmt.Sprintf("%s_bucket%s %d", h.name, labelString(bucketLabels, labelValues), s.count),
+			fmt.Sprintf("%s_sum%s %s", h.name, labelString(h.labels, s.labels), formatValue(s.sum)),
+			fmt.Sprintf("%s_count%s %d", h.name, labelString(h.labels, s.labels), s.count),
+		)
+	}
+	h.mutex.Unlock()
+
+	h.writeHeader(w, typeHistogram)
+	for _, line := range lines {
+		io.WriteString(w, line+"\n")
+	}
+}
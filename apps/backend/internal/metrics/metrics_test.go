@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test the text exposition output of each metric type
+func TestExpositionFormat(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := NewCounterVec("test_requests_total", "Requests served.", "method", "path")
+	requests.Inc("GET", "/")
+	requests.Add(2, "POST", `/quote"d\path`)
+
+	inFlight := NewGaugeVec("test_in_flight", "In-flight requests.")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+
+	latency := NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.5, "/a")
+	latency.Observe(5, "/a")
+
+	users := NewGaugeFunc("test_users", "Users by role.", []string{"role"}, func(emit func(float64, ...string)) {
+		emit(3, "admin")
+		emit(10, "participant")
+	})
+
+	registry.MustRegister(requests, inFlight, latency, users)
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+
+	want := `# HELP test_in_flight In-flight requests.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/a",le="0.1"} 1
+test_latency_seconds_bucket{route="/a",le="1"} 2
+test_latency_seconds_bucket{route="/a",le="+Inf"} 3
+test_latency_seconds_sum{route="/a"} 5.55
+test_latency_seconds_count{route="/a"} 3
+# HELP test_requests_total Requests served.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",path="/"} 1
+test_requests_total{method="POST",path="/quote\"d\\path"} 2
+# HELP test_users Users by role.
+# TYPE test_users gauge
+test_users{role="admin"} 3
+test_users{role="participant"} 10
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("Unexpected exposition output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// Test misuse that indicates programming errors
+func TestRegistryPanics(t *testing.T) {
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+
+	registry := NewRegistry()
+	registry.MustRegister(NewCounterVec("dup_total", "Duplicate."))
+	expectPanic("duplicate name", func() { registry.MustRegister(NewCounterVec("dup_total", "Duplicate.")) })
+
+	counter := NewCounterVec("labelled_total", "Labelled.", "a")
+	expectPanic("wrong label count", func() { counter.Inc() })
+	expectPanic("negative counter delta", func() { counter.Add(-1, "x") })
+}
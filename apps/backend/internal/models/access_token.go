@@ -0,0 +1,143 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scope is a permission a personal access token can be granted. Sessions
+// hold every scope.
+type Scope string
+
+const (
+	ScopeReadProfile        Scope = "read:profile"        // Read the user, profile and registrations
+	ScopeWriteProfile       Scope = "write:profile"       // Update the profile
+	ScopeWriteRegistrations Scope = "write:registrations" // Register for and cancel competitions
+	ScopeSubmitScores       Scope = "submit:scores"       // Submit competition scores
+)
+
+// Scopes lists every scope in the order they are presented to users
+var Scopes = []Scope{ScopeReadProfile, ScopeWriteProfile, ScopeWriteRegistrations, ScopeSubmitScores}
+
+// AccessTokenPrefix starts every personal access token, so that they can be
+// told apart from session tokens and found by secret scanners
+const AccessTokenPrefix = "cpat_"
+
+// Access token limits
+const (
+	MaxAccessTokensPerUser   = 20
+	MaxAccessTokenNameLength = 100
+	accessTokenDisplayLength = len(AccessTokenPrefix) + 6
+)
+
+// AccessToken is a personal access token a user created to call the API from
+// scripts. Only TokenHash is persisted; Token holds the plaintext token
+// transiently so it can be shown to the user once.
+type AccessToken struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Name       string    `json:"name" db:"name"`
+	Token      string    `json:"-" db:"-"`
+	TokenHash  string    `json:"-" db:"token_hash"`
+	Prefix     string    `json:"prefix" db:"prefix"` // Start of the token, to recognise it by
+	Scopes     []Scope   `json:"scopes" db:"scopes"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`     // Zero when the token never expires
+	LastUsedAt time.Time `json:"last_used_at" db:"last_used_at"` // Zero until first used; refreshed at most once per LastSeenResolution
+}
+
+// AccessTokenRepository defines the interface for access token data operations
+type AccessTokenRepository interface {
+	Create(token *AccessToken) error
+	GetByToken(token string) (*AccessToken, error)
+	GetByUserID(userID string) ([]*AccessToken, error)
+	Delete(id string) error
+	Touch(token string, usedAt time.Time) error
+}
+
+// Access token errors
+var (
+	ErrAccessTokenNotFound     = &Error{Kind: KindNotFound, Code: "access_token_not_found", Message: "Access token not found"}
+	ErrAccessTokenExpired      = &Error{Kind: KindUnauthorized, Code: "access_token_expired", Message: "Access token has expired"}
+	ErrAccessTokenNameRequired = &Error{Kind: KindInvalid, Code: "access_token_name_required", Field: "name", Message: "Token name is required"}
+	ErrAccessTokenNameTooLong  = &Error{Kind: KindInvalid, Code: "access_token_name_too_long", Field: "name", Message: "Token name must be at most 100 characters"}
+	ErrScopesRequired          = &Error{Kind: KindInvalid, Code: "scopes_required", Field: "scopes", Message: "Select at least one scope"}
+	ErrInvalidScope            = &Error{Kind: KindInvalid, Code: "scope_invalid", Field: "scopes", Message: "Unknown scope"}
+	ErrInvalidTokenExpiry      = &Error{Kind: KindInvalid, Code: "access_token_expiry_invalid", Field: "expires_at", Message: "Expiry must be in the future"}
+	ErrAccessTokenLimit        = &Error{Kind: KindConflict, Code: "access_token_limit", Message: "You have reached the maximum of 20 access tokens"}
+)
+
+// NewAccessToken creates a token with a random secret for the user. A zero
+// expiresAt creates a token that never expires.
+func NewAccessToken(userID, name string, scopes []Scope, expiresAt time.Time) (*AccessToken, error) {
+	secret := make([]byte, 32) // 256 bits
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := AccessTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	accessToken := &AccessToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Token:     token,
+		TokenHash: HashSessionToken(token),
+		Prefix:    token[:accessTokenDisplayLength],
+		Scopes:    slices.Clone(scopes),
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := accessToken.Validate(); err != nil {
+		return nil, err
+	}
+	return accessToken, nil
+}
+
+// IsAccessToken reports whether a bearer token is a personal access token
+// rather than a session token
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// IsExpired checks if the token has expired
+func (t *AccessToken) IsExpired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().After(t.ExpiresAt)
+}
+
+// HasScope reports whether the token was granted a scope
+func (t *AccessToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Validate validates the token data, reporting every invalid field
+func (t *AccessToken) Validate() error {
+	if t.UserID == "" {
+		return ErrInvalidUserID
+	}
+	if t.TokenHash == "" {
+		return ErrInvalidToken
+	}
+
+	var errs []*Error
+	switch {
+	case t.Name == "":
+		errs = append(errs, ErrAccessTokenNameRequired)
+	case len(t.Name) > MaxAccessTokenNameLength:
+		errs = append(errs, ErrAccessTokenNameTooLong)
+	}
+	if len(t.Scopes) == 0 {
+		errs = append(errs, ErrScopesRequired)
+	}
+	for _, scope := range t.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			errs = append(errs, ErrInvalidScope)
+			break
+		}
+	}
+	if !t.ExpiresAt.IsZero() && !t.ExpiresAt.After(t.CreatedAt) {
+		errs = append(errs, ErrInvalidTokenExpiry)
+	}
+	return NewValidationError(errs...)
+}
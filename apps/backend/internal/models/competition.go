@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// DefaultCompetitionID is the competition the dashboard registers users for
+const DefaultCompetitionID = "compify-2024"
+
+// Competition represents a competition users can register for
+type Competition struct {
+	ID                   string    `json:"id" db:"id"`
+	Name                 string    `json:"name" db:"name"`
+	Description          string    `json:"description" db:"description"`
+	StartsAt             time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt               time.Time `json:"ends_at" db:"ends_at"`
+	RegistrationClosesAt time.Time `json:"registration_closes_at" db:"registration_closes_at"`
+	OpenSignup           bool      `json:"open_signup" db:"open_signup"` // Emailed sign-in links may create accounts
+}
+
+// CompetitionRepository defines the interface for competition data operations
+type CompetitionRepository interface {
+	Create(competition *Competition) error
+	GetByID(id string) (*Competition, error)
+	GetAll() ([]*Competition, error)
+}
+
+// Competition validation errors
+var (
+	ErrCompetitionNotFound = &Error{Kind: KindNotFound, Code: "competition_not_found", Message: "Competition not found"}
+	ErrCompetitionExists   = &Error{Kind: KindConflict, Code: "competition_exists", Message: "Competition already exists"}
+	ErrInvalidCompetition  = &Error{Kind: KindInvalid, Code: "competition_invalid", Message: "Invalid competition"}
+	ErrRegistrationClosed  = &Error{Kind: KindConflict, Code: "registration_closed", Message: "Registration for this competition is closed"}
+)
+
+// Validate validates the competition data
+func (c *Competition) Validate() error {
+	if c.ID == "" {
+		return ErrInvalidCompetitionID
+	}
+	if c.Name == "" || len(c.Name) > 200 {
+		return ErrInvalidCompetition
+	}
+	if !c.EndsAt.IsZero() && c.EndsAt.Before(c.StartsAt) {
+		return ErrInvalidCompetition
+	}
+	return nil
+}
+
+// RegistrationOpen reports whether users can still register at the given
+// time. A zero closing time leaves registration open.
+func (c *Competition) RegistrationOpen(now time.Time) bool {
+	return c.RegistrationClosesAt.IsZero() || now.Before(c.RegistrationClosesAt)
+}
+
+// AllowsLinkSignup reports whether signing in with an emailed link requested
+// for the competition may create an account at the given time
+func (c *Competition) AllowsLinkSignup(now time.Time) bool {
+	return c.OpenSignup && c.RegistrationOpen(now)
+}
@@ -7,10 +7,16 @@ import (
 
 // DashboardData represents the data displayed on the user dashboard
 type DashboardData struct {
-	User          User           `json:"user"`
-	Registration  *Registration  `json:"registration"`
-	Announcements []Announcement `json:"announcements"`
-	Stats         UserStats      `json:"stats"`
+	User             User           `json:"user"`
+	Registration     *Registration  `json:"registration"`
+	Announcements    []Announcement `json:"announcements"`
+	Stats            UserStats      `json:"stats"`
+	Sessions         []Session      `json:"sessions"`
+	CurrentSessionID string         `json:"current_session_id"`
+	AccessTokens     []AccessToken  `json:"access_tokens"`
+	Identities       []Identity     `json:"identities"`
+	Providers        []string       `json:"providers"`      // OpenID Connect providers that can be linked
+	IdentityError    string         `json:"identity_error"` // Why linking an identity failed, if it did
 }
 
 // Announcement represents a competition announcement
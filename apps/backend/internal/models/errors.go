@@ -0,0 +1,76 @@
+package models
+
+import "strings"
+
+// ErrorKind classifies domain errors by how a caller should react to them.
+// The server maps each kind to an HTTP status.
+type ErrorKind int
+
+const (
+	KindInternal     ErrorKind = iota // Unexpected failure
+	KindInvalid                       // The request is malformed or fails validation
+	KindUnauthorized                  // Credentials are missing or wrong
+	KindForbidden                     // The caller may not do this
+	KindNotFound                      // The resource does not exist
+	KindConflict                      // The request conflicts with the current state
+	KindRateLimited                   // Too many attempts; try again later
+)
+
+// Error is a domain error with a stable, machine-readable code. Clients
+// match on Code, which never changes once published, while Message is for
+// people and may be reworded.
+type Error struct {
+	Kind    ErrorKind
+	Code    string // Such as "email_taken"
+	Field   string // Request field at fault, empty when none is
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors with the same code, so that a copy made by WithField
+// still matches the sentinel it came from
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithField returns a copy of the error attributed to a request field
+func (e *Error) WithField(field string) *Error {
+	copy := *e
+	copy.Field = field
+	return &copy
+}
+
+// ValidationError reports every invalid field of a request at once
+type ValidationError struct {
+	Errors []*Error
+}
+
+// NewValidationError returns a ValidationError for errs, or nil when there
+// are none
+func NewValidationError(errs ...*Error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap lets errors.Is find the field errors
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Identity links a user to an account at an OpenID Connect provider, which
+// the user can then sign in with. The provider's subject identifies the
+// account; the email is as the provider last reported it.
+type Identity struct {
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	Provider    string    `json:"provider" db:"provider"`
+	Subject     string    `json:"-" db:"subject"`
+	Email       string    `json:"email" db:"email"`
+	LinkedAt    time.Time `json:"linked_at" db:"linked_at"`
+	LastLoginAt time.Time `json:"last_login_at" db:"last_login_at"` // Zero until first used to sign in
+}
+
+// IdentityRepository defines the interface for linked identity data operations
+type IdentityRepository interface {
+	Create(identity *Identity) error
+	GetByProviderSubject(provider, subject string) (*Identity, error)
+	GetByUserID(userID string) ([]*Identity, error)
+	Update(identity *Identity) error
+	Delete(id string) error
+}
+
+// Linked identity errors
+var (
+	ErrIdentityNotFound      = &Error{Kind: KindNotFound, Code: "identity_not_found", Message: "Linked account not found"}
+	ErrIdentityLinked        = &Error{Kind: KindConflict, Code: "identity_linked", Message: "This account is already linked to another Compify user"}
+	ErrProviderAlreadyLinked = &Error{Kind: KindConflict, Code: "provider_already_linked", Message: "Another account from this provider is already linked"}
+	ErrEmailNotVerified      = &Error{Kind: KindForbidden, Code: "email_not_verified", Message: "The identity provider has not verified your email address"}
+	ErrLastSignInMethod      = &Error{Kind: KindConflict, Code: "last_sign_in_method", Message: "Link another account before unlinking your only way to sign in"}
+	ErrInvalidIdentity       = &Error{Kind: KindInvalid, Code: "identity_invalid", Message: "Invalid linked account"}
+)
+
+// Validate validates the identity data
+func (i *Identity) Validate() error {
+	if i.UserID == "" {
+		return ErrInvalidUserID
+	}
+	if i.Provider == "" || i.Subject == "" {
+		return ErrInvalidIdentity
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"crypto/subtle"
+	"strings"
+	"time"
+)
+
+// LoginLinkLifetime is how long an emailed sign-in link can be used
+const LoginLinkLifetime = 15 * time.Minute
+
+// LoginLink is a single-use sign-in link emailed to a user. It only works in
+// the browser that requested it, identified by a random value kept in a
+// cookie there; link scanners and anyone the email is forwarded to cannot
+// use it. Only hashes of the link token and browser value are stored.
+type LoginLink struct {
+	ID            string    `json:"id" db:"id"`
+	Email         string    `json:"email" db:"email"`
+	Token         string    `json:"-" db:"-"` // Plaintext, only set when created
+	TokenHash     string    `json:"-" db:"token_hash"`
+	BrowserHash   string    `json:"-" db:"browser_hash"`
+	CompetitionID string    `json:"competition_id,omitempty" db:"competition_id"` // Competition whose page the link was requested from
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at" db:"expires_at"`
+	UsedAt        time.Time `json:"used_at" db:"used_at"` // Zero until used
+}
+
+// LoginLinkRepository defines the interface for sign-in link data operations
+type LoginLinkRepository interface {
+	Create(link *LoginLink) error
+	GetByToken(token string) (*LoginLink, error)
+	MarkUsed(id string, now time.Time) error
+	DeleteExpired() error
+}
+
+// Sign-in link errors
+var (
+	ErrLoginLinkInvalid      = &Error{Kind: KindUnauthorized, Code: "login_link_invalid", Message: "This sign-in link is invalid, expired or already used. Please request a new one."}
+	ErrLoginLinkOtherBrowser = &Error{Kind: KindForbidden, Code: "login_link_other_browser", Message: "Open the sign-in link in the browser you requested it from"}
+	ErrLoginLinkThrottled    = &Error{Kind: KindRateLimited, Code: "login_link_throttled", Message: "Too many sign-in links were requested for this address. Please try again later."}
+)
+
+// NewLoginLink creates a sign-in link for an email address, bound to the
+// browser holding browserToken
+func NewLoginLink(email, browserToken, competitionID string) (*LoginLink, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, NewValidationError(ErrEmailRequired)
+	}
+	if !emailRegex.MatchString(email) {
+		return nil, NewValidationError(ErrInvalidEmail)
+	}
+	if browserToken == "" {
+		return nil, ErrLoginLinkOtherBrowser
+	}
+
+	token, err := GenerateSessionToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &LoginLink{
+		Email:         email,
+		Token:         token,
+		TokenHash:     HashSessionToken(token),
+		BrowserHash:   HashSessionToken(browserToken),
+		CompetitionID: competitionID,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(LoginLinkLifetime),
+	}, nil
+}
+
+// Usable reports whether the link has neither been used nor expired
+func (l *LoginLink) Usable(now time.Time) bool {
+	return l.UsedAt.IsZero() && now.Before(l.ExpiresAt)
+}
+
+// MatchesBrowser reports whether browserToken is the value of the browser
+// that requested the link
+func (l *LoginLink) MatchesBrowser(browserToken string) bool {
+	return browserToken != "" && subtle.ConstantTimeCompare([]byte(HashSessionToken(browserToken)), []byte(l.BrowserHash)) == 1
+}
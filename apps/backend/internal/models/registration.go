@@ -2,7 +2,6 @@ package models
 
 import (
 	"encoding/json"
-	"errors"
 	"time"
 )
 
@@ -37,14 +36,23 @@ type RegistrationRepository interface {
 	Update(registration *Registration) error
 	Delete(id string) error
 	UpdateStatus(id string, status RegistrationStatus) error
+	CountByCompetitionAndStatus() ([]RegistrationCount, error)
+}
+
+// RegistrationCount is the number of registrations with a given status for a
+// competition
+type RegistrationCount struct {
+	CompetitionID string
+	Status        RegistrationStatus
+	Count         int
 }
 
 // Registration validation errors
 var (
-	ErrInvalidRegistrationStatus = errors.New("invalid registration status")
-	ErrInvalidCompetitionID      = errors.New("invalid competition ID")
-	ErrRegistrationExists        = errors.New("registration already exists")
-	ErrRegistrationNotFound      = errors.New("registration not found")
+	ErrInvalidRegistrationStatus = &Error{Kind: KindInvalid, Code: "registration_status_invalid", Field: "status", Message: "Invalid registration status"}
+	ErrInvalidCompetitionID      = &Error{Kind: KindInvalid, Code: "competition_id_invalid", Field: "competition_id", Message: "Invalid competition ID"}
+	ErrRegistrationExists        = &Error{Kind: KindConflict, Code: "registration_exists", Message: "Already registered for this competition"}
+	ErrRegistrationNotFound      = &Error{Kind: KindNotFound, Code: "registration_not_found", Message: "Registration not found"}
 )
 
 // Valid registration statuses
@@ -2,20 +2,25 @@ package models
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
-	"errors"
 	"time"
 )
 
-// Session represents a user session
+// Session represents a user session. Only TokenHash is persisted; Token holds
+// the plaintext token transiently so it can be handed to the client once.
 type Session struct {
-	ID        string    `json:"id" db:"id"`
-	UserID    string    `json:"user_id" db:"user_id"`
-	Token     string    `json:"token" db:"token"`
-	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	IPAddress string    `json:"ip_address" db:"ip_address"`
-	UserAgent string    `json:"user_agent" db:"user_agent"`
+	ID                string        `json:"id" db:"id"`
+	UserID            string        `json:"user_id" db:"user_id"`
+	Token             string        `json:"-" db:"-"`
+	TokenHash         string        `json:"-" db:"token_hash"`
+	ExpiresAt         time.Time     `json:"expires_at" db:"expires_at"` // Idle expiry, capped by AbsoluteExpiresAt
+	AbsoluteExpiresAt time.Time     `json:"absolute_expires_at" db:"absolute_expires_at"`
+	IdleTimeout       time.Duration `json:"-" db:"idle_timeout"`
+	CreatedAt         time.Time     `json:"created_at" db:"created_at"`
+	IPAddress         string        `json:"ip_address" db:"ip_address"`
+	UserAgent         string        `json:"user_agent" db:"user_agent"`
+	LastSeenAt        time.Time     `json:"last_seen_at" db:"last_seen_at"` // Refreshed at most once per LastSeenResolution
 }
 
 // SessionRepository defines the interface for session data operations
@@ -28,39 +33,72 @@ type SessionRepository interface {
 	DeleteByToken(token string) error
 	DeleteByUserID(userID string) error
 	DeleteExpired() error
+	Touch(token string, seenAt time.Time) error
+	ReplaceToken(oldToken, newToken string) (*Session, error)
+	CountActive() (int, error)
 }
 
 // Session validation errors
 var (
-	ErrInvalidToken     = errors.New("invalid session token")
-	ErrSessionExpired   = errors.New("session has expired")
-	ErrSessionNotFound  = errors.New("session not found")
-	ErrInvalidUserID    = errors.New("invalid user ID")
+	ErrInvalidToken    = &Error{Kind: KindUnauthorized, Code: "session_token_invalid", Message: "Invalid session token"}
+	ErrSessionExpired  = &Error{Kind: KindUnauthorized, Code: "session_expired", Message: "Session has expired"}
+	ErrSessionNotFound = &Error{Kind: KindNotFound, Code: "session_not_found", Message: "Session not found"}
+	ErrInvalidUserID   = &Error{Kind: KindInvalid, Code: "user_id_invalid", Field: "user_id", Message: "Invalid user ID"}
 )
 
-// Default session duration
+// Default session duration. This is the absolute lifetime of a session,
+// regardless of activity.
 const DefaultSessionDuration = 24 * time.Hour * 7 // 7 days
 
-// NewSession creates a new session with a random token
+// DefaultIdleTimeout is how long a session survives without any activity
+const DefaultIdleTimeout = 24 * time.Hour
+
+// SessionPolicy controls how long sessions live
+type SessionPolicy struct {
+	IdleTimeout      time.Duration
+	AbsoluteLifetime time.Duration
+}
+
+// DefaultSessionPolicy is the policy used by NewSession
+var DefaultSessionPolicy = SessionPolicy{
+	IdleTimeout:      DefaultIdleTimeout,
+	AbsoluteLifetime: DefaultSessionDuration,
+}
+
+// LastSeenResolution is the granularity at which session activity is recorded.
+// Requests within this window of the previous one do not update LastSeenAt.
+const LastSeenResolution = time.Minute
+
+// NewSession creates a new session with a random token using DefaultSessionPolicy
 func NewSession(userID, ipAddress, userAgent string) (*Session, error) {
+	return NewSessionWithPolicy(userID, ipAddress, userAgent, DefaultSessionPolicy)
+}
+
+// NewSessionWithPolicy creates a new session with a random token whose idle and
+// absolute expiry follow the given policy
+func NewSessionWithPolicy(userID, ipAddress, userAgent string, policy SessionPolicy) (*Session, error) {
 	if userID == "" {
 		return nil, ErrInvalidUserID
 	}
 
-	token, err := generateSecureToken()
+	token, err := GenerateSessionToken()
 	if err != nil {
 		return nil, err
 	}
 
 	now := time.Now()
 	session := &Session{
-		UserID:    userID,
-		Token:     token,
-		ExpiresAt: now.Add(DefaultSessionDuration),
-		CreatedAt: now,
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
+		UserID:            userID,
+		Token:             token,
+		TokenHash:         HashSessionToken(token),
+		AbsoluteExpiresAt: now.Add(policy.AbsoluteLifetime),
+		IdleTimeout:       policy.IdleTimeout,
+		CreatedAt:         now,
+		IPAddress:         ipAddress,
+		UserAgent:         userAgent,
+		LastSeenAt:        now,
 	}
+	session.ExpiresAt = session.capExpiry(now.Add(policy.IdleTimeout))
 
 	return session, nil
 }
@@ -72,17 +110,50 @@ func (s *Session) IsExpired() bool {
 
 // IsValid checks if the session is valid (not expired and has valid token)
 func (s *Session) IsValid() bool {
-	return !s.IsExpired() && s.Token != ""
+	return !s.IsExpired() && (s.Token != "" || s.TokenHash != "")
 }
 
-// Extend extends the session expiration time
+// Extend extends the session expiration time, never past its absolute expiry
 func (s *Session) Extend(duration time.Duration) {
-	s.ExpiresAt = time.Now().Add(duration)
+	s.ExpiresAt = s.capExpiry(time.Now().Add(duration))
 }
 
-// ExtendDefault extends the session with default duration
+// ExtendDefault extends the session by its idle timeout
 func (s *Session) ExtendDefault() {
-	s.Extend(DefaultSessionDuration)
+	if s.IdleTimeout > 0 {
+		s.Extend(s.IdleTimeout)
+		return
+	}
+	s.Extend(DefaultIdleTimeout)
+}
+
+// RecordActivity marks the session as seen at the given time and slides its
+// idle expiry forward
+func (s *Session) RecordActivity(at time.Time) {
+	if at.After(s.LastSeenAt) {
+		s.LastSeenAt = at
+	}
+
+	idle := s.IdleTimeout
+	if idle <= 0 {
+		idle = DefaultIdleTimeout
+	}
+	if expiry := s.capExpiry(at.Add(idle)); expiry.After(s.ExpiresAt) {
+		s.ExpiresAt = expiry
+	}
+}
+
+// capExpiry limits an expiry time to the session's absolute expiry
+func (s *Session) capExpiry(expiry time.Time) time.Time {
+	if !s.AbsoluteExpiresAt.IsZero() && expiry.After(s.AbsoluteExpiresAt) {
+		return s.AbsoluteExpiresAt
+	}
+	return expiry
+}
+
+// Device returns the parsed device and browser information for the session
+func (s *Session) Device() DeviceInfo {
+	return ParseUserAgent(s.UserAgent)
 }
 
 // Validate validates the session data
@@ -90,7 +161,7 @@ func (s *Session) Validate() error {
 	if s.UserID == "" {
 		return ErrInvalidUserID
 	}
-	if s.Token == "" {
+	if s.Token == "" && s.TokenHash == "" {
 		return ErrInvalidToken
 	}
 	if s.IsExpired() {
@@ -99,8 +170,15 @@ func (s *Session) Validate() error {
 	return nil
 }
 
-// generateSecureToken generates a cryptographically secure random token
-func generateSecureToken() (string, error) {
+// HashSessionToken returns the SHA-256 digest under which a session token is
+// stored. Plaintext tokens are never persisted.
+func HashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateSessionToken generates a cryptographically secure random token
+func GenerateSessionToken() (string, error) {
 	bytes := make([]byte, 32) // 256 bits
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
@@ -1,7 +1,6 @@
 package models
 
 import (
-	"errors"
 	"regexp"
 	"strings"
 	"time"
@@ -13,11 +12,26 @@ type User struct {
 	Email        string    `json:"email" db:"email"`
 	Username     string    `json:"username" db:"username"`
 	PasswordHash string    `json:"-" db:"password_hash"`
+	Role         Role      `json:"role" db:"role"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 	Profile      Profile   `json:"profile"`
 }
 
+// Role represents a user's privilege level
+type Role string
+
+const (
+	RoleParticipant Role = "participant"
+	RoleAdmin       Role = "admin"
+)
+
+// Valid user roles
+var validRoles = map[Role]bool{
+	RoleParticipant: true,
+	RoleAdmin:       true,
+}
+
 // Profile represents user profile information
 type Profile struct {
 	UserID    string `json:"user_id" db:"user_id"`
@@ -41,12 +55,19 @@ type UserRepository interface {
 
 // Validation errors
 var (
-	ErrInvalidEmail    = errors.New("invalid email format")
-	ErrInvalidUsername = errors.New("invalid username format")
-	ErrEmailTooLong    = errors.New("email too long")
-	ErrUsernameTooLong = errors.New("username too long")
-	ErrBioTooLong      = errors.New("bio too long")
-	ErrNameTooLong     = errors.New("name too long")
+	ErrEmailRequired    = &Error{Kind: KindInvalid, Code: "email_required", Field: "email", Message: "Email is required"}
+	ErrInvalidEmail     = &Error{Kind: KindInvalid, Code: "email_invalid", Field: "email", Message: "Please enter a valid email address"}
+	ErrEmailTooLong     = &Error{Kind: KindInvalid, Code: "email_too_long", Field: "email", Message: "Email must be at most 255 characters"}
+	ErrEmailTaken       = &Error{Kind: KindConflict, Code: "email_taken", Field: "email", Message: "Email is already registered"}
+	ErrUsernameRequired = &Error{Kind: KindInvalid, Code: "username_required", Field: "username", Message: "Username is required"}
+	ErrInvalidUsername  = &Error{Kind: KindInvalid, Code: "username_invalid", Field: "username", Message: "Username must be 3-30 characters and contain only letters, numbers, underscores, and hyphens"}
+	ErrUsernameTooLong  = &Error{Kind: KindInvalid, Code: "username_too_long", Field: "username", Message: "Username must be at most 30 characters"}
+	ErrUsernameTaken    = &Error{Kind: KindConflict, Code: "username_taken", Field: "username", Message: "Username is already taken"}
+	ErrBioTooLong       = &Error{Kind: KindInvalid, Code: "bio_too_long", Field: "bio", Message: "Bio must be at most 1000 characters"}
+	ErrNameTooLong      = &Error{Kind: KindInvalid, Code: "name_too_long", Message: "Names must be at most 100 characters"}
+	ErrInvalidRole      = &Error{Kind: KindInvalid, Code: "role_invalid", Field: "role", Message: "Invalid role"}
+	ErrUserNotFound     = &Error{Kind: KindNotFound, Code: "user_not_found", Message: "User not found"}
+	ErrProfileNotFound  = &Error{Kind: KindNotFound, Code: "profile_not_found", Message: "Profile not found"}
 )
 
 // Email validation regex
@@ -59,7 +80,7 @@ var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,30}$`)
 func (u *User) Validate() error {
 	// Email validation
 	if u.Email == "" {
-		return errors.New("email is required")
+		return ErrEmailRequired
 	}
 	if len(u.Email) > 255 {
 		return ErrEmailTooLong
@@ -70,7 +91,7 @@ func (u *User) Validate() error {
 
 	// Username validation
 	if u.Username == "" {
-		return errors.New("username is required")
+		return ErrUsernameRequired
 	}
 	if len(u.Username) > 30 {
 		return ErrUsernameTooLong
@@ -79,24 +100,40 @@ func (u *User) Validate() error {
 		return ErrInvalidUsername
 	}
 
+	// Role validation (empty means the default participant role)
+	if u.Role != "" && !validRoles[u.Role] {
+		return ErrInvalidRole
+	}
+
 	return nil
 }
 
+// IsAdmin checks if the user has administrative privileges
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
 // Sanitize sanitizes user input data
 func (u *User) Sanitize() {
 	u.Email = strings.TrimSpace(strings.ToLower(u.Email))
 	u.Username = strings.TrimSpace(u.Username)
 }
 
+// Profile field length limits
+const (
+	MaxNameLength = 100
+	MaxBioLength  = 1000
+)
+
 // Validate validates the profile data
 func (p *Profile) Validate() error {
-	if len(p.FirstName) > 100 {
+	if len(p.FirstName) > MaxNameLength {
 		return ErrNameTooLong
 	}
-	if len(p.LastName) > 100 {
+	if len(p.LastName) > MaxNameLength {
 		return ErrNameTooLong
 	}
-	if len(p.Bio) > 1000 {
+	if len(p.Bio) > MaxBioLength {
 		return ErrBioTooLong
 	}
 	return nil
@@ -0,0 +1,76 @@
+package models
+
+import "strings"
+
+// DeviceInfo describes the client behind a User-Agent string
+type DeviceInfo struct {
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	Mobile  bool   `json:"mobile"`
+}
+
+// String returns a short human readable description such as "Firefox on Linux"
+func (d DeviceInfo) String() string {
+	return d.Browser + " on " + d.OS
+}
+
+// Browser signatures, checked in order. Chromium derivatives must come before
+// Chrome, and Chrome before Safari, because their User-Agents contain both tokens.
+var browserSignatures = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"Go-http-client/", "Go HTTP client"},
+}
+
+// OS signatures, checked in order. iOS and Android must come before macOS and
+// Linux respectively.
+var osSignatures = []struct {
+	token string
+	name  string
+}{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// ParseUserAgent extracts browser and operating system information from a
+// User-Agent header. Unrecognised values are reported as "Unknown".
+func ParseUserAgent(userAgent string) DeviceInfo {
+	info := DeviceInfo{
+		Browser: "Unknown browser",
+		OS:      "Unknown OS",
+	}
+
+	for _, sig := range browserSignatures {
+		if strings.Contains(userAgent, sig.token) {
+			info.Browser = sig.name
+			break
+		}
+	}
+
+	for _, sig := range osSignatures {
+		if strings.Contains(userAgent, sig.token) {
+			info.OS = sig.name
+			break
+		}
+	}
+
+	info.Mobile = strings.Contains(userAgent, "Mobile") || info.OS == "iOS" || info.OS == "Android"
+
+	return info
+}
@@ -0,0 +1,227 @@
+// Package oidctest is a mock OpenID Connect provider for tests and local
+// development. It signs users in without asking for credentials: the
+// authorization endpoint immediately redirects back with a code for the
+// configured user, and the token endpoint checks the client credentials,
+// redirect URI and PKCE verifier like a real provider would.
+package oidctest
+
+import (
+	"compify-backend/internal/oidc"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// keyID identifies the provider's signing key
+const keyID = "oidctest"
+
+// User is the identity the provider signs users in as
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// authorization is an issued authorization code waiting to be exchanged
+type authorization struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// Provider is a running mock provider. Close it when done.
+type Provider struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key   *rsa.PrivateKey
+	mutex sync.Mutex
+	user  User
+	codes map[string]authorization
+}
+
+// NewProvider starts a mock provider that signs users in as user
+func NewProvider(user User) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oidctest: generating key: " + err.Error())
+	}
+	p := &Provider{
+		ClientID:     "compify-test",
+		ClientSecret: "test-secret",
+		key:          key,
+		user:         user,
+		codes:        make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("GET /authorize", p.handleAuthorize)
+	mux.HandleFunc("POST /token", p.handleToken)
+	mux.HandleFunc("GET /jwks", p.handleJWKS)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// Config returns the configuration of a relying party registered with the
+// provider under name
+func (p *Provider) Config(name string) oidc.Config {
+	return oidc.Config{Name: name, Issuer: p.URL, ClientID: p.ClientID, ClientSecret: p.ClientSecret}
+}
+
+// Spec returns the provider as configured in OIDC_PROVIDERS
+func (p *Provider) Spec(name string) string {
+	u, _ := url.Parse(p.URL)
+	u.User = url.UserPassword(p.ClientID, p.ClientSecret)
+	return name + "=" + u.String()
+}
+
+// SetUser changes the identity the next sign-in authenticates as
+func (p *Provider) SetUser(user User) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.user = user
+}
+
+// SignIDToken signs arbitrary claims with the provider's key, for testing
+// how relying parties handle invalid tokens
+func (p *Provider) SignIDToken(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic("oidctest: signing token: " + err.Error())
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Claims returns the ID token claims the provider issues for a user
+func (p *Provider) Claims(user User, nonce string) map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":            p.URL,
+		"sub":            user.Subject,
+		"aud":            p.ClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"given_name":     user.GivenName,
+		"family_name":    user.FamilyName,
+	}
+}
+
+// handleDiscovery serves the provider metadata
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// handleAuthorize signs the configured user in and redirects back with a code
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("client_id") != p.ClientID || redirectURI == "" {
+		http.Error(w, "unknown client or missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "the code flow with S256 PKCE is required", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	p.mutex.Lock()
+	p.codes[code] = authorization{user: p.user, redirectURI: redirectURI, nonce: query.Get("nonce"), codeChallenge: query.Get("code_challenge")}
+	p.mutex.Unlock()
+
+	callback, _ := url.Parse(redirectURI)
+	values := callback.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	callback.RawQuery = values.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// handleToken exchanges a code for an ID token
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if id, err := url.QueryUnescape(clientID); err == nil {
+		clientID = id
+	}
+	if secret, err := url.QueryUnescape(clientSecret); err == nil {
+		clientSecret = secret
+	}
+	if !ok || clientID != p.ClientID || clientSecret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostFormValue("code")
+	p.mutex.Lock()
+	auth, exists := p.codes[code]
+	delete(p.codes, code) // Codes are single use
+	p.mutex.Unlock()
+
+	switch {
+	case r.PostFormValue("grant_type") != "authorization_code" || !exists:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+	case r.PostFormValue("redirect_uri") != auth.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri does not match"})
+	case oidc.CodeChallenge(r.PostFormValue("code_verifier")) != auth.codeChallenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"access_token": rand.Text(),
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     p.SignIDToken(p.Claims(auth.user, auth.nonce)),
+		})
+	}
+}
+
+// handleJWKS serves the provider's public key
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	publicKey := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}},
+	})
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
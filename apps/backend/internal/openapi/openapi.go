@@ -0,0 +1,254 @@
+// Package openapi describes HTTP APIs as OpenAPI 3.1 documents. Schemas are
+// derived from Go types by reflection, so that a document cannot drift from
+// the types the handlers actually decode and encode.
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version of the documents built here
+const Version = "3.1.0"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API as a whole
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations on a path, keyed by lowercase method
+type PathItem map[string]*Operation
+
+// Operation describes one method on one path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // "path" or "query"
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body an operation accepts
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one possible response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType gives the schema of a body in one media type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// SecurityRequirement names the security schemes an operation accepts
+type SecurityRequirement map[string][]string
+
+// Components holds the schemas and security schemes operations refer to
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes a way of authenticating
+type SecurityScheme struct {
+	Type        string `json:"type"`             // "http" or "apiKey"
+	Scheme      string `json:"scheme,omitempty"` // For http, such as "bearer"
+	In          string `json:"in,omitempty"`     // For apiKey, such as "cookie"
+	Name        string `json:"name,omitempty"`   // For apiKey
+	Description string `json:"description,omitempty"`
+}
+
+// Schema is the subset of JSON Schema needed to describe Go types
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// refPrefix starts the $ref of a component schema
+const refPrefix = "#/components/schemas/"
+
+// Ref returns a reference to the component schema with the given name
+func Ref(name string) *Schema {
+	return &Schema{Ref: refPrefix + name}
+}
+
+// RefName returns the component schema a reference points to, or "" when
+// the schema is not a reference
+func (s *Schema) RefName() string {
+	return strings.TrimPrefix(s.Ref, refPrefix)
+}
+
+// Schemas derives schemas from Go types. Named struct types become
+// components, referred to by $ref, so each is described once.
+type Schemas struct {
+	components map[string]*Schema
+}
+
+// NewSchemas returns an empty set of component schemas
+func NewSchemas() *Schemas {
+	return &Schemas{components: make(map[string]*Schema)}
+}
+
+// For returns the schema of the type of v
+func (s *Schemas) For(v any) *Schema {
+	return s.schema(reflect.TypeOf(v))
+}
+
+// Components returns the component schemas collected so far
+func (s *Schemas) Components() map[string]*Schema {
+	return s.components
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func (s *Schemas) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			// Registered before it is filled in, so recursive types end
+			s.components[t.Name()] = &Schema{}
+			*s.components[t.Name()] = *s.object(t)
+		}
+		return Ref(t.Name())
+	default:
+		// Interfaces, and anything else, may hold any value
+		return &Schema{}
+	}
+}
+
+// object describes a struct by its JSON encoding. Fields marked omitempty
+// and pointer fields are optional; the rest are required.
+func (s *Schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		// The fields of embedded structs are promoted, even when the struct
+		// type itself is unexported
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := s.object(field.Type)
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = s.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// Example returns a value matching schema, with empty strings, zeros and
+// one-element arrays, as a starting point for a request body
+func (c Components) Example(schema *Schema) any {
+	return c.example(schema, make(map[string]bool))
+}
+
+func (c Components) example(schema *Schema, seen map[string]bool) any {
+	if name := schema.RefName(); schema.Ref != "" {
+		if seen[name] || c.Schemas[name] == nil {
+			return nil
+		}
+		seen[name] = true
+		defer delete(seen, name)
+		return c.example(c.Schemas[name], seen)
+	}
+
+	switch schema.Type {
+	case "string":
+		if schema.Format == "date-time" {
+			return time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC).Format(time.RFC3339)
+		}
+		return ""
+	case "boolean":
+		return false
+	case "integer", "number":
+		return 0
+	case "array":
+		return []any{c.example(schema.Items, seen)}
+	case "object":
+		example := make(map[string]any, len(schema.Properties))
+		for name, property := range schema.Properties {
+			example[name] = c.example(property, seen)
+		}
+		return example
+	default:
+		return nil
+	}
+}
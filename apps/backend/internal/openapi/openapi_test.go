@@ -0,0 +1,84 @@
+package openapi
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type node struct {
+	Name     string  `json:"name"`
+	Children []*node `json:"children,omitempty"`
+}
+
+type account struct {
+	address
+	ID       string            `json:"id"`
+	Secret   string            `json:"-"`
+	Nickname *string           `json:"nickname"`
+	Age      int               `json:"age,omitempty"`
+	Tags     []string          `json:"tags"`
+	Extra    map[string]any    `json:"extra"`
+	Created  time.Time         `json:"created"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Tree     node              `json:"tree"`
+	internal string
+}
+
+// Test schemas derived from Go types
+func TestSchemas(t *testing.T) {
+	schemas := NewSchemas()
+	ref := schemas.For(&account{})
+	if ref.RefName() != "account" {
+		t.Fatalf("Expected a reference to account, got %+v", ref)
+	}
+
+	schema := schemas.Components()["account"]
+	for _, name := range []string{"city", "id", "nickname", "age", "tags", "extra", "created", "labels", "tree"} {
+		if schema.Properties[name] == nil {
+			t.Errorf("Missing property %s", name)
+		}
+	}
+	if len(schema.Properties) != 9 {
+		t.Errorf("Expected hidden and unexported fields to be skipped, got %d properties", len(schema.Properties))
+	}
+
+	for name, required := range map[string]bool{"city": true, "id": true, "nickname": false, "age": false, "labels": false, "tree": true} {
+		if slices.Contains(schema.Required, name) != required {
+			t.Errorf("%s: expected required %v", name, required)
+		}
+	}
+
+	if created := schema.Properties["created"]; created.Type != "string" || created.Format != "date-time" {
+		t.Errorf("Expected a date-time, got %+v", created)
+	}
+	if tags := schema.Properties["tags"]; tags.Type != "array" || tags.Items.Type != "string" {
+		t.Errorf("Expected an array of strings, got %+v", tags)
+	}
+	if labels := schema.Properties["labels"]; labels.Type != "object" || labels.AdditionalProperties.Type != "string" {
+		t.Errorf("Expected a map of strings, got %+v", labels)
+	}
+
+	tree := schemas.Components()["node"]
+	if tree == nil || tree.Properties["children"].Items.RefName() != "node" {
+		t.Errorf("Expected a recursive node schema, got %+v", tree)
+	}
+}
+
+// Test examples generated from schemas
+func TestExample(t *testing.T) {
+	schemas := NewSchemas()
+	components := Components{Schemas: schemas.Components()}
+	example, ok := components.Example(schemas.For(node{})).(map[string]any)
+	if !ok || example["name"] != "" {
+		t.Fatalf("Unexpected example %#v", example)
+	}
+	children, ok := example["children"].([]any)
+	if !ok || len(children) != 1 || children[0] != nil {
+		t.Errorf("Expected recursion to stop at the first repeat, got %#v", example["children"])
+	}
+}
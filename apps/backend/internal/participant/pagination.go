@@ -0,0 +1,115 @@
+package participant
+
+import (
+	"compify-backend/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Page sizes for list endpoints
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Pagination errors
+var (
+	ErrInvalidLimit  = &models.Error{Kind: models.KindInvalid, Code: "limit_invalid", Field: "limit", Message: "Limit must be a number between 1 and 100"}
+	ErrInvalidCursor = &models.Error{Kind: models.KindInvalid, Code: "cursor_invalid", Field: "cursor", Message: "Cursor is invalid"}
+)
+
+// PageRequest selects a page of a list. Cursor is the NextCursor of the
+// previous page, empty for the first page; a zero Limit means
+// DefaultPageLimit.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// Page is one page of a list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// cursor is the sort key of the last item of a page. Encoding the key rather
+// than an offset keeps pages stable while items are added or removed.
+type cursor struct {
+	Time time.Time `json:"t"`
+	ID   string    `json:"id"`
+}
+
+// encode returns the cursor as an opaque URL-safe string
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor returned by encode
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.ID == "" {
+		return cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// compareCursors orders keys by time, newest first when descending, then by
+// ID so that items with equal times have a stable order
+func compareCursors(a, b cursor, descending bool) int {
+	c := a.Time.Compare(b.Time)
+	if descending {
+		c = -c
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	return c
+}
+
+// paginate sorts items by key and returns the page the request selects
+func paginate[T any](items []T, key func(T) cursor, descending bool, page PageRequest) (Page[T], error) {
+	limit := page.Limit
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 1 || limit > MaxPageLimit {
+		return Page[T]{}, ErrInvalidLimit
+	}
+
+	slices.SortFunc(items, func(a, b T) int {
+		return compareCursors(key(a), key(b), descending)
+	})
+
+	start := 0
+	if page.Cursor != "" {
+		after, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		start = len(items)
+		for i, item := range items {
+			if compareCursors(key(item), after, descending) > 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	result := Page[T]{Items: items[start:]}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	if len(result.Items) > limit {
+		result.Items = result.Items[:limit]
+		result.NextCursor = key(result.Items[limit-1]).encode()
+	}
+	return result, nil
+}
@@ -0,0 +1,196 @@
+// Package participant implements what a signed-in participant can do:
+// editing their profile, registering for competitions and reading
+// announcements. The dashboard and the JSON API share it so that both apply
+// the same rules.
+package participant
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"context"
+	"errors"
+	"time"
+)
+
+// Service implements participant operations on top of the repositories
+type Service struct {
+	repos *repository.Repositories
+	now   func() time.Time
+}
+
+// NewService creates a new participant service
+func NewService(repos *repository.Repositories) *Service {
+	return &Service{repos: repos, now: time.Now}
+}
+
+// ProfileUpdate holds the profile fields to change; nil fields are kept
+type ProfileUpdate struct {
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Bio       *string `json:"bio"`
+}
+
+// UpdateProfile applies an update to the user's profile and saves it. On a
+// *models.ValidationError the user is left unchanged.
+func (s *Service) UpdateProfile(ctx context.Context, user *models.User, update ProfileUpdate) error {
+	profile := user.Profile
+	profile.UserID = user.ID
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{update.FirstName, &profile.FirstName},
+		{update.LastName, &profile.LastName},
+		{update.Bio, &profile.Bio},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+	profile.Sanitize()
+
+	var errs []*models.Error
+	if len(profile.FirstName) > models.MaxNameLength {
+		errs = append(errs, models.ErrNameTooLong.WithField("first_name"))
+	}
+	if len(profile.LastName) > models.MaxNameLength {
+		errs = append(errs, models.ErrNameTooLong.WithField("last_name"))
+	}
+	if len(profile.Bio) > models.MaxBioLength {
+		errs = append(errs, models.ErrBioTooLong)
+	}
+	if err := models.NewValidationError(errs...); err != nil {
+		return err
+	}
+
+	if err := s.repos.WithContext(ctx).Users.UpdateProfile(&profile); err != nil {
+		return err
+	}
+	user.Profile = profile
+	return nil
+}
+
+// Registrations returns a page of the user's registrations, newest first
+func (s *Service) Registrations(ctx context.Context, userID string, page PageRequest) (Page[*models.Registration], error) {
+	registrations, err := s.repos.WithContext(ctx).Registrations.GetByUserID(userID)
+	if err != nil {
+		return Page[*models.Registration]{}, err
+	}
+	return paginate(registrations, registrationKey, true, page)
+}
+
+// LatestRegistration returns the user's most recent registration, or nil
+// when they have none
+func (s *Service) LatestRegistration(ctx context.Context, userID string) (*models.Registration, error) {
+	page, err := s.Registrations(ctx, userID, PageRequest{Limit: 1})
+	if err != nil || len(page.Items) == 0 {
+		return nil, err
+	}
+	return page.Items[0], nil
+}
+
+// Register registers the user for a competition, reopening a cancelled
+// registration. When the user already has an active registration it is
+// returned along with models.ErrRegistrationExists.
+func (s *Service) Register(ctx context.Context, userID, competitionID string) (*models.Registration, error) {
+	repos := s.repos.WithContext(ctx)
+
+	if competitionID == "" {
+		return nil, models.ErrInvalidCompetitionID
+	}
+	competition, err := repos.Competitions.GetByID(competitionID)
+	if err != nil {
+		return nil, err
+	}
+	if !competition.RegistrationOpen(s.now()) {
+		return nil, models.ErrRegistrationClosed
+	}
+
+	existing, err := repos.Registrations.GetByUserAndCompetition(userID, competitionID)
+	switch {
+	case err == nil && !existing.IsCancelled():
+		return existing, models.ErrRegistrationExists
+	case err == nil:
+		if err := repos.Registrations.UpdateStatus(existing.ID, models.RegistrationStatusPending); err != nil {
+			return nil, err
+		}
+		return repos.Registrations.GetByID(existing.ID)
+	case !errors.Is(err, models.ErrRegistrationNotFound):
+		return nil, err
+	}
+
+	registration := models.NewRegistration(userID, competitionID, map[string]interface{}{
+		"registration_type": "individual",
+		"team_name":         "",
+	})
+	if err := repos.Registrations.Create(registration); err != nil {
+		return nil, err
+	}
+	return registration, nil
+}
+
+// CancelRegistration cancels one of the user's registrations. Registrations
+// of other users are reported as models.ErrRegistrationNotFound.
+func (s *Service) CancelRegistration(ctx context.Context, userID, registrationID string) (*models.Registration, error) {
+	repos := s.repos.WithContext(ctx)
+
+	registration, err := repos.Registrations.GetByID(registrationID)
+	if err != nil {
+		return nil, err
+	}
+	if registration.UserID != userID {
+		return nil, models.ErrRegistrationNotFound
+	}
+	if registration.IsCancelled() {
+		return registration, nil
+	}
+
+	if err := repos.Registrations.UpdateStatus(registration.ID, models.RegistrationStatusCancelled); err != nil {
+		return nil, err
+	}
+	return repos.Registrations.GetByID(registration.ID)
+}
+
+// Competitions returns a page of competitions, soonest first
+func (s *Service) Competitions(ctx context.Context, page PageRequest) (Page[*models.Competition], error) {
+	competitions, err := s.repos.WithContext(ctx).Competitions.GetAll()
+	if err != nil {
+		return Page[*models.Competition]{}, err
+	}
+	return paginate(competitions, competitionKey, false, page)
+}
+
+// Announcements returns a page of published announcements, newest first
+func (s *Service) Announcements(ctx context.Context, page PageRequest) (Page[*models.Announcement], error) {
+	announcements, err := s.repos.WithContext(ctx).Announcements.GetPublished()
+	if err != nil {
+		return Page[*models.Announcement]{}, err
+	}
+	return paginate(announcements, announcementKey, true, page)
+}
+
+// PublishedAnnouncements returns the newest published announcements for
+// the dashboard
+func (s *Service) PublishedAnnouncements(ctx context.Context) ([]models.Announcement, error) {
+	page, err := s.Announcements(ctx, PageRequest{Limit: MaxPageLimit})
+	if err != nil {
+		return nil, err
+	}
+	announcements := make([]models.Announcement, len(page.Items))
+	for i, a := range page.Items {
+		announcements[i] = *a
+	}
+	return announcements, nil
+}
+
+func registrationKey(r *models.Registration) cursor {
+	return cursor{Time: r.RegisteredAt, ID: r.ID}
+}
+
+func competitionKey(c *models.Competition) cursor {
+	return cursor{Time: c.StartsAt, ID: c.ID}
+}
+
+func announcementKey(a *models.Announcement) cursor {
+	return cursor{Time: a.CreatedAt, ID: a.ID}
+}
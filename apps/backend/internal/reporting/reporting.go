@@ -0,0 +1,92 @@
+// Package reporting sends unexpected errors, such as recovered panics, to an
+// error tracking sink.
+package reporting
+
+import (
+	"compify-backend/internal/logging"
+	"compify-backend/internal/tracing"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event describes one unexpected error with the request it occurred in
+type Event struct {
+	Time      time.Time         `json:"time"`
+	Message   string            `json:"message"`
+	Panic     bool              `json:"panic"`
+	Stack     string            `json:"stack,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	TraceID   string            `json:"trace_id,omitempty"`
+	UserID    string            `json:"user_id,omitempty"`
+	Method    string            `json:"method,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// NewEvent creates an event for a recovered value or error, with the request
+// ID, user ID and trace ID taken from the context
+func NewEvent(ctx context.Context, recovered any) Event {
+	event := Event{
+		Time:      time.Now(),
+		Message:   fmt.Sprint(recovered),
+		RequestID: logging.RequestID(ctx),
+		UserID:    logging.UserID(ctx),
+	}
+	if sc := tracing.SpanFromContext(ctx).SpanContext(); sc.TraceID.IsValid() {
+		event.TraceID = sc.TraceID.String()
+	}
+	return event
+}
+
+// ErrorReporter receives error events. Implementations, such as a client for
+// a Sentry-like service, must be safe for concurrent use and must not block
+// the request for long; remote sinks should queue and send in the
+// background, and flush on Shutdown.
+type ErrorReporter interface {
+	Report(ctx context.Context, event Event)
+	Shutdown(ctx context.Context) error
+}
+
+// WriterReporter writes each event as a JSON line. It serves as a local
+// sink for development and tests.
+type WriterReporter struct {
+	mutex  sync.Mutex
+	w      io.Writer
+	closer io.Closer // Set when the reporter owns the writer
+}
+
+// NewWriterReporter creates a reporter writing to w
+func NewWriterReporter(w io.Writer) *WriterReporter {
+	return &WriterReporter{w: w}
+}
+
+// NewFileReporter creates a reporter that owns and closes the given file
+func NewFileReporter(f io.WriteCloser) *WriterReporter {
+	return &WriterReporter{w: f, closer: f}
+}
+
+// Report writes the event
+func (r *WriterReporter) Report(ctx context.Context, event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.w.Write(append(data, '\n'))
+}
+
+// Shutdown closes the file when the reporter owns it
+func (r *WriterReporter) Shutdown(ctx context.Context) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
@@ -1,6 +1,11 @@
 package repository
 
-import "compify-backend/internal/models"
+import (
+	"compify-backend/internal/models"
+	"context"
+	"errors"
+	"io"
+)
 
 // Repositories aggregates all repository interfaces
 type Repositories struct {
@@ -8,6 +13,10 @@ type Repositories struct {
 	Sessions      models.SessionRepository
 	Registrations models.RegistrationRepository
 	Announcements models.AnnouncementRepository
+	Competitions  models.CompetitionRepository
+	AccessTokens  models.AccessTokenRepository
+	Identities    models.IdentityRepository
+	LoginLinks    models.LoginLinkRepository
 }
 
 // NewRepositories creates a new repositories instance
@@ -18,5 +27,42 @@ func NewRepositories() *Repositories {
 		Sessions:      NewMemorySessionRepository(),
 		Registrations: NewMemoryRegistrationRepository(),
 		Announcements: NewMemoryAnnouncementRepository(),
+		Competitions:  NewMemoryCompetitionRepository(),
+		AccessTokens:  NewMemoryAccessTokenRepository(),
+		Identities:    NewMemoryIdentityRepository(),
+		LoginLinks:    NewMemoryLoginLinkRepository(),
 	}
+}
+
+// Pinger is implemented by repositories that can report their availability
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that every repository is able to serve requests
+func (r *Repositories) Ping(ctx context.Context) error {
+	var errs []error
+	for _, repo := range []any{r.Users, r.Sessions, r.Registrations, r.Announcements, r.Competitions, r.AccessTokens, r.Identities, r.LoginLinks} {
+		if pinger, ok := repo.(Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases resources held by repositories that need it, such as
+// database connections. It is called once on shutdown after all requests
+// have drained.
+func (r *Repositories) Close() error {
+	var errs []error
+	for _, repo := range []any{r.Users, r.Sessions, r.Registrations, r.Announcements, r.Competitions, r.AccessTokens, r.Identities, r.LoginLinks} {
+		if closer, ok := repo.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
\ No newline at end of file
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"compify-backend/internal/models"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryAccessTokenRepository implements AccessTokenRepository using in-memory
+// storage. Tokens are keyed by the SHA-256 hash of their secret; plaintext
+// tokens are never stored.
+type MemoryAccessTokenRepository struct {
+	tokens map[string]*models.AccessToken
+	mutex  sync.RWMutex
+}
+
+// NewMemoryAccessTokenRepository creates a new in-memory access token repository
+func NewMemoryAccessTokenRepository() *MemoryAccessTokenRepository {
+	return &MemoryAccessTokenRepository{
+		tokens: make(map[string]*models.AccessToken),
+	}
+}
+
+// Create stores a new token, failing when the user already has the maximum
+// number of tokens
+func (r *MemoryAccessTokenRepository) Create(token *models.AccessToken) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := token.Validate(); err != nil {
+		return err
+	}
+
+	count := 0
+	for _, existing := range r.tokens {
+		if existing.UserID == token.UserID {
+			count++
+		}
+	}
+	if count >= models.MaxAccessTokensPerUser {
+		return models.ErrAccessTokenLimit
+	}
+
+	if token.ID == "" {
+		id, err := generateID()
+		if err != nil {
+			return err
+		}
+		token.ID = id
+	}
+
+	// Store a copy without the plaintext token
+	stored := *token
+	stored.Token = ""
+	stored.Scopes = slices.Clone(token.Scopes)
+	r.tokens[stored.TokenHash] = &stored
+
+	return nil
+}
+
+// GetByToken retrieves a token by its plaintext secret. Expired tokens are
+// returned as well; callers check IsExpired.
+func (r *MemoryAccessTokenRepository) GetByToken(token string) (*models.AccessToken, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stored, exists := r.tokens[models.HashSessionToken(token)]
+	if !exists {
+		return nil, models.ErrAccessTokenNotFound
+	}
+
+	return copyAccessToken(stored), nil
+}
+
+// GetByUserID retrieves all tokens of a user, including expired ones
+func (r *MemoryAccessTokenRepository) GetByUserID(userID string) ([]*models.AccessToken, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var tokens []*models.AccessToken
+	for _, token := range r.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, copyAccessToken(token))
+		}
+	}
+
+	return tokens, nil
+}
+
+// Delete deletes a token by ID
+func (r *MemoryAccessTokenRepository) Delete(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for tokenHash, token := range r.tokens {
+		if token.ID == id {
+			delete(r.tokens, tokenHash)
+			return nil
+		}
+	}
+
+	return models.ErrAccessTokenNotFound
+}
+
+// Touch records that a token was used. Like session activity, it is only
+// written when LastUsedAt is older than models.LastSeenResolution.
+func (r *MemoryAccessTokenRepository) Touch(token string, usedAt time.Time) error {
+	tokenHash := models.HashSessionToken(token)
+
+	r.mutex.RLock()
+	stored, exists := r.tokens[tokenHash]
+	if !exists {
+		r.mutex.RUnlock()
+		return models.ErrAccessTokenNotFound
+	}
+	recent := usedAt.Sub(stored.LastUsedAt) < models.LastSeenResolution
+	r.mutex.RUnlock()
+
+	if recent {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// The token may have been deleted while the lock was released
+	stored, exists = r.tokens[tokenHash]
+	if !exists {
+		return models.ErrAccessTokenNotFound
+	}
+	if usedAt.After(stored.LastUsedAt) {
+		stored.LastUsedAt = usedAt
+	}
+
+	return nil
+}
+
+// Ping reports whether the repository can serve requests. For the in-memory
+// store this verifies that its lock can be acquired.
+func (r *MemoryAccessTokenRepository) Ping(ctx context.Context) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return ctx.Err()
+}
+
+// copyAccessToken returns a copy callers can modify without racing with Touch
+func copyAccessToken(token *models.AccessToken) *models.AccessToken {
+	result := *token
+	result.Scopes = slices.Clone(token.Scopes)
+	return &result
+}
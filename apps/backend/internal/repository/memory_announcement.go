@@ -2,6 +2,7 @@ package repository
 
 import (
 	"compify-backend/internal/models"
+	"context"
 	"errors"
 	"sort"
 	"sync"
@@ -173,4 +174,13 @@ func (r *MemoryAnnouncementRepository) Unpublish(id string) error {
 	announcement.UpdatedAt = time.Now()
 
 	return nil
+}
+
+// Ping reports whether the repository can serve requests. For the in-memory
+// store this verifies that its lock can be acquired.
+func (r *MemoryAnnouncementRepository) Ping(ctx context.Context) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return ctx.Err()
 }
\ No newline at end of file
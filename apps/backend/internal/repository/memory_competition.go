@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"compify-backend/internal/models"
+	"context"
+	"sync"
+)
+
+// MemoryCompetitionRepository implements CompetitionRepository using in-memory storage
+type MemoryCompetitionRepository struct {
+	competitions map[string]*models.Competition
+	mutex        sync.RWMutex
+}
+
+// NewMemoryCompetitionRepository creates a new in-memory competition repository
+func NewMemoryCompetitionRepository() *MemoryCompetitionRepository {
+	return &MemoryCompetitionRepository{
+		competitions: make(map[string]*models.Competition),
+	}
+}
+
+// Create creates a new competition
+func (r *MemoryCompetitionRepository) Create(competition *models.Competition) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Validate competition data
+	if err := competition.Validate(); err != nil {
+		return err
+	}
+
+	if _, exists := r.competitions[competition.ID]; exists {
+		return models.ErrCompetitionExists
+	}
+
+	// Store competition
+	r.competitions[competition.ID] = competition
+
+	return nil
+}
+
+// GetByID retrieves a competition by ID
+func (r *MemoryCompetitionRepository) GetByID(id string) (*models.Competition, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	competition, exists := r.competitions[id]
+	if !exists {
+		return nil, models.ErrCompetitionNotFound
+	}
+
+	return competition, nil
+}
+
+// GetAll retrieves all competitions
+func (r *MemoryCompetitionRepository) GetAll() ([]*models.Competition, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	competitions := make([]*models.Competition, 0, len(r.competitions))
+	for _, competition := range r.competitions {
+		competitions = append(competitions, competition)
+	}
+
+	return competitions, nil
+}
+
+// Ping reports whether the repository can serve requests. For the in-memory
+// store this verifies that its lock can be acquired.
+func (r *MemoryCompetitionRepository) Ping(ctx context.Context) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return ctx.Err()
+}
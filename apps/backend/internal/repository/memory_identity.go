@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"compify-backend/internal/models"
+	"context"
+	"sync"
+)
+
+// MemoryIdentityRepository implements IdentityRepository using in-memory
+// storage
+type MemoryIdentityRepository struct {
+	identities map[string]*models.Identity
+	mutex      sync.RWMutex
+}
+
+// NewMemoryIdentityRepository creates a new in-memory identity repository
+func NewMemoryIdentityRepository() *MemoryIdentityRepository {
+	return &MemoryIdentityRepository{
+		identities: make(map[string]*models.Identity),
+	}
+}
+
+// Create links a new identity. Each provider account can be linked to one
+// user, and each user to one account per provider.
+func (r *MemoryIdentityRepository) Create(identity *models.Identity) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := identity.Validate(); err != nil {
+		return err
+	}
+
+	for _, existing := range r.identities {
+		if existing.Provider != identity.Provider {
+			continue
+		}
+		if existing.Subject == identity.Subject {
+			return models.ErrIdentityLinked
+		}
+		if existing.UserID == identity.UserID {
+			return models.ErrProviderAlreadyLinked
+		}
+	}
+
+	if identity.ID == "" {
+		id, err := generateID()
+		if err != nil {
+			return err
+		}
+		identity.ID = id
+	}
+
+	stored := *identity
+	r.identities[stored.ID] = &stored
+
+	return nil
+}
+
+// GetByProviderSubject retrieves the identity of a provider account
+func (r *MemoryIdentityRepository) GetByProviderSubject(provider, subject string) (*models.Identity, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			result := *identity
+			return &result, nil
+		}
+	}
+
+	return nil, models.ErrIdentityNotFound
+}
+
+// GetByUserID retrieves all identities linked to a user
+func (r *MemoryIdentityRepository) GetByUserID(userID string) ([]*models.Identity, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var identities []*models.Identity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			result := *identity
+			identities = append(identities, &result)
+		}
+	}
+
+	return identities, nil
+}
+
+// Update updates an identity
+func (r *MemoryIdentityRepository) Update(identity *models.Identity) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := identity.Validate(); err != nil {
+		return err
+	}
+	if _, exists := r.identities[identity.ID]; !exists {
+		return models.ErrIdentityNotFound
+	}
+
+	stored := *identity
+	r.identities[stored.ID] = &stored
+
+	return nil
+}
+
+// Delete unlinks an identity by ID
+func (r *MemoryIdentityRepository) Delete(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.identities[id]; !exists {
+		return models.ErrIdentityNotFound
+	}
+
+	delete(r.identities, id)
+	return nil
+}
+
+// Ping reports whether the repository can serve requests. For the in-memory
+// store this verifies that its lock can be acquired.
+func (r *MemoryIdentityRepository) Ping(ctx context.Context) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return ctx.Err()
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"compify-backend/internal/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLoginLinkRepository implements LoginLinkRepository using in-memory
+// storage. Links are keyed by the SHA-256 hash of their token; plaintext
+// tokens are never stored.
+type MemoryLoginLinkRepository struct {
+	links map[string]*models.LoginLink
+	mutex sync.RWMutex
+}
+
+// NewMemoryLoginLinkRepository creates a new in-memory sign-in link repository
+func NewMemoryLoginLinkRepository() *MemoryLoginLinkRepository {
+	return &MemoryLoginLinkRepository{
+		links: make(map[string]*models.LoginLink),
+	}
+}
+
+// Create stores a new link
+func (r *MemoryLoginLinkRepository) Create(link *models.LoginLink) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if link.ID == "" {
+		id, err := generateID()
+		if err != nil {
+			return err
+		}
+		link.ID = id
+	}
+
+	// Store a copy without the plaintext token
+	stored := *link
+	stored.Token = ""
+	r.links[stored.TokenHash] = &stored
+
+	return nil
+}
+
+// GetByToken retrieves a link by its plaintext token. Used and expired links
+// are returned as well; callers check Usable.
+func (r *MemoryLoginLinkRepository) GetByToken(token string) (*models.LoginLink, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stored, exists := r.links[models.HashSessionToken(token)]
+	if !exists {
+		return nil, models.ErrLoginLinkInvalid
+	}
+
+	result := *stored
+	return &result, nil
+}
+
+// MarkUsed records that a link was used. It fails for links that are
+// already used or expired, so that of two concurrent uses only one succeeds.
+func (r *MemoryLoginLinkRepository) MarkUsed(id string, now time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, link := range r.links {
+		if link.ID == id {
+			if !link.Usable(now) {
+				return models.ErrLoginLinkInvalid
+			}
+			link.UsedAt = now
+			return nil
+		}
+	}
+
+	return models.ErrLoginLinkInvalid
+}
+
+// DeleteExpired removes expired links, used or not
+func (r *MemoryLoginLinkRepository) DeleteExpired() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	for hash, link := range r.links {
+		if !now.Before(link.ExpiresAt) {
+			delete(r.links, hash)
+		}
+	}
+
+	return nil
+}
+
+// Ping reports whether the repository can serve requests. For the in-memory
+// store this verifies that its lock can be acquired.
+func (r *MemoryLoginLinkRepository) Ping(ctx context.Context) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return ctx.Err()
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"compify-backend/internal/models"
+	"context"
 	"sync"
 )
 
@@ -93,6 +94,33 @@ func (r *MemoryRegistrationRepository) GetByCompetitionID(competitionID string)
 	return registrations, nil
 }
 
+// CountByCompetitionAndStatus counts registrations grouped by competition and
+// status
+func (r *MemoryRegistrationRepository) CountByCompetitionAndStatus() ([]models.RegistrationCount, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	type group struct {
+		competitionID string
+		status        models.RegistrationStatus
+	}
+	counts := make(map[group]int)
+	for _, registration := range r.registrations {
+		counts[group{registration.CompetitionID, registration.Status}]++
+	}
+
+	result := make([]models.RegistrationCount, 0, len(counts))
+	for g, count := range counts {
+		result = append(result, models.RegistrationCount{
+			CompetitionID: g.competitionID,
+			Status:        g.status,
+			Count:         count,
+		})
+	}
+
+	return result, nil
+}
+
 // GetByUserAndCompetition retrieves a registration for a specific user and competition
 func (r *MemoryRegistrationRepository) GetByUserAndCompetition(userID, competitionID string) (*models.Registration, error) {
 	r.mutex.RLock()
@@ -156,4 +184,13 @@ func (r *MemoryRegistrationRepository) UpdateStatus(id string, status models.Reg
 	}
 
 	return nil
+}
+
+// Ping reports whether the repository can serve requests. For the in-memory
+// store this verifies that its lock can be acquired.
+func (r *MemoryRegistrationRepository) Ping(ctx context.Context) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return ctx.Err()
 }
\ No newline at end of file
@@ -2,11 +2,14 @@ package repository
 
 import (
 	"compify-backend/internal/models"
+	"context"
 	"sync"
 	"time"
 )
 
-// MemorySessionRepository implements SessionRepository using in-memory storage
+// MemorySessionRepository implements SessionRepository using in-memory storage.
+// Sessions are keyed by the SHA-256 hash of their token; plaintext tokens are
+// never stored.
 type MemorySessionRepository struct {
 	sessions map[string]*models.Session
 	mutex    sync.RWMutex
@@ -38,8 +41,15 @@ func (r *MemorySessionRepository) Create(session *models.Session) error {
 		session.ID = id
 	}
 
-	// Store session
-	r.sessions[session.Token] = session
+	// Only the token hash is persisted
+	if session.TokenHash == "" {
+		session.TokenHash = models.HashSessionToken(session.Token)
+	}
+
+	// Store a copy so the caller's session is not shared with Touch
+	stored := *session
+	stored.Token = ""
+	r.sessions[stored.TokenHash] = &stored
 
 	return nil
 }
@@ -49,7 +59,7 @@ func (r *MemorySessionRepository) GetByToken(token string) (*models.Session, err
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	session, exists := r.sessions[token]
+	session, exists := r.sessions[models.HashSessionToken(token)]
 	if !exists {
 		return nil, models.ErrSessionNotFound
 	}
@@ -59,7 +69,9 @@ func (r *MemorySessionRepository) GetByToken(token string) (*models.Session, err
 		return nil, models.ErrSessionExpired
 	}
 
-	return session, nil
+	// Return a copy so callers never race with Touch
+	result := *session
+	return &result, nil
 }
 
 // GetByUserID retrieves all sessions for a user
@@ -70,7 +82,8 @@ func (r *MemorySessionRepository) GetByUserID(userID string) ([]*models.Session,
 	var sessions []*models.Session
 	for _, session := range r.sessions {
 		if session.UserID == userID && !session.IsExpired() {
-			sessions = append(sessions, session)
+			result := *session
+			sessions = append(sessions, &result)
 		}
 	}
 
@@ -87,13 +100,19 @@ func (r *MemorySessionRepository) Update(session *models.Session) error {
 		return err
 	}
 
+	if session.TokenHash == "" {
+		session.TokenHash = models.HashSessionToken(session.Token)
+	}
+
 	// Check if session exists
-	if _, exists := r.sessions[session.Token]; !exists {
+	if _, exists := r.sessions[session.TokenHash]; !exists {
 		return models.ErrSessionNotFound
 	}
 
-	// Store session
-	r.sessions[session.Token] = session
+	// Store a copy so the caller's session is not shared with Touch
+	stored := *session
+	stored.Token = ""
+	r.sessions[stored.TokenHash] = &stored
 
 	return nil
 }
@@ -104,9 +123,9 @@ func (r *MemorySessionRepository) Delete(id string) error {
 	defer r.mutex.Unlock()
 
 	// Find session by ID
-	for token, session := range r.sessions {
+	for tokenHash, session := range r.sessions {
 		if session.ID == id {
-			delete(r.sessions, token)
+			delete(r.sessions, tokenHash)
 			return nil
 		}
 	}
@@ -119,11 +138,12 @@ func (r *MemorySessionRepository) DeleteByToken(token string) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, exists := r.sessions[token]; !exists {
+	tokenHash := models.HashSessionToken(token)
+	if _, exists := r.sessions[tokenHash]; !exists {
 		return models.ErrSessionNotFound
 	}
 
-	delete(r.sessions, token)
+	delete(r.sessions, tokenHash)
 	return nil
 }
 
@@ -132,37 +152,127 @@ func (r *MemorySessionRepository) DeleteByUserID(userID string) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	tokensToDelete := make([]string, 0)
-	for token, session := range r.sessions {
+	hashesToDelete := make([]string, 0)
+	for tokenHash, session := range r.sessions {
 		if session.UserID == userID {
-			tokensToDelete = append(tokensToDelete, token)
+			hashesToDelete = append(hashesToDelete, tokenHash)
 		}
 	}
 
-	for _, token := range tokensToDelete {
-		delete(r.sessions, token)
+	for _, tokenHash := range hashesToDelete {
+		delete(r.sessions, tokenHash)
 	}
 
 	return nil
 }
 
+// CountActive counts sessions that have not expired
+func (r *MemorySessionRepository) CountActive() (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	now := time.Now()
+	for _, session := range r.sessions {
+		if !now.After(session.ExpiresAt) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
 // DeleteExpired deletes all expired sessions
 func (r *MemorySessionRepository) DeleteExpired() error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	tokensToDelete := make([]string, 0)
+	hashesToDelete := make([]string, 0)
 	now := time.Now()
 	
-	for token, session := range r.sessions {
+	for tokenHash, session := range r.sessions {
 		if now.After(session.ExpiresAt) {
-			tokensToDelete = append(tokensToDelete, token)
+			hashesToDelete = append(hashesToDelete, tokenHash)
 		}
 	}
 
-	for _, token := range tokensToDelete {
-		delete(r.sessions, token)
+	for _, tokenHash := range hashesToDelete {
+		delete(r.sessions, tokenHash)
 	}
 
 	return nil
+}
+
+// Touch records activity on a session and slides its idle expiry. Most calls
+// only need the read lock: the write lock is taken only when LastSeenAt is
+// older than models.LastSeenResolution.
+func (r *MemorySessionRepository) Touch(token string, seenAt time.Time) error {
+	tokenHash := models.HashSessionToken(token)
+
+	r.mutex.RLock()
+	session, exists := r.sessions[tokenHash]
+	if !exists {
+		r.mutex.RUnlock()
+		return models.ErrSessionNotFound
+	}
+	recent := seenAt.Sub(session.LastSeenAt) < models.LastSeenResolution
+	r.mutex.RUnlock()
+
+	if recent {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// The session may have been deleted while the lock was released
+	session, exists = r.sessions[tokenHash]
+	if !exists {
+		return models.ErrSessionNotFound
+	}
+	if session.IsExpired() {
+		return models.ErrSessionExpired
+	}
+
+	session.RecordActivity(seenAt)
+
+	return nil
+}
+
+// ReplaceToken atomically moves a session from oldToken to newToken, keeping
+// its ID and lifetime. The returned copy carries the new plaintext token.
+func (r *MemorySessionRepository) ReplaceToken(oldToken, newToken string) (*models.Session, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	oldHash := models.HashSessionToken(oldToken)
+	session, exists := r.sessions[oldHash]
+	if !exists {
+		return nil, models.ErrSessionNotFound
+	}
+	if session.IsExpired() {
+		return nil, models.ErrSessionExpired
+	}
+
+	newHash := models.HashSessionToken(newToken)
+	if _, exists := r.sessions[newHash]; exists {
+		return nil, models.ErrInvalidToken
+	}
+
+	delete(r.sessions, oldHash)
+	session.TokenHash = newHash
+	r.sessions[newHash] = session
+
+	result := *session
+	result.Token = newToken
+	return &result, nil
+}
+
+// Ping reports whether the repository can serve requests. For the in-memory
+// store this verifies that its lock can be acquired.
+func (r *MemorySessionRepository) Ping(ctx context.Context) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return ctx.Err()
 }
\ No newline at end of file
@@ -2,9 +2,9 @@ package repository
 
 import (
 	"compify-backend/internal/models"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
 	"sync"
 	"time"
 )
@@ -40,10 +40,10 @@ func (r *MemoryUserRepository) Create(user *models.User) error {
 	// Check if email already exists
 	for _, existingUser := range r.users {
 		if existingUser.Email == user.Email {
-			return errors.New("email already exists")
+			return models.ErrEmailTaken
 		}
 		if existingUser.Username == user.Username {
-			return errors.New("username already exists")
+			return models.ErrUsernameTaken
 		}
 	}
 
@@ -56,6 +56,11 @@ func (r *MemoryUserRepository) Create(user *models.User) error {
 		user.ID = id
 	}
 
+	// New users are participants unless stated otherwise
+	if user.Role == "" {
+		user.Role = models.RoleParticipant
+	}
+
 	// Set timestamps
 	now := time.Now()
 	user.CreatedAt = now
@@ -81,7 +86,7 @@ func (r *MemoryUserRepository) GetByID(id string) (*models.User, error) {
 
 	user, exists := r.users[id]
 	if !exists {
-		return nil, errors.New("user not found")
+		return nil, models.ErrUserNotFound
 	}
 
 	// Load profile
@@ -107,7 +112,7 @@ func (r *MemoryUserRepository) GetByEmail(email string) (*models.User, error) {
 		}
 	}
 
-	return nil, errors.New("user not found")
+	return nil, models.ErrUserNotFound
 }
 
 // GetByUsername retrieves a user by username
@@ -125,7 +130,7 @@ func (r *MemoryUserRepository) GetByUsername(username string) (*models.User, err
 		}
 	}
 
-	return nil, errors.New("user not found")
+	return nil, models.ErrUserNotFound
 }
 
 // Update updates a user
@@ -143,7 +148,7 @@ func (r *MemoryUserRepository) Update(user *models.User) error {
 
 	// Check if user exists
 	if _, exists := r.users[user.ID]; !exists {
-		return errors.New("user not found")
+		return models.ErrUserNotFound
 	}
 
 	// Update timestamp
@@ -161,7 +166,7 @@ func (r *MemoryUserRepository) Delete(id string) error {
 	defer r.mutex.Unlock()
 
 	if _, exists := r.users[id]; !exists {
-		return errors.New("user not found")
+		return models.ErrUserNotFound
 	}
 
 	delete(r.users, id)
@@ -185,7 +190,7 @@ func (r *MemoryUserRepository) UpdateProfile(profile *models.Profile) error {
 
 	// Check if user exists
 	if _, exists := r.users[profile.UserID]; !exists {
-		return errors.New("user not found")
+		return models.ErrUserNotFound
 	}
 
 	// Store profile
@@ -201,7 +206,7 @@ func (r *MemoryUserRepository) GetProfile(userID string) (*models.Profile, error
 
 	profile, exists := r.profiles[userID]
 	if !exists {
-		return nil, errors.New("profile not found")
+		return nil, models.ErrProfileNotFound
 	}
 
 	return profile, nil
@@ -214,4 +219,13 @@ func generateID() (string, error) {
 		return "", err
 	}
 	return hex.EncodeToString(bytes), nil
+}
+
+// Ping reports whether the repository can serve requests. For the in-memory
+// store this verifies that its lock can be acquired.
+func (r *MemoryUserRepository) Ping(ctx context.Context) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return ctx.Err()
 }
\ No newline at end of file
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/tracing"
+	"context"
+	"time"
+)
+
+// WithContext returns repositories that record a tracing span for each call
+// as a child of the span in ctx. Repository methods do not take a context,
+// so handlers wrap them per request.
+func (r *Repositories) WithContext(ctx context.Context) *Repositories {
+	return &Repositories{
+		Users:         tracedUserRepository{ctx: ctx, next: r.Users},
+		Sessions:      tracedSessionRepository{ctx: ctx, next: r.Sessions},
+		Registrations: tracedRegistrationRepository{ctx: ctx, next: r.Registrations},
+		Announcements: tracedAnnouncementRepository{ctx: ctx, next: r.Announcements},
+		Competitions:  tracedCompetitionRepository{ctx: ctx, next: r.Competitions},
+		AccessTokens:  tracedAccessTokenRepository{ctx: ctx, next: r.AccessTokens},
+		Identities:    tracedIdentityRepository{ctx: ctx, next: r.Identities},
+		LoginLinks:    tracedLoginLinkRepository{ctx: ctx, next: r.LoginLinks},
+	}
+}
+
+// traceCall runs a repository call that only returns an error in a span
+func traceCall(ctx context.Context, name string, fn func() error) error {
+	return tracing.Do(ctx, name, func(context.Context) error { return fn() })
+}
+
+// tracedUserRepository records a span for each call to the wrapped repository
+type tracedUserRepository struct {
+	ctx  context.Context
+	next models.UserRepository
+}
+
+func (r tracedUserRepository) Create(user *models.User) error {
+	return traceCall(r.ctx, "UserRepository.Create", func() error { return r.next.Create(user) })
+}
+
+func (r tracedUserRepository) GetByID(id string) (*models.User, error) {
+	return tracing.Call(r.ctx, "UserRepository.GetByID", func() (*models.User, error) { return r.next.GetByID(id) })
+}
+
+func (r tracedUserRepository) GetByEmail(email string) (*models.User, error) {
+	return tracing.Call(r.ctx, "UserRepository.GetByEmail", func() (*models.User, error) { return r.next.GetByEmail(email) })
+}
+
+func (r tracedUserRepository) GetByUsername(username string) (*models.User, error) {
+	return tracing.Call(r.ctx, "UserRepository.GetByUsername", func() (*models.User, error) { return r.next.GetByUsername(username) })
+}
+
+func (r tracedUserRepository) Update(user *models.User) error {
+	return traceCall(r.ctx, "UserRepository.Update", func() error { return r.next.Update(user) })
+}
+
+func (r tracedUserRepository) Delete(id string) error {
+	return traceCall(r.ctx, "UserRepository.Delete", func() error { return r.next.Delete(id) })
+}
+
+func (r tracedUserRepository) UpdateProfile(profile *models.Profile) error {
+	return traceCall(r.ctx, "UserRepository.UpdateProfile", func() error { return r.next.UpdateProfile(profile) })
+}
+
+func (r tracedUserRepository) GetProfile(userID string) (*models.Profile, error) {
+	return tracing.Call(r.ctx, "UserRepository.GetProfile", func() (*models.Profile, error) { return r.next.GetProfile(userID) })
+}
+
+// tracedSessionRepository records a span for each call to the wrapped repository
+type tracedSessionRepository struct {
+	ctx  context.Context
+	next models.SessionRepository
+}
+
+func (r tracedSessionRepository) Create(session *models.Session) error {
+	return traceCall(r.ctx, "SessionRepository.Create", func() error { return r.next.Create(session) })
+}
+
+func (r tracedSessionRepository) GetByToken(token string) (*models.Session, error) {
+	return tracing.Call(r.ctx, "SessionRepository.GetByToken", func() (*models.Session, error) { return r.next.GetByToken(token) })
+}
+
+func (r tracedSessionRepository) GetByUserID(userID string) ([]*models.Session, error) {
+	return tracing.Call(r.ctx, "SessionRepository.GetByUserID", func() ([]*models.Session, error) { return r.next.GetByUserID(userID) })
+}
+
+func (r tracedSessionRepository) Update(session *models.Session) error {
+	return traceCall(r.ctx, "SessionRepository.Update", func() error { return r.next.Update(session) })
+}
+
+func (r tracedSessionRepository) Delete(id string) error {
+	return traceCall(r.ctx, "SessionRepository.Delete", func() error { return r.next.Delete(id) })
+}
+
+func (r tracedSessionRepository) DeleteByToken(token string) error {
+	return traceCall(r.ctx, "SessionRepository.DeleteByToken", func() error { return r.next.DeleteByToken(token) })
+}
+
+func (r tracedSessionRepository) DeleteByUserID(userID string) error {
+	return traceCall(r.ctx, "SessionRepository.DeleteByUserID", func() error { return r.next.DeleteByUserID(userID) })
+}
+
+func (r tracedSessionRepository) DeleteExpired() error {
+	return traceCall(r.ctx, "SessionRepository.DeleteExpired", func() error { return r.next.DeleteExpired() })
+}
+
+func (r tracedSessionRepository) Touch(token string, seenAt time.Time) error {
+	return traceCall(r.ctx, "SessionRepository.Touch", func() error { return r.next.Touch(token, seenAt) })
+}
+
+func (r tracedSessionRepository) ReplaceToken(oldToken, newToken string) (*models.Session, error) {
+	return tracing.Call(r.ctx, "SessionRepository.ReplaceToken", func() (*models.Session, error) { return r.next.ReplaceToken(oldToken, newToken) })
+}
+
+func (r tracedSessionRepository) CountActive() (int, error) {
+	return tracing.Call(r.ctx, "SessionRepository.CountActive", func() (int, error) { return r.next.CountActive() })
+}
+
+// tracedRegistrationRepository records a span for each call to the wrapped repository
+type tracedRegistrationRepository struct {
+	ctx  context.Context
+	next models.RegistrationRepository
+}
+
+func (r tracedRegistrationRepository) Create(registration *models.Registration) error {
+	return traceCall(r.ctx, "RegistrationRepository.Create", func() error { return r.next.Create(registration) })
+}
+
+func (r tracedRegistrationRepository) GetByID(id string) (*models.Registration, error) {
+	return tracing.Call(r.ctx, "RegistrationRepository.GetByID", func() (*models.Registration, error) { return r.next.GetByID(id) })
+}
+
+func (r tracedRegistrationRepository) GetByUserID(userID string) ([]*models.Registration, error) {
+	return tracing.Call(r.ctx, "RegistrationRepository.GetByUserID", func() ([]*models.Registration, error) { return r.next.GetByUserID(userID) })
+}
+
+func (r tracedRegistrationRepository) GetByCompetitionID(competitionID string) ([]*models.Registration, error) {
+	return tracing.Call(r.ctx, "RegistrationRepository.GetByCompetitionID", func() ([]*models.Registration, error) { return r.next.GetByCompetitionID(competitionID) })
+}
+
+func (r tracedRegistrationRepository) GetByUserAndCompetition(userID, competitionID string) (*models.Registration, error) {
+	return tracing.Call(r.ctx, "RegistrationRepository.GetByUserAndCompetition", func() (*models.Registration, error) { return r.next.GetByUserAndCompetition(userID, competitionID) })
+}
+
+func (r tracedRegistrationRepository) Update(registration *models.Registration) error {
+	return traceCall(r.ctx, "RegistrationRepository.Update", func() error { return r.next.Update(registration) })
+}
+
+func (r tracedRegistrationRepository) Delete(id string) error {
+	return traceCall(r.ctx, "RegistrationRepository.Delete", func() error { return r.next.Delete(id) })
+}
+
+func (r tracedRegistrationRepository) UpdateStatus(id string, status models.RegistrationStatus) error {
+	return traceCall(r.ctx, "RegistrationRepository.UpdateStatus", func() error { return r.next.UpdateStatus(id, status) })
+}
+
+func (r tracedRegistrationRepository) CountByCompetitionAndStatus() ([]models.RegistrationCount, error) {
+	return tracing.Call(r.ctx, "RegistrationRepository.CountByCompetitionAndStatus", func() ([]models.RegistrationCount, error) { return r.next.CountByCompetitionAndStatus() })
+}
+
+// tracedAnnouncementRepository records a span for each call to the wrapped repository
+type tracedAnnouncementRepository struct {
+	ctx  context.Context
+	next models.AnnouncementRepository
+}
+
+func (r tracedAnnouncementRepository) Create(announcement *models.Announcement) error {
+	return traceCall(r.ctx, "AnnouncementRepository.Create", func() error { return r.next.Create(announcement) })
+}
+
+func (r tracedAnnouncementRepository) GetByID(id string) (*models.Announcement, error) {
+	return tracing.Call(r.ctx, "AnnouncementRepository.GetByID", func() (*models.Announcement, error) { return r.next.GetByID(id) })
+}
+
+func (r tracedAnnouncementRepository) GetPublished() ([]*models.Announcement, error) {
+	return tracing.Call(r.ctx, "AnnouncementRepository.GetPublished", func() ([]*models.Announcement, error) { return r.next.GetPublished() })
+}
+
+func (r tracedAnnouncementRepository) GetByPriority(priority models.AnnouncementPriority) ([]*models.Announcement, error) {
+	return tracing.Call(r.ctx, "AnnouncementRepository.GetByPriority", func() ([]*models.Announcement, error) { return r.next.GetByPriority(priority) })
+}
+
+func (r tracedAnnouncementRepository) Update(announcement *models.Announcement) error {
+	return traceCall(r.ctx, "AnnouncementRepository.Update", func() error { return r.next.Update(announcement) })
+}
+
+func (r tracedAnnouncementRepository) Delete(id string) error {
+	return traceCall(r.ctx, "AnnouncementRepository.Delete", func() error { return r.next.Delete(id) })
+}
+
+func (r tracedAnnouncementRepository) Publish(id string) error {
+	return traceCall(r.ctx, "AnnouncementRepository.Publish", func() error { return r.next.Publish(id) })
+}
+
+func (r tracedAnnouncementRepository) Unpublish(id string) error {
+	return traceCall(r.ctx, "AnnouncementRepository.Unpublish", func() error { return r.next.Unpublish(id) })
+}
+
+// tracedCompetitionRepository records a span for each call to the wrapped repository
+type tracedCompetitionRepository struct {
+	ctx  context.Context
+	next models.CompetitionRepository
+}
+
+func (r tracedCompetitionRepository) Create(competition *models.Competition) error {
+	return traceCall(r.ctx, "CompetitionRepository.Create", func() error { return r.next.Create(competition) })
+}
+
+func (r tracedCompetitionRepository) GetByID(id string) (*models.Competition, error) {
+	return tracing.Call(r.ctx, "CompetitionRepository.GetByID", func() (*models.Competition, error) { return r.next.GetByID(id) })
+}
+
+func (r tracedCompetitionRepository) GetAll() ([]*models.Competition, error) {
+	return tracing.Call(r.ctx, "CompetitionRepository.GetAll", func() ([]*models.Competition, error) { return r.next.GetAll() })
+}
+
+// tracedAccessTokenRepository records a span for each call to the wrapped repository
+type tracedAccessTokenRepository struct {
+	ctx  context.Context
+	next models.AccessTokenRepository
+}
+
+func (r tracedAccessTokenRepository) Create(token *models.AccessToken) error {
+	return traceCall(r.ctx, "AccessTokenRepository.Create", func() error { return r.next.Create(token) })
+}
+
+func (r tracedAccessTokenRepository) GetByToken(token string) (*models.AccessToken, error) {
+	return tracing.Call(r.ctx, "AccessTokenRepository.GetByToken", func() (*models.AccessToken, error) { return r.next.GetByToken(token) })
+}
+
+func (r tracedAccessTokenRepository) GetByUserID(userID string) ([]*models.AccessToken, error) {
+	return tracing.Call(r.ctx, "AccessTokenRepository.GetByUserID", func() ([]*models.AccessToken, error) { return r.next.GetByUserID(userID) })
+}
+
+func (r tracedAccessTokenRepository) Delete(id string) error {
+	return traceCall(r.ctx, "AccessTokenRepository.Delete", func() error { return r.next.Delete(id) })
+}
+
+func (r tracedAccessTokenRepository) Touch(token string, usedAt time.Time) error {
+	return traceCall(r.ctx, "AccessTokenRepository.Touch", func() error { return r.next.Touch(token, usedAt) })
+}
+
+// tracedIdentityRepository records a span for each call to the wrapped repository
+type tracedIdentityRepository struct {
+	ctx  context.Context
+	next models.IdentityRepository
+}
+
+func (r tracedIdentityRepository) Create(identity *models.Identity) error {
+	return traceCall(r.ctx, "IdentityRepository.Create", func() error { return r.next.Create(identity) })
+}
+
+func (r tracedIdentityRepository) GetByProviderSubject(provider, subject string) (*models.Identity, error) {
+	return tracing.Call(r.ctx, "IdentityRepository.GetByProviderSubject", func() (*models.Identity, error) { return r.next.GetByProviderSubject(provider, subject) })
+}
+
+func (r tracedIdentityRepository) GetByUserID(userID string) ([]*models.Identity, error) {
+	return tracing.Call(r.ctx, "IdentityRepository.GetByUserID", func() ([]*models.Identity, error) { return r.next.GetByUserID(userID) })
+}
+
+func (r tracedIdentityRepository) Update(identity *models.Identity) error {
+	return traceCall(r.ctx, "IdentityRepository.Update", func() error { return r.next.Update(identity) })
+}
+
+func (r tracedIdentityRepository) Delete(id string) error {
+	return traceCall(r.ctx, "IdentityRepository.Delete", func() error { return r.next.Delete(id) })
+}
+
+// tracedLoginLinkRepository records a span for each call to the wrapped repository
+type tracedLoginLinkRepository struct {
+	ctx  context.Context
+	next models.LoginLinkRepository
+}
+
+func (r tracedLoginLinkRepository) Create(link *models.LoginLink) error {
+	return traceCall(r.ctx, "LoginLinkRepository.Create", func() error { return r.next.Create(link) })
+}
+
+func (r tracedLoginLinkRepository) GetByToken(token string) (*models.LoginLink, error) {
+	return tracing.Call(r.ctx, "LoginLinkRepository.GetByToken", func() (*models.LoginLink, error) { return r.next.GetByToken(token) })
+}
+
+func (r tracedLoginLinkRepository) MarkUsed(id string, now time.Time) error {
+	return traceCall(r.ctx, "LoginLinkRepository.MarkUsed", func() error { return r.next.MarkUsed(id, now) })
+}
+
+func (r tracedLoginLinkRepository) DeleteExpired() error {
+	return traceCall(r.ctx, "LoginLinkRepository.DeleteExpired", func() error { return r.next.DeleteExpired() })
+}
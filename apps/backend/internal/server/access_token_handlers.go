@@ -0,0 +1,114 @@
+package server
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/templates"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleAccessTokensList renders the access tokens section
+func (s *Server) handleAccessTokensList(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	s.renderAccessTokensSection(w, r, user.ID, "", Problem{})
+}
+
+// handleAccessTokenCreate creates an access token from the dashboard form and
+// shows its plaintext once
+func (s *Server) handleAccessTokenCreate(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	scopes := make([]models.Scope, len(r.Form["scopes"]))
+	for i, scope := range r.Form["scopes"] {
+		scopes[i] = models.Scope(scope)
+	}
+
+	var token *models.AccessToken
+	expiresAt, err := tokenExpiry(r.FormValue("expires_in"), time.Now())
+	if err == nil {
+		token, err = s.auth.CreateAccessToken(r.Context(), user.ID, r.FormValue("name"), scopes, expiresAt)
+	}
+	if err != nil {
+		s.renderAccessTokensSection(w, r, user.ID, "", s.formProblem(r, err, "Failed to create token. Please try again."))
+		return
+	}
+
+	s.renderAccessTokensSection(w, r, user.ID, token.Token, Problem{})
+}
+
+// handleAccessTokenRevoke revokes one of the user's access tokens
+func (s *Server) handleAccessTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	tokenID := r.FormValue("token_id")
+	if tokenID == "" {
+		http.Error(w, "Missing token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.auth.RevokeAccessToken(r.Context(), user.ID, tokenID); err != nil {
+		if errors.Is(err, models.ErrAccessTokenNotFound) {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	s.renderAccessTokensSection(w, r, user.ID, "", Problem{})
+}
+
+// renderAccessTokensSection renders the access tokens section for the user,
+// with a token just created or the problem with the form, if any
+func (s *Server) renderAccessTokensSection(w http.ResponseWriter, r *http.Request, userID, newToken string, problem Problem) {
+	tokens := s.getAccessTokensData(r.Context(), userID)
+
+	w.Header().Set("Content-Type", "text/html")
+	s.render(w, r, "AccessTokensSection", templates.AccessTokensSection(tokens, newToken, problem.Detail, problem.FieldErrors()))
+}
+
+// getAccessTokensData returns the user's access tokens
+func (s *Server) getAccessTokensData(ctx context.Context, userID string) []models.AccessToken {
+	tokens, err := s.auth.ListAccessTokens(ctx, userID)
+	if err != nil {
+		// Log error but don't fail - just show no tokens
+		tokens = []*models.AccessToken{}
+	}
+
+	// Convert to slice of values instead of pointers
+	tokenValues := make([]models.AccessToken, len(tokens))
+	for i, token := range tokens {
+		tokenValues[i] = *token
+	}
+
+	return tokenValues
+}
+
+// tokenExpiry returns the expiry of a token created now with a lifetime from
+// the dashboard form: a number of days, or "never" for the zero time
+func tokenExpiry(expiresIn string, now time.Time) (time.Time, error) {
+	if expiresIn == "never" {
+		return time.Time{}, nil
+	}
+	days, err := strconv.Atoi(expiresIn)
+	if err != nil || days <= 0 || days > 365 {
+		return time.Time{}, models.NewValidationError(models.ErrInvalidTokenExpiry)
+	}
+	return now.AddDate(0, 0, days), nil
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"compify-backend/internal/templates"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test creating and revoking access tokens from the dashboard, and the
+// scopes they are limited to in the API
+func TestAccessTokens(t *testing.T) {
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  authService,
+	}
+	server.setupRoutes()
+
+	user := createTestUser(t, repos)
+	session := createTestSession(t, repos, user.ID)
+
+	dashboard := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		req.Header.Set(templates.CSRFHeader, server.csrfToken(session.ID))
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+	api := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+	problemCode := func(rec *httptest.ResponseRecorder) string {
+		var problem Problem
+		json.NewDecoder(rec.Body).Decode(&problem)
+		return problem.Code
+	}
+
+	var plaintext string
+	t.Run("creating a token shows it once and stores its hash", func(t *testing.T) {
+		rec := dashboard("POST", "/dashboard/tokens", "name=CI+script&scopes=read:profile&expires_in=30")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		plaintext = regexp.MustCompile(`cpat_[A-Za-z0-9_-]{43}`).FindString(rec.Body.String())
+		if plaintext == "" || !strings.Contains(rec.Body.String(), "CI script") {
+			t.Fatalf("Expected the new token in the section, got %s", rec.Body.String())
+		}
+
+		tokens, _ := authService.ListAccessTokens(context.Background(), user.ID)
+		if len(tokens) != 1 || tokens[0].Token != "" || tokens[0].TokenHash != models.HashSessionToken(plaintext) {
+			t.Fatalf("Expected one token stored by its hash, got %+v", tokens)
+		}
+		if expiry := time.Until(tokens[0].ExpiresAt); expiry < 29*24*time.Hour || expiry > 30*24*time.Hour {
+			t.Errorf("Expected the token to expire in 30 days, got %v", expiry)
+		}
+
+		rec = dashboard("GET", "/dashboard/tokens", "")
+		if strings.Contains(rec.Body.String(), plaintext) || !strings.Contains(rec.Body.String(), plaintext[:11]) {
+			t.Error("Expected the token list to show only the token's prefix")
+		}
+	})
+
+	t.Run("invalid tokens are reported next to their fields", func(t *testing.T) {
+		rec := dashboard("POST", "/dashboard/tokens", "name=&scopes=admin:everything&expires_in=90")
+		body := rec.Body.String()
+		if !strings.Contains(body, models.ErrAccessTokenNameRequired.Message) || !strings.Contains(body, models.ErrInvalidScope.Message) {
+			t.Errorf("Expected name and scope errors, got %s", body)
+		}
+		rec = dashboard("POST", "/dashboard/tokens", "name=Forever&scopes=read:profile&expires_in=9999")
+		if !strings.Contains(rec.Body.String(), models.ErrInvalidTokenExpiry.Message) {
+			t.Errorf("Expected an expiry error, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("scopes are enforced", func(t *testing.T) {
+		rec := api("GET", "/api/v1/me", plaintext, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected read:profile to allow GET /me, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		rec = api("POST", "/api/v1/registrations", plaintext, `{"competition_id": "compify-2024"}`)
+		if rec.Code != http.StatusForbidden || problemCode(rec) != "insufficient_scope" {
+			t.Errorf("Expected 403 insufficient_scope, got %d", rec.Code)
+		}
+
+		rec = api("GET", "/dashboard/sessions", plaintext, "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected access tokens to be refused by the dashboard, got %d", rec.Code)
+		}
+
+		tokens, _ := authService.ListAccessTokens(context.Background(), user.ID)
+		if tokens[0].LastUsedAt.IsZero() {
+			t.Error("Expected the token's last use to be recorded")
+		}
+	})
+
+	t.Run("sessions hold every scope", func(t *testing.T) {
+		rec := api("PATCH", "/api/v1/me", session.Token, `{"first_name": "Ada"}`)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected a session to update the profile, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("expired and unknown tokens are unauthorized", func(t *testing.T) {
+		expired, err := models.NewAccessToken(user.ID, "Old", []models.Scope{models.ScopeReadProfile}, time.Now().Add(-time.Minute))
+		if err == nil {
+			t.Fatal("Expected a token expiring in the past to be invalid")
+		}
+		expired, _ = models.NewAccessToken(user.ID, "Old", []models.Scope{models.ScopeReadProfile}, time.Now().Add(time.Hour))
+		expired.CreatedAt = expired.CreatedAt.Add(-2 * time.Hour)
+		expired.ExpiresAt = expired.ExpiresAt.Add(-2 * time.Hour)
+		if err := repos.AccessTokens.Create(expired); err != nil {
+			t.Fatalf("Failed to save expired token: %v", err)
+		}
+
+		for _, token := range []string{expired.Token, "cpat_unknown"} {
+			if rec := api("GET", "/api/v1/me", token, ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected 401 for %s, got %d", token[:9], rec.Code)
+			}
+		}
+	})
+
+	t.Run("revoking a token", func(t *testing.T) {
+		tokens, _ := authService.ListAccessTokens(context.Background(), user.ID)
+		var id string
+		for _, token := range tokens {
+			if token.TokenHash == models.HashSessionToken(plaintext) {
+				id = token.ID
+			}
+		}
+
+		if rec := dashboard("POST", "/dashboard/tokens/revoke", "token_id="+id); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rec.Code)
+		}
+		if rec := api("GET", "/api/v1/me", plaintext, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a revoked token to be unauthorized, got %d", rec.Code)
+		}
+		if rec := dashboard("POST", "/dashboard/tokens/revoke", "token_id="+id); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a revoked token, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"compify-backend/internal/jobs"
+	"compify-backend/internal/templates"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// registerJobs registers the server's periodic maintenance jobs
+func (s *Server) registerJobs() {
+	interval := s.config.SessionCleanupInterval
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	err := s.jobs.Register(jobs.Job{
+		Name:     "session-cleanup",
+		Schedule: jobs.Every(interval),
+		Jitter:   interval / 10,
+		Timeout:  time.Minute,
+		Run: func(ctx context.Context) error {
+			return s.repos.WithContext(ctx).Sessions.DeleteExpired()
+		},
+	})
+	if err != nil {
+		slog.Error("Failed to register session cleanup job", "error", err)
+	}
+
+	err = s.jobs.Register(jobs.Job{
+		Name:     "login-link-cleanup",
+		Schedule: jobs.Every(interval),
+		Jitter:   interval / 10,
+		Timeout:  time.Minute,
+		Run: func(ctx context.Context) error {
+			return s.repos.WithContext(ctx).LoginLinks.DeleteExpired()
+		},
+	})
+	if err != nil {
+		slog.Error("Failed to register sign-in link cleanup job", "error", err)
+	}
+}
+
+// handleAdminJobs renders the background job status page for admins
+func (s *Server) handleAdminJobs(w http.ResponseWriter, r *http.Request) {
+	var statuses []jobs.Status
+	if s.jobs != nil {
+		statuses = s.jobs.Status()
+	}
+
+	// Serve JSON to API clients
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	s.render(w, r, "AdminJobsPage", templates.AdminJobsPage(statuses))
+}
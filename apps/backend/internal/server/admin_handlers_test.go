@@ -0,0 +1,126 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/jobs"
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that the job status page is restricted to admins
+func TestAdminJobsPage(t *testing.T) {
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	authService.SetAdminEmails([]string{"Admin@Example.com"})
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  authService,
+		jobs:  jobs.NewScheduler(),
+	}
+	server.registerJobs()
+	server.setupRoutes()
+
+	participant := createTestUser(t, repos)
+	participantSession := createTestSession(t, repos, participant.ID)
+
+	admin, adminSession, err := authService.Register(context.Background(), &auth.RegistrationRequest{
+		Email:           "admin@example.com",
+		Username:        "admin",
+		Password:        "password123",
+		ConfirmPassword: "password123",
+	}, "127.0.0.1", "test-agent")
+	if err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	if !admin.IsAdmin() {
+		t.Fatalf("Expected configured admin email to be granted the admin role")
+	}
+
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/jobs", nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+		}
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get(""); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected anonymous request to redirect, got %d", rec.Code)
+	}
+	if rec := get(participantSession.Token); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for participant, got %d", rec.Code)
+	}
+
+	// Run the cleanup job once so the page has history to show
+	policy := models.SessionPolicy{IdleTimeout: time.Millisecond, AbsoluteLifetime: time.Millisecond}
+	expired, err := models.NewSessionWithPolicy(participant.ID, "127.0.0.1", "test-agent", policy)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repos.Sessions.Create(expired); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := server.jobs.RunNow("session-cleanup"); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for server.jobs.Status()[0].Runs == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := repos.Sessions.GetByToken(expired.Token); err != models.ErrSessionNotFound {
+		t.Errorf("Expected expired session to be cleaned up, got %v", err)
+	}
+
+	rec := get(adminSession.Token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for admin, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "session-cleanup") || !strings.Contains(body, "@every 10m0s") {
+		t.Errorf("Expected job status in page, got: %s", body)
+	}
+
+	server.jobs.Stop(context.Background())
+}
+
+// Test that configured admins are promoted on login
+func TestAdminPromotionOnLogin(t *testing.T) {
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+
+	if _, _, err := authService.Register(context.Background(), &auth.RegistrationRequest{
+		Email:           "late@example.com",
+		Username:        "late",
+		Password:        "password123",
+		ConfirmPassword: "password123",
+	}, "127.0.0.1", "test-agent"); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+
+	authService.SetAdminEmails([]string{"late@example.com"})
+	user, session, err := authService.Login(context.Background(), &auth.LoginRequest{Email: "late@example.com", Password: "password123"}, "127.0.0.1", "test-agent")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if !user.IsAdmin() {
+		t.Errorf("Expected user to be promoted to admin on login")
+	}
+	if _, err := authService.GetUserFromSession(context.Background(), session.Token); err != nil {
+		t.Errorf("Session issued after promotion should remain valid: %v", err)
+	}
+}
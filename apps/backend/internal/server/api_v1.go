@@ -0,0 +1,182 @@
+package server
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/participant"
+	"compify-backend/internal/templates"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIResponse is the envelope of every successful /api/v1 response. Errors
+// are Problems.
+type APIResponse struct {
+	Data       any         `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes a page of a list. Passing NextCursor as the cursor
+// query parameter fetches the next page; it is omitted on the last page.
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// registrationRequest is the body of POST /api/v1/registrations
+type registrationRequest struct {
+	CompetitionID string `json:"competition_id"`
+}
+
+// csrfTokenResponse is the body of GET /api/v1/csrf-token
+type csrfTokenResponse struct {
+	Token string `json:"csrf_token"`
+}
+
+// handleAPICSRFToken returns the CSRF token of the session, for clients on
+// other origins that authenticate with the session cookie and cannot read
+// it from a page. CORS only lets allowed origins read the response.
+func (s *Server) handleAPICSRFToken(w http.ResponseWriter, r *http.Request) {
+	writeAPIResponse(w, http.StatusOK, APIResponse{Data: csrfTokenResponse{Token: templates.CSRFToken(r.Context())}})
+}
+
+// handleAPIMe returns the authenticated user
+func (s *Server) handleAPIMe(w http.ResponseWriter, r *http.Request) {
+	writeAPIResponse(w, http.StatusOK, APIResponse{Data: userFromContext(r.Context())})
+}
+
+// handleAPIUpdateMe updates the fields of the user's profile present in the
+// body
+func (s *Server) handleAPIUpdateMe(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	var update participant.ProfileUpdate
+	if !s.decodeAPIRequest(w, r, &update) {
+		return
+	}
+	if err := s.participants.UpdateProfile(r.Context(), user, update); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeAPIResponse(w, http.StatusOK, APIResponse{Data: user})
+}
+
+// handleAPIMyRegistrations lists the user's registrations, newest first
+func (s *Server) handleAPIMyRegistrations(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	request, err := pageRequest(r)
+	if err == nil {
+		var page participant.Page[*models.Registration]
+		if page, err = s.participants.Registrations(r.Context(), user.ID, request); err == nil {
+			writeAPIPage(w, request, page)
+			return
+		}
+	}
+	s.writeError(w, r, err)
+}
+
+// handleAPICreateRegistration registers the user for a competition
+func (s *Server) handleAPICreateRegistration(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	var req registrationRequest
+	if !s.decodeAPIRequest(w, r, &req) {
+		return
+	}
+	registration, err := s.participants.Register(r.Context(), user.ID, strings.TrimSpace(req.CompetitionID))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeAPIResponse(w, http.StatusCreated, APIResponse{Data: registration})
+}
+
+// handleAPICancelRegistration cancels one of the user's registrations
+func (s *Server) handleAPICancelRegistration(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	registration, err := s.participants.CancelRegistration(r.Context(), user.ID, r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	writeAPIResponse(w, http.StatusOK, APIResponse{Data: registration})
+}
+
+// handleAPICompetitions lists competitions, soonest first
+func (s *Server) handleAPICompetitions(w http.ResponseWriter, r *http.Request) {
+	request, err := pageRequest(r)
+	if err == nil {
+		var page participant.Page[*models.Competition]
+		if page, err = s.participants.Competitions(r.Context(), request); err == nil {
+			writeAPIPage(w, request, page)
+			return
+		}
+	}
+	s.writeError(w, r, err)
+}
+
+// handleAPIAnnouncements lists published announcements, newest first
+func (s *Server) handleAPIAnnouncements(w http.ResponseWriter, r *http.Request) {
+	request, err := pageRequest(r)
+	if err == nil {
+		var page participant.Page[*models.Announcement]
+		if page, err = s.participants.Announcements(r.Context(), request); err == nil {
+			writeAPIPage(w, request, page)
+			return
+		}
+	}
+	s.writeError(w, r, err)
+}
+
+// Helper methods
+
+// pageRequest reads the cursor and limit query parameters
+func pageRequest(r *http.Request) (participant.PageRequest, error) {
+	query := r.URL.Query()
+	request := participant.PageRequest{Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return request, participant.ErrInvalidLimit
+		}
+		request.Limit = n
+	}
+	return request, nil
+}
+
+// decodeAPIRequest decodes a JSON request body into v, answering 400 when it
+// is malformed or has unknown fields
+func (s *Server) decodeAPIRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		s.writeError(w, r, errInvalidBody)
+		return false
+	}
+	return true
+}
+
+// writeAPIPage writes a page of a list with its pagination
+func writeAPIPage[T any](w http.ResponseWriter, request participant.PageRequest, page participant.Page[T]) {
+	limit := request.Limit
+	if limit == 0 {
+		limit = participant.DefaultPageLimit
+	}
+	writeAPIResponse(w, http.StatusOK, APIResponse{
+		Data:       page.Items,
+		Pagination: &Pagination{Limit: limit, NextCursor: page.NextCursor},
+	})
+}
+
+// writeAPIResponse writes a JSON response body
+func writeAPIResponse(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test the versioned JSON API: envelopes, pagination, problems and
+// registrations
+func TestAPIV1(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+	}
+	server.setupRoutes()
+
+	user := createTestUser(t, repos)
+	session := createTestSession(t, repos, user.ID)
+
+	start := time.Date(2030, time.June, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		competition := &models.Competition{
+			ID:       fmt.Sprintf("comp-%d", i),
+			Name:     fmt.Sprintf("Competition %d", i),
+			StartsAt: start.AddDate(0, i, 0),
+		}
+		if i == 4 {
+			competition.RegistrationClosesAt = time.Now().Add(-time.Hour)
+		}
+		if err := repos.Competitions.Create(competition); err != nil {
+			t.Fatalf("Failed to create competition: %v", err)
+		}
+	}
+
+	// do sends a request with the session as a Bearer token when authenticated
+	do := func(method, path, body string, authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if authenticated {
+			req.Header.Set("Authorization", "Bearer "+session.Token)
+		}
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("Invalid JSON body %q: %v", rec.Body.String(), err)
+		}
+	}
+
+	t.Run("me requires a session", func(t *testing.T) {
+		rec := do("GET", "/api/v1/me", "", false)
+		var problem Problem
+		decode(t, rec, &problem)
+		if rec.Code != http.StatusUnauthorized || problem.Code != "unauthorized" {
+			t.Errorf("Expected a 401 problem, got %d %q", rec.Code, rec.Body.String())
+		}
+
+		rec = do("GET", "/api/v1/me", "", true)
+		var me struct {
+			Data models.User `json:"data"`
+		}
+		decode(t, rec, &me)
+		if rec.Code != http.StatusOK || me.Data.ID != user.ID || me.Data.Profile.UserID != user.ID {
+			t.Errorf("Expected the user in the envelope, got %d %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("patch me updates only the given fields", func(t *testing.T) {
+		for _, body := range []string{`{"first_name": "Ada"}`, `{"bio": "  Likes puzzles  "}`} {
+			if rec := do("PATCH", "/api/v1/me", body, true); rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d %q", rec.Code, rec.Body.String())
+			}
+		}
+		updated, _ := repos.Users.GetByID(user.ID)
+		if updated.Profile.Bio != "Likes puzzles" || updated.Profile.FirstName != "Ada" {
+			t.Errorf("Unexpected profile %+v", updated.Profile)
+		}
+
+		rec := do("PATCH", "/api/v1/me", `{"first_name": "`+strings.Repeat("a", 101)+`", "bio": "`+strings.Repeat("b", 1001)+`"}`, true)
+		var problem Problem
+		decode(t, rec, &problem)
+		fields := problem.FieldErrors()
+		if rec.Code != http.StatusBadRequest || problem.Code != "validation_failed" || len(fields) != 2 || fields["first_name"] == "" || fields["bio"] == "" {
+			t.Errorf("Expected field errors for first_name and bio, got %d %q", rec.Code, rec.Body.String())
+		}
+		if updated, _ := repos.Users.GetByID(user.ID); updated.Profile.Bio != "Likes puzzles" {
+			t.Error("Invalid updates must not be saved")
+		}
+
+		if rec := do("PATCH", "/api/v1/me", `{"email": "x@example.com"}`, true); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown field, got %d", rec.Code)
+		}
+	})
+
+	t.Run("competitions are paginated with cursors", func(t *testing.T) {
+		var ids []string
+		path := "/api/v1/competitions?limit=2"
+		for pages := 0; path != ""; pages++ {
+			if pages > 3 {
+				t.Fatal("Pagination did not end")
+			}
+			rec := do("GET", path, "", false)
+			var page struct {
+				Data       []models.Competition `json:"data"`
+				Pagination Pagination           `json:"pagination"`
+			}
+			decode(t, rec, &page)
+			if rec.Code != http.StatusOK || page.Pagination.Limit != 2 || len(page.Data) > 2 {
+				t.Fatalf("Unexpected page %d %q", rec.Code, rec.Body.String())
+			}
+			for _, competition := range page.Data {
+				ids = append(ids, competition.ID)
+			}
+			path = ""
+			if page.Pagination.NextCursor != "" {
+				path = "/api/v1/competitions?limit=2&cursor=" + page.Pagination.NextCursor
+			}
+		}
+		if strings.Join(ids, ",") != "comp-0,comp-1,comp-2,comp-3,comp-4" {
+			t.Errorf("Expected every competition once, soonest first, got %v", ids)
+		}
+
+		for _, query := range []string{"limit=0x", "limit=500", "cursor=bogus"} {
+			rec := do("GET", "/api/v1/competitions?"+query, "", false)
+			var problem Problem
+			decode(t, rec, &problem)
+			if rec.Code != http.StatusBadRequest || len(problem.Errors) != 1 {
+				t.Errorf("%s: expected a field error, got %d %q", query, rec.Code, rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("registrations", func(t *testing.T) {
+		rec := do("POST", "/api/v1/registrations", `{"competition_id": "comp-1"}`, true)
+		var created struct {
+			Data models.Registration `json:"data"`
+		}
+		decode(t, rec, &created)
+		if rec.Code != http.StatusCreated || created.Data.Status != models.RegistrationStatusPending {
+			t.Fatalf("Expected a pending registration, got %d %q", rec.Code, rec.Body.String())
+		}
+
+		for body, status := range map[string]int{
+			`{"competition_id": "comp-1"}`:  http.StatusConflict,
+			`{"competition_id": "comp-4"}`:  http.StatusConflict,
+			`{"competition_id": "missing"}`: http.StatusNotFound,
+			`{}`:                            http.StatusBadRequest,
+		} {
+			if rec := do("POST", "/api/v1/registrations", body, true); rec.Code != status {
+				t.Errorf("%s: expected status %d, got %d", body, status, rec.Code)
+			}
+		}
+
+		rec = do("GET", "/api/v1/me/registrations", "", true)
+		var list struct {
+			Data []models.Registration `json:"data"`
+		}
+		decode(t, rec, &list)
+		if len(list.Data) != 1 || list.Data[0].ID != created.Data.ID {
+			t.Errorf("Expected the registration in the list, got %q", rec.Body.String())
+		}
+
+		other := &models.User{Email: "other@example.com", Username: "otheruser"}
+		if err := repos.Users.Create(other); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		foreign := models.NewRegistration(other.ID, "comp-2", nil)
+		if err := repos.Registrations.Create(foreign); err != nil {
+			t.Fatalf("Failed to create registration: %v", err)
+		}
+		if rec := do("DELETE", "/api/v1/registrations/"+foreign.ID, "", true); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for another user's registration, got %d", rec.Code)
+		}
+
+		rec = do("DELETE", "/api/v1/registrations/"+created.Data.ID, "", true)
+		var cancelled struct {
+			Data models.Registration `json:"data"`
+		}
+		decode(t, rec, &cancelled)
+		if rec.Code != http.StatusOK || cancelled.Data.Status != models.RegistrationStatusCancelled {
+			t.Errorf("Expected the cancelled registration, got %d %q", rec.Code, rec.Body.String())
+		}
+
+		if rec := do("POST", "/api/v1/registrations", `{"competition_id": "comp-1"}`, true); rec.Code != http.StatusCreated {
+			t.Errorf("Expected a cancelled registration to be reopened, got %d", rec.Code)
+		}
+	})
+
+	t.Run("cookie sessions need the CSRF token to write", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/api/v1/me", strings.NewReader(`{"bio": "Forged"}`))
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		var problem Problem
+		decode(t, rec, &problem)
+		if rec.Code != http.StatusForbidden || problem.Code != "csrf_token_invalid" {
+			t.Errorf("Expected a 403 problem, got %d %q", rec.Code, rec.Body.String())
+		}
+	})
+}
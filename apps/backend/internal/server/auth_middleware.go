@@ -0,0 +1,89 @@
+package server
+
+import (
+	"compify-backend/internal/logging"
+	"compify-backend/internal/models"
+	"context"
+	"net/http"
+)
+
+// userKey is the context key for the authenticated user
+type userKey struct{}
+
+// requireUser rejects requests without a valid session with 401, for HTMX
+// fragments and API calls. It stores the user in the context for handlers to
+// read with userFromContext.
+func (s *Server) requireUser(next http.Handler) http.Handler {
+	return s.authenticate(next, func(w http.ResponseWriter, r *http.Request) {
+		s.rejectRequest(w, r, errUnauthorized)
+	})
+}
+
+// requireLogin is requireUser for pages, redirecting to the login page
+func (s *Server) requireLogin(next http.Handler) http.Handler {
+	return s.authenticate(next, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+}
+
+// authenticate passes requests with a valid session to next, with the user
+// in the context, and the rest to reject
+func (s *Server) authenticate(next http.Handler, reject http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getAuthenticatedUser(r)
+		if err != nil {
+			reject(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// requireScope is requireUser for API routes that personal access tokens may
+// call. A session grants every scope; a request with an access token in its
+// Authorization header is let through only when the token has the scope.
+func (s *Server) requireScope(scope models.Scope) func(http.Handler) http.Handler {
+	insufficientScope := *errInsufficientScope
+	insufficientScope.Message = "This request needs an access token with the " + string(scope) + " scope"
+
+	return func(next http.Handler) http.Handler {
+		withSession := s.requireUser(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accessToken := s.auth.GetAccessTokenFromRequest(r)
+			if accessToken == "" {
+				withSession.ServeHTTP(w, r)
+				return
+			}
+
+			user, token, err := s.auth.GetUserFromAccessToken(r.Context(), accessToken)
+			if err != nil {
+				s.rejectRequest(w, r, errUnauthorized)
+				return
+			}
+			logging.SetUserID(r.Context(), user.ID)
+			if !token.HasScope(scope) {
+				s.rejectRequest(w, r, &insufficientScope)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+		})
+	}
+}
+
+// requireAdmin rejects users without the admin role; it must run after
+// requireUser or requireLogin
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := userFromContext(r.Context()); user == nil || !user.IsAdmin() {
+			s.rejectRequest(w, r, errForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// userFromContext returns the user stored by requireUser, or nil
+func userFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userKey{}).(*models.User)
+	return user
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// proxyRangeAliases are shorthands accepted in TRUSTED_PROXIES
+var proxyRangeAliases = map[string][]string{
+	"loopback": {"127.0.0.0/8", "::1/128"},
+	"private":  {"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+}
+
+// trustedProxies is the set of networks whose forwarding headers are believed
+type trustedProxies []netip.Prefix
+
+// parseTrustedProxies parses CIDRs, bare addresses and the aliases
+// "loopback" and "private", skipping invalid entries with a warning
+func parseTrustedProxies(entries []string) trustedProxies {
+	var proxies trustedProxies
+	for _, entry := range entries {
+		if aliases, ok := proxyRangeAliases[strings.ToLower(entry)]; ok {
+			proxies = append(proxies, parseTrustedProxies(aliases)...)
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				slog.Warn("Ignoring invalid trusted proxy", "proxy", entry)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies
+}
+
+// contains reports whether the address belongs to a trusted proxy
+func (p trustedProxies) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve determines the client address of a request. Forwarding headers are
+// only consulted when the peer is a trusted proxy, and are read from right to
+// left: each trusted hop vouches for the address before it, and the first
+// untrusted address is the client. Forwarded (RFC 7239) takes precedence
+// over X-Forwarded-For, which takes precedence over X-Real-IP.
+func (p trustedProxies) resolve(r *http.Request) (netip.Addr, bool) {
+	client, ok := parseRemoteAddr(r.RemoteAddr)
+	if !ok || !p.contains(client) {
+		return client, ok
+	}
+
+	var hops []string
+	switch {
+	case len(r.Header.Values("Forwarded")) > 0:
+		hops = forwardedFor(r.Header.Values("Forwarded"))
+	case len(r.Header.Values("X-Forwarded-For")) > 0:
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(value, ",")...)
+		}
+	default:
+		if addr, ok := parseNodeAddr(r.Header.Get("X-Real-IP")); ok {
+			return addr, true
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseNodeAddr(hops[i])
+		if !ok {
+			// An obfuscated or malformed hop: nothing before it can be
+			// attributed, so the last trusted proxy is the best we know
+			break
+		}
+		client = addr
+		if !p.contains(addr) {
+			break
+		}
+	}
+	return client, true
+}
+
+// forwardedFor extracts the for= parameters of Forwarded header values, in
+// order
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, val)
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// parseNodeAddr parses an address from a forwarding header: a bare IPv4 or
+// IPv6 address, or an RFC 7239 node such as "192.0.2.1:80" or
+// "[2001:db8::1]:443", optionally quoted
+func parseNodeAddr(node string) (netip.Addr, bool) {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if node == "" {
+		return netip.Addr{}, false
+	}
+
+	if strings.HasPrefix(node, "[") {
+		end := strings.IndexByte(node, ']')
+		if end < 0 {
+			return netip.Addr{}, false
+		}
+		node = node[1:end]
+	} else if strings.Count(node, ":") == 1 {
+		// IPv4 with port
+		node, _, _ = strings.Cut(node, ":")
+	}
+
+	addr, err := netip.ParseAddr(node)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+// parseRemoteAddr parses the peer address of a connection, which is
+// normally "host:port" but may be a bare address
+func parseRemoteAddr(remoteAddr string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(remoteAddr); err == nil {
+		return addrPort.Addr().Unmap().WithZone(""), true
+	}
+	return parseNodeAddr(remoteAddr)
+}
+
+type clientIPKey struct{}
+
+// clientIPMiddleware resolves the client address once per request, honouring
+// forwarding headers only from the configured trusted proxies
+func (s *Server) clientIPMiddleware(next http.Handler) http.Handler {
+	proxies := parseTrustedProxies(s.config.TrustedProxies)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := "unknown"
+		if addr, ok := proxies.resolve(r); ok {
+			ip = addr.String()
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
+
+// getClientIP returns the client IP address resolved by clientIPMiddleware.
+// Without the middleware no proxy is trusted and the peer address is used.
+func (s *Server) getClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	if addr, ok := parseRemoteAddr(r.RemoteAddr); ok {
+		return addr.String()
+	}
+	return "unknown"
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/repository"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test client IP resolution through trusted proxies
+func TestClientIPResolution(t *testing.T) {
+	proxies := parseTrustedProxies([]string{"10.0.0.0/8", "2001:db8:aaaa::/48", "192.0.2.10", "loopback", "not-a-cidr"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:52000", nil, "203.0.113.7"},
+		{"spoofed header from untrusted peer", "203.0.113.7:52000", map[string][]string{"X-Forwarded-For": {"1.2.3.4"}}, "203.0.113.7"},
+		{"IPv6 peer", "[2001:db8::1]:443", nil, "2001:db8::1"},
+		{"IPv4-mapped IPv6 peer", "[::ffff:203.0.113.7]:443", nil, "203.0.113.7"},
+		{"bare remote address", "203.0.113.7", nil, "203.0.113.7"},
+		{"single trusted proxy", "10.0.0.5:80", map[string][]string{"X-Forwarded-For": {"198.51.100.4"}}, "198.51.100.4"},
+		{"client-supplied entries are ignored", "10.0.0.5:80", map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.4"}}, "198.51.100.4"},
+		{"chain of trusted proxies", "10.0.0.5:80", map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.4, 192.0.2.10", "10.1.1.1"}}, "198.51.100.4"},
+		{"all hops trusted", "127.0.0.1:80", map[string][]string{"X-Forwarded-For": {"10.2.2.2, 10.1.1.1"}}, "10.2.2.2"},
+		{"malformed hop stops the walk", "10.0.0.5:80", map[string][]string{"X-Forwarded-For": {"198.51.100.4, garbage, 10.1.1.1"}}, "10.1.1.1"},
+		{"IPv6 in X-Forwarded-For", "[2001:db8:aaaa::2]:443", map[string][]string{"X-Forwarded-For": {"2001:db8:beef::9"}}, "2001:db8:beef::9"},
+		{"Forwarded header", "10.0.0.5:80", map[string][]string{"Forwarded": {`for=198.51.100.4;proto=https, for="[2001:db8:beef::9]:4711"`}}, "2001:db8:beef::9"},
+		{"Forwarded with port and trusted hop", "10.0.0.5:80", map[string][]string{"Forwarded": {`For="198.51.100.4:8080"`, "for=10.3.3.3;by=10.0.0.5"}}, "198.51.100.4"},
+		{"Forwarded takes precedence", "10.0.0.5:80", map[string][]string{"Forwarded": {"for=198.51.100.4"}, "X-Forwarded-For": {"1.2.3.4"}}, "198.51.100.4"},
+		{"obfuscated Forwarded node", "10.0.0.5:80", map[string][]string{"Forwarded": {"for=_hidden"}}, "10.0.0.5"},
+		{"X-Real-IP from trusted proxy", "10.0.0.5:80", map[string][]string{"X-Real-IP": {"198.51.100.4"}}, "198.51.100.4"},
+		{"X-Real-IP from untrusted peer", "203.0.113.7:52000", map[string][]string{"X-Real-IP": {"198.51.100.4"}}, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		for key, values := range tt.headers {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+		addr, ok := proxies.resolve(req)
+		if !ok || addr.String() != tt.want {
+			t.Errorf("%s: got %v (ok %v), want %s", tt.name, addr, ok, tt.want)
+		}
+	}
+
+	t.Run("sessions record the resolved address", func(t *testing.T) {
+		repos := repository.NewRepositories()
+		server := &Server{
+			router: http.NewServeMux(),
+			config: &Config{
+				Port:           "8080",
+				Environment:    "test",
+				LogLevel:       "info",
+				TrustedProxies: []string{"private"},
+			},
+			repos: repos,
+			auth:  auth.NewService(repos),
+		}
+		server.setupRoutes()
+		handler := server.applyMiddleware(server.router)
+
+		if _, _, err := server.auth.Register(context.Background(), &auth.RegistrationRequest{
+			Email: "proxy@example.com", Username: "proxyuser", Password: "password123", ConfirmPassword: "password123",
+		}, "", ""); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+
+		login := func(remoteAddr, xff string) string {
+			req := httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"email": "proxy@example.com", "password": "password123"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-For", xff)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.Name == "session_token" {
+					session, err := repos.Sessions.GetByToken(cookie.Value)
+					if err != nil {
+						t.Fatalf("Session not found: %v", err)
+					}
+					return session.IPAddress
+				}
+			}
+			t.Fatalf("Login failed with status %d", rec.Code)
+			return ""
+		}
+
+		if ip := login("10.0.0.5:41000", "6.6.6.6, 198.51.100.4"); ip != "198.51.100.4" {
+			t.Errorf("Expected forwarded client address, got %s", ip)
+		}
+		if ip := login("203.0.113.7:41000", "198.51.100.4"); ip != "203.0.113.7" {
+			t.Errorf("Expected untrusted peer address, got %s", ip)
+		}
+	})
+}
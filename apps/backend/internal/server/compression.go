@@ -0,0 +1,293 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Compression settings
+const (
+	// minCompressSize is the smallest body worth compressing; below it the
+	// encoding overhead outweighs the savings
+	minCompressSize = 1024
+
+	// brotliQuality trades ratio for speed on dynamic responses
+	brotliQuality = 4
+)
+
+// Content codings offered by the server
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// compressibleTypes lists media types that benefit from compression.
+// Images other than SVG, fonts and archives are already compressed, and
+// event streams must reach the client unbuffered.
+var compressibleTypes = map[string]bool{
+	"text/html":                true,
+	"text/css":                 true,
+	"text/plain":               true,
+	"text/javascript":          true,
+	"text/xml":                 true,
+	"application/javascript":   true,
+	"application/json":         true,
+	"application/problem+json": true,
+	"application/xml":          true,
+	"image/svg+xml":            true,
+}
+
+// Encoder pools, so each response reuses compression state instead of
+// allocating the large internal buffers
+var (
+	gzipPool = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	brotliPool = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotliQuality)
+	}}
+)
+
+// compressionMiddleware compresses responses with the best encoding the
+// client accepts. Small bodies, incompressible types, event streams and
+// bodiless responses such as 304 are sent as they are.
+func (s *Server) compressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Caches must store a separate variant per encoding
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK}
+		// Not deferred: after a panic nothing may be flushed, so that the
+		// recovery middleware can still replace the response
+		next.ServeHTTP(cw, r)
+		cw.Close()
+	})
+}
+
+// negotiateEncoding picks an encoding from an Accept-Encoding header,
+// preferring brotli when the client weights both equally. It returns "" when
+// the response should not be encoded.
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		weights[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{encodingBrotli, encodingGzip} {
+		q, listed := weights[coding]
+		if !listed {
+			q, listed = weights["*"]
+		}
+		if listed && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// compressWriter decides whether to compress once it knows the status,
+// content type and enough of the body, then encodes the rest on the fly
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	status      int
+	wroteHeader bool // WriteHeader was called by the handler
+	decided     bool // Headers have been sent
+	encoder     io.WriteCloser
+	buf         []byte // Body written before the decision
+}
+
+// WriteHeader records the status; headers are sent once the decision is made
+func (cw *compressWriter) WriteHeader(code int) {
+	// Informational responses precede the real one and pass straight through
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.wroteHeader || cw.decided {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+
+	// Responses without a body, or whose size is known to be small, can be
+	// decided immediately
+	if !bodyAllowed(code) || cw.knownSmall() {
+		cw.decide(false)
+	}
+}
+
+// Write buffers the start of the body until the threshold is reached
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		if cw.knownSmall() {
+			cw.decide(false)
+		} else {
+			cw.buf = append(cw.buf, b...)
+			if len(cw.buf) < minCompressSize {
+				return len(b), nil
+			}
+			if err := cw.decideAndFlush(); err != nil {
+				return 0, err
+			}
+			return len(b), nil
+		}
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends everything written so far. A handler that flushes before the
+// threshold is streaming, so the response is compressed only if the type
+// allows it and encoded data is flushed through.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decideAndFlush()
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the response, sending small bodies uncompressed and
+// returning encoders to their pools
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		// The whole body fits below the threshold
+		cw.decide(false)
+		if len(cw.buf) > 0 {
+			if _, err := cw.ResponseWriter.Write(cw.buf); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cw.encoder == nil {
+		return nil
+	}
+	err := cw.encoder.Close()
+	switch encoder := cw.encoder.(type) {
+	case *gzip.Writer:
+		encoder.Reset(io.Discard)
+		gzipPool.Put(encoder)
+	case *brotli.Writer:
+		encoder.Reset(io.Discard)
+		brotliPool.Put(encoder)
+	}
+	cw.encoder = nil
+	return err
+}
+
+// decideAndFlush makes the decision with the buffered body and writes it
+func (cw *compressWriter) decideAndFlush() error {
+	cw.decide(cw.shouldCompress())
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// shouldCompress checks the response headers against the compression rules
+func (cw *compressWriter) shouldCompress() bool {
+	header := cw.Header()
+	if !bodyAllowed(cw.status) || header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && compressibleTypes[mediaType]
+}
+
+// knownSmall reports whether the handler declared a body below the threshold
+func (cw *compressWriter) knownSmall() bool {
+	length, err := strconv.Atoi(cw.Header().Get("Content-Length"))
+	return err == nil && length < minCompressSize
+}
+
+// decide sends the headers, switching to an encoder when compressing
+func (cw *compressWriter) decide(compress bool) {
+	cw.decided = true
+
+	if compress {
+		header := cw.Header()
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		// The encoded bytes differ from the representation the ETag was
+		// computed for, so it can only be a weak validator
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		switch cw.encoding {
+		case encodingBrotli:
+			encoder := brotliPool.Get().(*brotli.Writer)
+			encoder.Reset(cw.ResponseWriter)
+			cw.encoder = encoder
+		case encodingGzip:
+			encoder := gzipPool.Get().(*gzip.Writer)
+			encoder.Reset(cw.ResponseWriter)
+			cw.encoder = encoder
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// bodyAllowed reports whether a response with the status may have a body
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/repository"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Test Accept-Encoding negotiation
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"identity", ""},
+		{"GZIP", "gzip"},
+		{"gzip;q=bogus, br;q=0.1", "br"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// Test compressing responses through the middleware chain
+func TestCompressionMiddleware(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+	}
+	server.setupRoutes()
+
+	page := "<html><style>" + strings.Repeat(".card { padding: 1rem; } ", 200) + "</style></html>"
+	server.router.HandleFunc("/test/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, page)
+	})
+	server.router.HandleFunc("/test/small", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<p>small</p>")
+	})
+	server.router.HandleFunc("/test/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(make([]byte, 4096))
+	})
+	server.router.HandleFunc("/test/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: hello\n\n")
+		http.NewResponseController(w).Flush()
+	})
+	handler := server.applyMiddleware(server.router)
+
+	do := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+	for encoding, decode := range decoders {
+		t.Run("compresses with "+encoding, func(t *testing.T) {
+			rec := do("/test/page", map[string]string{"Accept-Encoding": encoding})
+			if rec.Header().Get("Content-Encoding") != encoding {
+				t.Fatalf("Expected Content-Encoding %s, got %q", encoding, rec.Header().Get("Content-Encoding"))
+			}
+			if rec.Header().Get("Content-Length") != "" {
+				t.Errorf("Content-Length of the uncompressed body must be removed, got %s", rec.Header().Get("Content-Length"))
+			}
+			if rec.Body.Len() >= len(page) {
+				t.Errorf("Compressed body is not smaller: %d >= %d", rec.Body.Len(), len(page))
+			}
+			if !strings.HasPrefix(rec.Header().Get("ETag"), `W/"`) {
+				t.Errorf("Expected weak ETag on compressed response, got %q", rec.Header().Get("ETag"))
+			}
+
+			reader, err := decode(rec.Body)
+			if err != nil {
+				t.Fatalf("Failed to create decoder: %v", err)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil || string(body) != page {
+				t.Errorf("Decompressed body does not match (err %v)", err)
+			}
+
+			// The weak ETag still validates the cached copy
+			notModified := do("/test/page", map[string]string{"Accept-Encoding": encoding, "If-None-Match": rec.Header().Get("ETag")})
+			if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 || notModified.Header().Get("Content-Encoding") != "" {
+				t.Errorf("Expected bare 304, got %d with encoding %q", notModified.Code, notModified.Header().Get("Content-Encoding"))
+			}
+		})
+	}
+
+	t.Run("Vary is always set", func(t *testing.T) {
+		for _, path := range []string{"/test/page", "/test/small", "/health"} {
+			vary := strings.Join(do(path, nil).Header().Values("Vary"), ",")
+			if !strings.Contains(vary, "Accept-Encoding") {
+				t.Errorf("%s: expected Vary: Accept-Encoding, got %q", path, vary)
+			}
+		}
+	})
+
+	t.Run("skips small, incompressible and streamed responses", func(t *testing.T) {
+		for _, path := range []string{"/test/small", "/test/image", "/test/events"} {
+			rec := do(path, map[string]string{"Accept-Encoding": "gzip, br"})
+			if rec.Header().Get("Content-Encoding") != "" {
+				t.Errorf("%s: expected no compression, got %q", path, rec.Header().Get("Content-Encoding"))
+			}
+		}
+
+		rec := do("/test/events", map[string]string{"Accept-Encoding": "gzip"})
+		if !rec.Flushed || rec.Body.String() != "data: hello\n\n" {
+			t.Errorf("Expected event stream to be flushed unchanged, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("identity without Accept-Encoding", func(t *testing.T) {
+		rec := do("/test/page", nil)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != page {
+			t.Error("Expected uncompressed body without Accept-Encoding")
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxETagBody is the largest response buffered to compute an ETag. Larger
+// responses are streamed without one.
+const maxETagBody = 1 << 20
+
+// etagWriter buffers a successful response so that an ETag can be computed
+// from its body before anything is sent. Error responses, large bodies and
+// streams that flush are passed through unchanged.
+type etagWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	passthrough bool
+	buf         bytes.Buffer
+
+	// cacheHeaders, when set, adds cache headers once the handler has set
+	// its own, just before the status is sent
+	cacheHeaders func(http.Header)
+}
+
+// WriteHeader records the status, passing non-200 responses straight through
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.wroteHeader {
+		return
+	}
+	ew.wroteHeader = true
+	ew.status = code
+	if code != http.StatusOK {
+		ew.passthrough = true
+		ew.sendHeader(code)
+	}
+}
+
+// Write buffers the body until it grows too large to hash
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if !ew.passthrough && ew.buf.Len()+len(b) > maxETagBody {
+		if err := ew.startPassthrough(); err != nil {
+			return 0, err
+		}
+	}
+	if ew.passthrough {
+		return ew.ResponseWriter.Write(b)
+	}
+	return ew.buf.Write(b)
+}
+
+// Flush switches to streaming, since a flushed response can no longer be
+// validated as a whole
+func (ew *etagWriter) Flush() {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if !ew.passthrough {
+		ew.startPassthrough()
+	}
+	if flusher, ok := ew.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// startPassthrough sends the buffered response and writes the rest directly
+func (ew *etagWriter) startPassthrough() error {
+	ew.passthrough = true
+	ew.sendHeader(ew.status)
+	_, err := ew.ResponseWriter.Write(ew.buf.Bytes())
+	ew.buf.Reset()
+	return err
+}
+
+// finish sends the buffered response with an ETag, or 304 Not Modified when
+// the client's copy is current
+func (ew *etagWriter) finish(r *http.Request) {
+	if ew.passthrough {
+		return
+	}
+
+	header := ew.Header()
+	if header.Get("ETag") == "" {
+		header.Set("ETag", strongETag(ew.buf.Bytes()))
+	}
+
+	if notModified(r, header) {
+		header.Del("Content-Length")
+		ew.sendHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Length", strconv.Itoa(ew.buf.Len()))
+	ew.sendHeader(http.StatusOK)
+	ew.ResponseWriter.Write(ew.buf.Bytes())
+}
+
+// sendHeader adds the cache headers and sends the status
+func (ew *etagWriter) sendHeader(code int) {
+	if ew.cacheHeaders != nil {
+		ew.cacheHeaders(ew.Header())
+	}
+	ew.ResponseWriter.WriteHeader(code)
+}
+
+// strongETag returns an ETag for a body. It is strong because identical
+// bytes always produce the same tag.
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when no ETags
+// were sent, against the response headers (RFC 9110 section 13.2.2)
+func notModified(r *http.Request, header http.Header) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagListMatches(inm, header.Get("ETag"))
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		modified, err := http.ParseTime(header.Get("Last-Modified"))
+		if err != nil {
+			return false
+		}
+		return !modified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagListMatches reports whether any tag in an If-None-Match list matches
+// etag using the weak comparison, under which W/"x" and "x" are equal
+func etagListMatches(list, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// isAuthenticatedRequest reports whether the request carries credentials,
+// in which case the response may be personalized and must not be cached
+func isAuthenticatedRequest(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+	_, err := r.Cookie("session_token")
+	return err == nil
+}
+
+// setPublicCache lets browsers and shared caches keep public content for a
+// few minutes, unless the handler chose a policy itself. HTML pages embed
+// the per-response CSP nonce, so they are only ever sent to one client and
+// never stored.
+func setPublicCache(header http.Header) {
+	if header.Get("Cache-Control") != "" {
+		return
+	}
+	if strings.HasPrefix(header.Get("Content-Type"), "text/html") {
+		header.Set("Cache-Control", "private, no-store")
+		return
+	}
+	header.Set("Cache-Control", "public, max-age=300, s-maxage=600")
+	header.Add("Vary", "Cookie")
+}
+
+// setNoStore forbids caching of the response by browsers and proxies
+func setNoStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+}
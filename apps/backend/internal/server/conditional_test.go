@@ -0,0 +1,180 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test content-based ETags, conditional GETs and cache policies
+func TestConditionalRequests(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+	}
+	server.setupRoutes()
+
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server.router.HandleFunc("/test/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		w.Write([]byte("<p>" + r.URL.Query().Get("v") + "</p>"))
+	})
+	server.router.HandleFunc("/test/data", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"v": 1}`))
+	})
+	server.router.HandleFunc("/test/weak", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `W/"v1"`)
+		w.Write([]byte("weak"))
+	})
+	server.router.HandleFunc("/test/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		http.NewResponseController(w).Flush()
+		w.Write([]byte("second"))
+	})
+	handler := server.applyMiddleware(server.router)
+
+	do := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("ETag is derived from the body", func(t *testing.T) {
+		first := do("GET", "/test/page?v=a", nil)
+		etag := first.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+			t.Fatalf("Expected a strong ETag, got %q", etag)
+		}
+		if first.Header().Get("Content-Length") != "8" || first.Body.String() != "<p>a</p>" {
+			t.Errorf("Unexpected response %q with length %s", first.Body.String(), first.Header().Get("Content-Length"))
+		}
+
+		time.Sleep(1100 * time.Millisecond) // The previous implementation changed tags every second
+		if again := do("GET", "/test/page?v=a", nil).Header().Get("ETag"); again != etag {
+			t.Errorf("Same content produced different ETags %q and %q", etag, again)
+		}
+		if other := do("GET", "/test/page?v=b", nil).Header().Get("ETag"); other == etag {
+			t.Error("Different content produced the same ETag")
+		}
+	})
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		etag := do("GET", "/test/page?v=a", nil).Header().Get("ETag")
+
+		for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+			rec := do("GET", "/test/page?v=a", map[string]string{"If-None-Match": inm})
+			if rec.Code != http.StatusNotModified {
+				t.Errorf("If-None-Match %s: expected status 304, got %d", inm, rec.Code)
+			}
+			if rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+				t.Errorf("If-None-Match %s: expected empty body with ETag, got %q", inm, rec.Body.String())
+			}
+		}
+
+		if rec := do("GET", "/test/page?v=b", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusOK {
+			t.Errorf("Expected changed content to get status 200, got %d", rec.Code)
+		}
+		if rec := do("HEAD", "/test/page?v=a", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusNotModified {
+			t.Errorf("Expected HEAD to get status 304, got %d", rec.Code)
+		}
+	})
+
+	t.Run("If-Modified-Since", func(t *testing.T) {
+		rec := do("GET", "/test/page?v=a", map[string]string{"If-Modified-Since": lastModified.Add(time.Minute).Format(http.TimeFormat)})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("Expected status 304, got %d", rec.Code)
+		}
+
+		rec = do("GET", "/test/page?v=a", map[string]string{"If-Modified-Since": lastModified.Add(-time.Minute).Format(http.TimeFormat)})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200 for older copy, got %d", rec.Code)
+		}
+
+		// If-None-Match takes precedence
+		rec = do("GET", "/test/page?v=a", map[string]string{
+			"If-None-Match":     `"stale"`,
+			"If-Modified-Since": lastModified.Add(time.Minute).Format(http.TimeFormat),
+		})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected If-None-Match to override If-Modified-Since, got %d", rec.Code)
+		}
+	})
+
+	t.Run("handler ETags are kept", func(t *testing.T) {
+		rec := do("GET", "/test/weak", nil)
+		if rec.Header().Get("ETag") != `W/"v1"` {
+			t.Errorf("Expected handler's weak ETag, got %q", rec.Header().Get("ETag"))
+		}
+		if rec := do("GET", "/test/weak", map[string]string{"If-None-Match": `"v1"`}); rec.Code != http.StatusNotModified {
+			t.Errorf("Expected weak comparison to match, got %d", rec.Code)
+		}
+	})
+
+	t.Run("streamed responses are not buffered", func(t *testing.T) {
+		rec := do("GET", "/test/stream", nil)
+		if rec.Body.String() != "firstsecond" || rec.Header().Get("ETag") != "" || !rec.Flushed {
+			t.Errorf("Unexpected streamed response %q, ETag %q, flushed %v", rec.Body.String(), rec.Header().Get("ETag"), rec.Flushed)
+		}
+	})
+
+	t.Run("personalized and mutation responses are not cached", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			method  string
+			path    string
+			headers map[string]string
+		}{
+			{"session cookie", "GET", "/test/page?v=a", map[string]string{"Cookie": "session_token=abc"}},
+			{"authorization header", "GET", "/test/page?v=a", map[string]string{"Authorization": "Bearer abc"}},
+			{"dashboard fragment", "GET", "/dashboard/sessions", nil},
+			{"mutation", "POST", "/test/page?v=a", nil},
+		}
+		for _, tc := range cases {
+			rec := do(tc.method, tc.path, tc.headers)
+			if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "no-store") {
+				t.Errorf("%s: expected no-store, got %q", tc.name, cc)
+			}
+			if rec.Header().Get("ETag") != "" {
+				t.Errorf("%s: expected no ETag, got %q", tc.name, rec.Header().Get("ETag"))
+			}
+		}
+
+		rec := do("GET", "/test/data", nil)
+		if cc := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "public") || !strings.Contains(strings.Join(rec.Header().Values("Vary"), ","), "Cookie") {
+			t.Errorf("Expected public caching for anonymous data, got %q", cc)
+		}
+	})
+
+	t.Run("HTML pages are not stored", func(t *testing.T) {
+		// Pages embed a per-response CSP nonce, which must not be shared
+		for _, path := range []string{"/test/page?v=a", "/does-not-exist"} {
+			if cc := do("GET", path, nil).Header().Get("Cache-Control"); cc != "private, no-store" {
+				t.Errorf("%s: expected private, no-store, got %q", path, cc)
+			}
+		}
+	})
+
+	t.Run("error responses pass through", func(t *testing.T) {
+		rec := do("GET", "/does-not-exist", nil)
+		if rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" {
+			t.Errorf("Expected 404 without ETag, got %d with %q", rec.Code, rec.Header().Get("ETag"))
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"compify-backend/internal/templates"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corsMaxAge is how long browsers may cache a successful preflight
+const corsMaxAge = 10 * time.Minute
+
+// htmxHeaders are the request headers HTMX adds to its requests
+var htmxHeaders = []string{"HX-Request", "HX-Target", "HX-Trigger", "HX-Trigger-Name", "HX-Current-URL", "HX-Boosted", "HX-Prompt"}
+
+// corsRule is the cross-origin policy for routes under a path prefix
+type corsRule struct {
+	prefix      string
+	methods     []string
+	headers     []string
+	credentials bool // Whether cookies and Authorization may be sent
+}
+
+// corsRules are matched in order; the first rule whose prefix matches the
+// request path applies. Routes that authenticate with the session cookie
+// allow credentials, public read-only routes do not. Cross-origin clients
+// using the cookie fetch a CSRF token from /api/v1/csrf-token and send it in
+// the X-CSRF-Token header.
+var corsRules = []corsRule{
+	{
+		prefix:      "/api/auth/",
+		methods:     []string{http.MethodPost},
+		headers:     []string{"Content-Type", "X-Requested-With", templates.CSRFHeader},
+		credentials: true,
+	},
+	{
+		prefix:      "/api/",
+		methods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		headers:     []string{"Content-Type", "Authorization", "X-Requested-With", templates.CSRFHeader},
+		credentials: true,
+	},
+	{
+		prefix:      "/auth/",
+		methods:     []string{http.MethodPost},
+		headers:     append([]string{"Content-Type"}, htmxHeaders...),
+		credentials: true,
+	},
+	{
+		prefix:      "/dashboard",
+		methods:     []string{http.MethodGet, http.MethodPost},
+		headers:     append([]string{"Content-Type"}, htmxHeaders...),
+		credentials: true,
+	},
+	{
+		prefix:  "/",
+		methods: []string{http.MethodGet, http.MethodHead},
+		headers: []string{"Content-Type"},
+	},
+}
+
+// corsPolicy decides which origins may make cross-origin requests
+type corsPolicy struct {
+	origins  map[string]bool
+	allowAny bool // "*" configured: any origin, never with credentials
+	rules    []corsRule
+}
+
+// newCORSPolicy creates a policy for the configured origins, defaulting to
+// the static site and sandbox
+func (s *Server) newCORSPolicy() *corsPolicy {
+	origins := s.config.CORSOrigins
+	if len(origins) == 0 {
+		origins = []string{s.getStaticSiteURL(), s.getSandboxURL()}
+	}
+
+	policy := &corsPolicy{origins: make(map[string]bool), rules: corsRules}
+	for _, origin := range origins {
+		if origin == "*" {
+			policy.allowAny = true
+			continue
+		}
+		normalized, ok := normalizeOrigin(origin)
+		if !ok {
+			slog.Warn("Ignoring invalid CORS origin", "origin", origin)
+			continue
+		}
+		policy.origins[normalized] = true
+	}
+	return policy
+}
+
+// normalizeOrigin reduces a URL to its origin: lowercase scheme and host with
+// any non-default port
+func normalizeOrigin(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return scheme + "://" + host, true
+}
+
+// rule returns the policy for a request path
+func (p *corsPolicy) rule(path string) corsRule {
+	for _, rule := range p.rules {
+		if strings.HasPrefix(path, rule.prefix) || path+"/" == rule.prefix {
+			return rule
+		}
+	}
+	return corsRule{}
+}
+
+// allowed reports whether the origin is in the allowlist
+func (p *corsPolicy) allowed(origin string) bool {
+	normalized, ok := normalizeOrigin(origin)
+	return ok && p.origins[normalized]
+}
+
+// corsMiddleware applies the CORS policy. Allowed origins are echoed back,
+// with credentials where the route permits them; preflights from unknown
+// origins, or for methods or headers the route does not accept, are refused.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	policy := s.newCORSPolicy()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Responses differ by origin, so shared caches must key on it
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if origin == "" {
+			// Same-origin or non-browser request
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rule := policy.rule(r.URL.Path)
+		explicit := policy.allowed(origin)
+		if !explicit && !policy.allowAny {
+			if preflight {
+				http.Error(w, "CORS origin not allowed", http.StatusForbidden)
+				return
+			}
+			// The browser blocks the response without CORS headers
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Credentials require an exact origin, never the wildcard
+		credentials := rule.credentials && explicit
+		if credentials {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		} else if explicit {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+		method := r.Header.Get("Access-Control-Request-Method")
+		if !containsFold(rule.methods, method) {
+			http.Error(w, "CORS method not allowed", http.StatusForbidden)
+			return
+		}
+		for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+			if header = strings.TrimSpace(header); header != "" && !containsFold(rule.headers, header) {
+				http.Error(w, "CORS header not allowed", http.StatusForbidden)
+				return
+			}
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(rule.methods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(rule.headers, ", "))
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
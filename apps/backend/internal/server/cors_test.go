@@ -0,0 +1,178 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/repository"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test the CORS origin allowlist, credentials and preflight handling
+func TestCORSPolicy(t *testing.T) {
+	repos := repository.NewRepositories()
+	newHandler := func(origins []string) http.Handler {
+		server := &Server{
+			router: http.NewServeMux(),
+			config: &Config{
+				Port:        "8080",
+				Environment: "test",
+				LogLevel:    "info",
+				CORSOrigins: origins,
+			},
+			repos: repos,
+			auth:  auth.NewService(repos),
+		}
+		server.setupRoutes()
+		return server.applyMiddleware(server.router)
+	}
+	handler := newHandler([]string{"https://compify.com", "https://sandbox.compify.com:443/"})
+
+	do := func(h http.Handler, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("credentialed request from allowed origin", func(t *testing.T) {
+		rec := do(handler, "POST", "/api/auth/logout", map[string]string{"Origin": "https://sandbox.compify.com"})
+		if rec.Header().Get("Access-Control-Allow-Origin") != "https://sandbox.compify.com" {
+			t.Errorf("Expected origin to be echoed, got %q", rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Error("Expected credentials to be allowed on auth API")
+		}
+		if !strings.Contains(strings.Join(rec.Header().Values("Vary"), ","), "Origin") {
+			t.Errorf("Expected Vary: Origin, got %v", rec.Header().Values("Vary"))
+		}
+	})
+
+	t.Run("public route without credentials", func(t *testing.T) {
+		rec := do(handler, "GET", "/health", map[string]string{"Origin": "https://compify.com"})
+		if rec.Header().Get("Access-Control-Allow-Origin") != "https://compify.com" {
+			t.Errorf("Expected origin to be echoed, got %q", rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Error("Public routes must not allow credentials")
+		}
+	})
+
+	t.Run("unknown origin gets no CORS headers", func(t *testing.T) {
+		rec := do(handler, "GET", "/health", map[string]string{"Origin": "https://evil.example"})
+		if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected no Allow-Origin, got %q", rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if !strings.Contains(strings.Join(rec.Header().Values("Vary"), ","), "Origin") {
+			t.Error("Expected Vary: Origin on rejected origins too")
+		}
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		rec := do(handler, "OPTIONS", "/api/auth/login", map[string]string{
+			"Origin":                         "https://compify.com",
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "content-type",
+		})
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", rec.Code)
+		}
+		if rec.Header().Get("Access-Control-Allow-Methods") != "POST" || !strings.Contains(rec.Header().Get("Access-Control-Allow-Headers"), "Content-Type") {
+			t.Errorf("Unexpected preflight headers %v", rec.Header())
+		}
+		if rec.Header().Get("Access-Control-Max-Age") == "" {
+			t.Error("Expected Access-Control-Max-Age")
+		}
+
+		rejected := []struct {
+			name    string
+			path    string
+			headers map[string]string
+		}{
+			{"unknown origin", "/api/auth/login", map[string]string{"Origin": "https://evil.example", "Access-Control-Request-Method": "POST"}},
+			{"lookalike origin", "/api/auth/login", map[string]string{"Origin": "https://compify.com.evil.example", "Access-Control-Request-Method": "POST"}},
+			{"method not allowed", "/api/auth/login", map[string]string{"Origin": "https://compify.com", "Access-Control-Request-Method": "DELETE"}},
+			{"header not allowed", "/api/auth/login", map[string]string{"Origin": "https://compify.com", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "X-Custom"}},
+			{"mutation on public route", "/health", map[string]string{"Origin": "https://compify.com", "Access-Control-Request-Method": "POST"}},
+		}
+		for _, tc := range rejected {
+			rec := do(handler, "OPTIONS", tc.path, tc.headers)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("%s: expected status 403, got %d", tc.name, rec.Code)
+			}
+			if rec.Header().Get("Access-Control-Allow-Methods") != "" {
+				t.Errorf("%s: rejected preflight must not allow methods", tc.name)
+			}
+		}
+	})
+
+	t.Run("credentialed write with CSRF token", func(t *testing.T) {
+		for _, path := range []string{"/api/auth/logout", "/api/v1/me"} {
+			rec := do(handler, "OPTIONS", path, map[string]string{
+				"Origin":                         "https://compify.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "content-type, x-csrf-token",
+			})
+			if rec.Code != http.StatusNoContent || !strings.Contains(rec.Header().Get("Access-Control-Allow-Headers"), "X-CSRF-Token") {
+				t.Errorf("%s: expected the preflight to allow X-CSRF-Token, got %d %v", path, rec.Code, rec.Header())
+			}
+		}
+
+		user := createTestUser(t, repos)
+		cookie := "session_token=" + createTestSession(t, repos, user.ID).Token
+
+		rec := do(handler, "GET", "/api/v1/csrf-token", map[string]string{"Origin": "https://compify.com", "Cookie": cookie})
+		var body struct {
+			Data csrfTokenResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || rec.Code != http.StatusOK || body.Data.Token == "" {
+			t.Fatalf("Expected a CSRF token, got %d %v", rec.Code, err)
+		}
+		if rec.Header().Get("Access-Control-Allow-Origin") != "https://compify.com" || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("Expected the token to be readable by the allowed origin, got %v", rec.Header())
+		}
+
+		rec = do(handler, "POST", "/api/auth/logout", map[string]string{"Origin": "https://compify.com", "Cookie": cookie})
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("Expected status 403 without the token, got %d", rec.Code)
+		}
+		rec = do(handler, "POST", "/api/auth/logout", map[string]string{"Origin": "https://compify.com", "Cookie": cookie, "X-CSRF-Token": body.Data.Token})
+		if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("Expected the credentialed POST to succeed, got %d %v", rec.Code, rec.Header())
+		}
+	})
+
+	t.Run("HTMX preflight on dashboard", func(t *testing.T) {
+		rec := do(handler, "OPTIONS", "/dashboard/sessions/revoke", map[string]string{
+			"Origin":                         "https://compify.com",
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "hx-request, hx-target, hx-current-url",
+		})
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("Expected credentialed preflight to succeed, got %d %v", rec.Code, rec.Header())
+		}
+	})
+
+	t.Run("defaults to static site and sandbox", func(t *testing.T) {
+		h := newHandler(nil)
+		for _, origin := range []string{"http://localhost:4321", "http://localhost:5173"} {
+			rec := do(h, "GET", "/health", map[string]string{"Origin": origin})
+			if rec.Header().Get("Access-Control-Allow-Origin") != origin {
+				t.Errorf("Expected default origin %s to be allowed", origin)
+			}
+		}
+	})
+
+	t.Run("wildcard never allows credentials", func(t *testing.T) {
+		h := newHandler([]string{"*"})
+		rec := do(h, "POST", "/api/auth/logout", map[string]string{"Origin": "https://anywhere.example"})
+		if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("Expected wildcard without credentials, got %v", rec.Header())
+		}
+	})
+}
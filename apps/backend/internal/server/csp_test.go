@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"compify-backend/internal/auth"
+	"compify-backend/internal/logging"
+	"compify-backend/internal/repository"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Test the nonce-based Content Security Policy and violation reporting
+func TestContentSecurityPolicy(t *testing.T) {
+	repos := repository.NewRepositories()
+	newHandler := func(environment string) http.Handler {
+		server := &Server{
+			router: http.NewServeMux(),
+			config: &Config{
+				Port:        "8080",
+				Environment: environment,
+				LogLevel:    "info",
+			},
+			repos: repos,
+			auth:  auth.NewService(repos),
+		}
+		server.setupRoutes()
+		return server.applyMiddleware(server.router)
+	}
+	handler := newHandler("test")
+
+	do := func(h http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	noncePattern := regexp.MustCompile(`'nonce-([A-Za-z0-9+/=]+)'`)
+
+	t.Run("inline scripts and styles carry the response nonce", func(t *testing.T) {
+		rec := do(handler, "GET", "/login", "", "")
+		csp := rec.Header().Get("Content-Security-Policy")
+		match := noncePattern.FindStringSubmatch(csp)
+		if match == nil {
+			t.Fatalf("Expected a nonce in the policy, got %q", csp)
+		}
+		if strings.Contains(csp, "unsafe-eval") || strings.Contains(csp, "script-src 'self' 'unsafe-inline'") {
+			t.Errorf("Policy must not allow eval or inline scripts, got %q", csp)
+		}
+
+		body := rec.Body.String()
+		nonce := `nonce="` + match[1] + `"`
+		for _, tag := range []string{"<script", "<style"} {
+			count := strings.Count(body, tag)
+			if count == 0 || strings.Count(body, tag+" "+nonce) != count {
+				t.Errorf("Expected every %s tag to carry %s, got: %s", tag, nonce, body)
+			}
+		}
+
+		again := noncePattern.FindStringSubmatch(do(handler, "GET", "/login", "", "").Header().Get("Content-Security-Policy"))
+		if again == nil || again[1] == match[1] {
+			t.Error("Expected a fresh nonce for every response")
+		}
+	})
+
+	t.Run("HSTS and Permissions-Policy only in production", func(t *testing.T) {
+		rec := do(handler, "GET", "/health", "", "")
+		if rec.Header().Get("Strict-Transport-Security") != "" || rec.Header().Get("Permissions-Policy") != "" {
+			t.Error("Expected no HSTS or Permissions-Policy outside production")
+		}
+
+		rec = do(newHandler("production"), "GET", "/health", "", "")
+		if !strings.HasPrefix(rec.Header().Get("Strict-Transport-Security"), "max-age=") {
+			t.Errorf("Expected HSTS in production, got %q", rec.Header().Get("Strict-Transport-Security"))
+		}
+		if !strings.Contains(rec.Header().Get("Permissions-Policy"), "camera=()") {
+			t.Errorf("Expected Permissions-Policy in production, got %q", rec.Header().Get("Permissions-Policy"))
+		}
+	})
+
+	t.Run("violation reports are logged", func(t *testing.T) {
+		var buf bytes.Buffer
+		previous := slog.Default()
+		slog.SetDefault(logging.New(&buf, "info", logging.FormatJSON))
+		defer slog.SetDefault(previous)
+
+		legacy := `{"csp-report": {"document-uri": "https://compify.com/login", "violated-directive": "script-src-elem", "blocked-uri": "https://evil.example/x.js"}}`
+		if rec := do(handler, "POST", cspReportPath, "application/csp-report", legacy); rec.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", rec.Code)
+		}
+		reporting := `[{"type": "csp-violation", "body": {"documentURL": "https://compify.com/dashboard", "effectiveDirective": "style-src-elem", "blockedURL": "inline"}}]`
+		if rec := do(handler, "POST", cspReportPath, "application/reports+json", reporting); rec.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", rec.Code)
+		}
+
+		logs := buf.String()
+		for _, want := range []string{`"blocked_uri":"https://evil.example/x.js"`, `"violated_directive":"style-src-elem"`, `"document_uri":"https://compify.com/dashboard"`} {
+			if !strings.Contains(logs, want) {
+				t.Errorf("Expected %s in logs, got: %s", want, logs)
+			}
+		}
+
+		if rec := do(handler, "POST", cspReportPath, "application/csp-report", "not json"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for malformed report, got %d", rec.Code)
+		}
+		if rec := do(handler, "GET", cspReportPath, "", ""); rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405 for GET, got %d", rec.Code)
+		}
+	})
+}
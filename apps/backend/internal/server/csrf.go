@@ -0,0 +1,141 @@
+package server
+
+import (
+	"compify-backend/internal/templates"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// minCSRFSecretLength is the shortest CSRF_SECRET accepted without a warning
+const minCSRFSecretLength = 32
+
+// csrfFallbackSecret is used when CSRF_SECRET is not configured. Tokens then
+// stop validating after a restart, and instances behind a load balancer
+// reject each other's tokens.
+var csrfFallbackSecret = sync.OnceValue(func() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+})
+
+// checkCSRFSecret warns when the CSRF secret is missing or weak
+func (s *Server) checkCSRFSecret() {
+	switch secret := s.config.CSRFSecret; {
+	case secret == "":
+		slog.Warn("CSRF_SECRET is not set, using a random per-process secret")
+	case len(secret) < minCSRFSecretLength:
+		slog.Warn("CSRF_SECRET is shorter than recommended", "min_length", minCSRFSecretLength)
+	}
+}
+
+// csrfToken derives the CSRF token for a session. It is bound to the session
+// ID rather than the cookie value, so it survives token rotation, and cannot
+// be computed without the server's secret.
+func (s *Server) csrfToken(sessionID string) string {
+	mac := hmac.New(sha256.New, s.signingSecret())
+	mac.Write([]byte("csrf:" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signingSecret returns the key for values the server signs and later
+// verifies, such as CSRF tokens. Each use prefixes what it signs with its
+// purpose so that one kind of value cannot stand in for another.
+func (s *Server) signingSecret() []byte {
+	if s.config.CSRFSecret != "" {
+		return []byte(s.config.CSRFSecret)
+	}
+	return csrfFallbackSecret()
+}
+
+// validCSRFToken compares a submitted token with the session's in constant time
+func (s *Server) validCSRFToken(sessionID, token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(s.csrfToken(sessionID)))
+}
+
+// csrfMiddleware protects cookie-authenticated requests against cross-site
+// request forgery. Pages receive the session's token through the template
+// context; state-changing requests must send it back in the X-CSRF-Token
+// header or csrf_token form field. Requests without a session cookie have no
+// ambient credential to abuse, so they are exempt. Whenever the cookie is
+// sent it authenticates the request, even alongside a Bearer token, so the
+// token is checked then. It is attached to the route groups that accept
+// cookies; CSP violation reports are outside them, since browsers send those
+// without any way to attach a token.
+func (s *Server) csrfMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session_token")
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The session is looked up at most once, and only when needed
+		ctx := r.Context()
+		sessionID := sync.OnceValue(func() string {
+			session, err := s.auth.GetSession(ctx, cookie.Value)
+			if err != nil || !session.IsValid() {
+				return ""
+			}
+			return session.ID
+		})
+		r = r.WithContext(templates.WithCSRFToken(ctx, func() string {
+			if id := sessionID(); id != "" {
+				return s.csrfToken(id)
+			}
+			return ""
+		}))
+
+		if isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// An unknown or expired session is rejected by the handler itself
+		id := sessionID()
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.Header.Get(templates.CSRFHeader)
+		if token == "" {
+			token = r.PostFormValue(templates.CSRFField)
+		}
+		if !s.validCSRFToken(id, token) {
+			slog.WarnContext(ctx, "CSRF token rejected", "path", r.URL.Path, "token_present", token != "")
+			s.rejectRequest(w, r, errInvalidCSRF)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfUnlessAccessToken is csrfMiddleware for routes guarded by
+// requireScope. A request carrying a personal access token is authenticated
+// by that token alone and its cookie is ignored, so a forged request can
+// only act as the token's owner and needs no CSRF token.
+func (s *Server) csrfUnlessAccessToken(next http.Handler) http.Handler {
+	withCSRF := s.csrfMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.auth.GetAccessTokenFromRequest(r) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		withCSRF.ServeHTTP(w, r)
+	})
+}
+
+// isSafeMethod reports whether the method is read-only by definition
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
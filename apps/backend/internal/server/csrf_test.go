@@ -0,0 +1,150 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test CSRF token injection and enforcement on cookie-authenticated POSTs
+func TestCSRFProtection(t *testing.T) {
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+			CSRFSecret:  "test-csrf-secret-with-enough-length",
+		},
+		repos: repos,
+		auth:  authService,
+	}
+	server.setupRoutes()
+	handler := server.applyMiddleware(server.router)
+
+	user := createTestUser(t, repos)
+	session := createTestSession(t, repos, user.ID)
+	token := server.csrfToken(session.ID)
+
+	do := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	cookie := "session_token=" + session.Token
+
+	t.Run("token is injected into pages", func(t *testing.T) {
+		rec := do("GET", "/dashboard", "", map[string]string{"Cookie": cookie})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		body := html.UnescapeString(rec.Body.String())
+		if !strings.Contains(body, `hx-headers="{"X-CSRF-Token":"`+token+`"}"`) {
+			t.Errorf("Expected hx-headers with the session's token, got: %s", body)
+		}
+		if !strings.Contains(body, `name="csrf_token" value="`+token+`"`) {
+			t.Error("Expected hidden csrf_token field in the logout form")
+		}
+
+		if body := do("GET", "/login", "", nil).Body.String(); strings.Contains(body, "hx-headers") {
+			t.Error("Anonymous pages must not carry a CSRF token")
+		}
+	})
+
+	t.Run("cookie-authenticated POSTs require the token", func(t *testing.T) {
+		for _, path := range []string{"/dashboard/profile/update/first-name", "/dashboard/registration/create", "/auth/logout"} {
+			rec := do("POST", path, "first_name=Mallory", map[string]string{"Cookie": cookie})
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("%s without token: expected status 403, got %d", path, rec.Code)
+			}
+			rec = do("POST", path, "first_name=Mallory", map[string]string{"Cookie": cookie, "X-CSRF-Token": server.csrfToken("other-session")})
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("%s with another session's token: expected status 403, got %d", path, rec.Code)
+			}
+		}
+		if _, err := repos.Sessions.GetByToken(session.Token); err != nil {
+			t.Fatalf("Forged logout must not end the session: %v", err)
+		}
+	})
+
+	t.Run("valid token in header or form field", func(t *testing.T) {
+		rec := do("POST", "/dashboard/profile/update/first-name", "first_name=Alice", map[string]string{"Cookie": cookie, "X-CSRF-Token": token})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with header token, got %d", rec.Code)
+		}
+		rec = do("POST", "/dashboard/profile/update/first-name", "first_name=Alice&csrf_token="+token, map[string]string{"Cookie": cookie})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with form token, got %d", rec.Code)
+		}
+	})
+
+	t.Run("token survives session rotation", func(t *testing.T) {
+		rotated, err := authService.RotateSession(context.Background(), session.Token)
+		if err != nil {
+			t.Fatalf("Failed to rotate session: %v", err)
+		}
+		cookie = "session_token=" + rotated.Token
+		rec := do("POST", "/dashboard/profile/update/first-name", "first_name=Alice", map[string]string{"Cookie": cookie, "X-CSRF-Token": token})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200 after rotation, got %d", rec.Code)
+		}
+	})
+
+	t.Run("bearer alongside the cookie", func(t *testing.T) {
+		// The cookie still authenticates, so a junk Bearer value must not
+		// skip the check
+		for _, path := range []string{"/dashboard/profile/update/first-name", "/api/auth/logout"} {
+			rec := do("POST", path, "first_name=Mallory", map[string]string{"Cookie": cookie, "Authorization": "Bearer junk"})
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("%s with cookie and bogus Bearer: expected status 403, got %d", path, rec.Code)
+			}
+		}
+		rec := do("PATCH", "/api/v1/me", `{"first_name":"Mallory"}`, map[string]string{"Cookie": cookie, "Authorization": "Bearer junk", "Content-Type": "application/json"})
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("API write with cookie and bogus Bearer: expected status 403, got %d", rec.Code)
+		}
+
+		// An access token replaces the cookie on API routes, so it is checked
+		// instead of the CSRF token
+		rec = do("PATCH", "/api/v1/me", `{"first_name":"Mallory"}`, map[string]string{"Cookie": cookie, "Authorization": "Bearer " + models.AccessTokenPrefix + "bogus", "Content-Type": "application/json"})
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("API write with cookie and bogus access token: expected status 401, got %d", rec.Code)
+		}
+		if user, _ := repos.Users.GetByID(user.ID); user.Profile.FirstName == "Mallory" {
+			t.Error("Forged requests must not change the profile")
+		}
+	})
+
+	t.Run("exemptions", func(t *testing.T) {
+		// Bearer tokens are never attached by the browser
+		rec := do("POST", "/dashboard/profile/update/first-name", "first_name=Bob", map[string]string{"Authorization": "Bearer " + strings.TrimPrefix(cookie, "session_token=")})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected Bearer request to be exempt, got %d", rec.Code)
+		}
+
+		// Without a session there is nothing to forge; the handler decides
+		rec = do("POST", "/dashboard/profile/update/first-name", "first_name=Bob", nil)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 without session, got %d", rec.Code)
+		}
+		rec = do("POST", "/dashboard/profile/update/first-name", "first_name=Bob", map[string]string{"Cookie": "session_token=stale"})
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 with unknown session, got %d", rec.Code)
+		}
+	})
+}
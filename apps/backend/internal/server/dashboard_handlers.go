@@ -1,193 +1,106 @@
 package server
 
 import (
+	"compify-backend/internal/logging"
 	"compify-backend/internal/models"
+	"compify-backend/internal/participant"
 	"compify-backend/internal/templates"
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/a-h/templ"
 )
 
 // handleDashboard renders the main dashboard page
 func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Check authentication
-	sessionToken := s.auth.GetSessionFromRequest(r)
-	if sessionToken == "" {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-
-	user, err := s.auth.GetUserFromSession(sessionToken)
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
+	user := userFromContext(r.Context())
 
 	// Get dashboard data
-	dashboardData, err := s.getDashboardData(user)
+	dashboardData, err := s.getDashboardData(r.Context(), user, s.auth.GetSessionFromRequest(r))
 	if err != nil {
 		http.Error(w, "Failed to load dashboard data", http.StatusInternalServerError)
 		return
 	}
+	dashboardData.IdentityError = r.URL.Query().Get("identity_error")
 
 	w.Header().Set("Content-Type", "text/html")
-	templates.DashboardPage(*dashboardData).Render(r.Context(), w)
+	s.render(w, r, "DashboardPage", templates.DashboardPage(*dashboardData))
 }
 
-// handleProfileEditFirstName renders the first name edit form
-func (s *Server) handleProfileEditFirstName(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html")
-	templates.FirstNameEditForm(user.Profile.FirstName).Render(r.Context(), w)
+// profileField is a profile field editable inline from the dashboard
+type profileField struct {
+	formKey     string                        // Form field holding the new value
+	value       func(*models.Profile) *string // The field within the profile
+	update      func(string) participant.ProfileUpdate
+	editForm    func(value, errorMessage string) templ.Component
+	editName    string
+	display     func(string) templ.Component
+	displayName string
 }
 
-// handleProfileEditLastName renders the last name edit form
-func (s *Server) handleProfileEditLastName(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html")
-	templates.LastNameEditForm(user.Profile.LastName).Render(r.Context(), w)
+// profileFields maps the {field} wildcard of the profile routes to fields
+var profileFields = map[string]profileField{
+	"first-name": {
+		formKey:     "first_name",
+		value:       func(p *models.Profile) *string { return &p.FirstName },
+		update:      func(v string) participant.ProfileUpdate { return participant.ProfileUpdate{FirstName: &v} },
+		editForm:    templates.FirstNameEditForm,
+		editName:    "FirstNameEditForm",
+		display:     templates.FirstNameDisplay,
+		displayName: "FirstNameDisplay",
+	},
+	"last-name": {
+		formKey:     "last_name",
+		value:       func(p *models.Profile) *string { return &p.LastName },
+		update:      func(v string) participant.ProfileUpdate { return participant.ProfileUpdate{LastName: &v} },
+		editForm:    templates.LastNameEditForm,
+		editName:    "LastNameEditForm",
+		display:     templates.LastNameDisplay,
+		displayName: "LastNameDisplay",
+	},
+	"bio": {
+		formKey:     "bio",
+		value:       func(p *models.Profile) *string { return &p.Bio },
+		update:      func(v string) participant.ProfileUpdate { return participant.ProfileUpdate{Bio: &v} },
+		editForm:    templates.BioEditForm,
+		editName:    "BioEditForm",
+		display:     templates.BioDisplay,
+		displayName: "BioDisplay",
+	},
 }
 
-// handleProfileEditBio renders the bio edit form
-func (s *Server) handleProfileEditBio(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// lookupProfileField returns the field named by the {field} wildcard,
+// answering 404 when there is none
+func lookupProfileField(w http.ResponseWriter, r *http.Request) (profileField, bool) {
+	field, ok := profileFields[r.PathValue("field")]
+	if !ok {
+		http.Error(w, "Unknown profile field", http.StatusNotFound)
 	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html")
-	templates.BioEditForm(user.Profile.Bio).Render(r.Context(), w)
+	return field, ok
 }
 
-// handleProfileUpdateFirstName updates the first name
-func (s *Server) handleProfileUpdateFirstName(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Parse form data
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
-		return
-	}
-
-	firstName := strings.TrimSpace(r.FormValue("first_name"))
-	
-	// Update profile
-	user.Profile.FirstName = firstName
-	user.Profile.Sanitize()
-	
-	if err := user.Profile.Validate(); err != nil {
-		// Return error in the form
-		w.Header().Set("Content-Type", "text/html")
-		templates.FirstNameEditForm(firstName).Render(r.Context(), w)
-		return
-	}
-
-	// Save to repository
-	if err := s.repos.Users.UpdateProfile(&user.Profile); err != nil {
-		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
-		return
-	}
-
-	// Return updated display
-	w.Header().Set("Content-Type", "text/html")
-	templates.FirstNameDisplay(firstName).Render(r.Context(), w)
-}
-
-// handleProfileUpdateLastName updates the last name
-func (s *Server) handleProfileUpdateLastName(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Parse form data
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
-		return
-	}
-
-	lastName := strings.TrimSpace(r.FormValue("last_name"))
-	
-	// Update profile
-	user.Profile.LastName = lastName
-	user.Profile.Sanitize()
-	
-	if err := user.Profile.Validate(); err != nil {
-		// Return error in the form
-		w.Header().Set("Content-Type", "text/html")
-		templates.LastNameEditForm(lastName).Render(r.Context(), w)
-		return
-	}
-
-	// Save to repository
-	if err := s.repos.Users.UpdateProfile(&user.Profile); err != nil {
-		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+// handleProfileEdit renders the edit form for a profile field
+func (s *Server) handleProfileEdit(w http.ResponseWriter, r *http.Request) {
+	field, ok := lookupProfileField(w, r)
+	if !ok {
 		return
 	}
+	user := userFromContext(r.Context())
 
-	// Return updated display
 	w.Header().Set("Content-Type", "text/html")
-	templates.LastNameDisplay(lastName).Render(r.Context(), w)
+	s.render(w, r, field.editName, field.editForm(*field.value(&user.Profile), ""))
 }
 
-// handleProfileUpdateBio updates the bio
-func (s *Server) handleProfileUpdateBio(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+// handleProfileUpdate updates a profile field
+func (s *Server) handleProfileUpdate(w http.ResponseWriter, r *http.Request) {
+	field, ok := lookupProfileField(w, r)
+	if !ok {
 		return
 	}
+	user := userFromContext(r.Context())
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
@@ -195,127 +108,52 @@ func (s *Server) handleProfileUpdateBio(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	bio := strings.TrimSpace(r.FormValue("bio"))
+	value := strings.TrimSpace(r.FormValue(field.formKey))
 	
 	// Update profile
-	user.Profile.Bio = bio
-	user.Profile.Sanitize()
-	
-	if err := user.Profile.Validate(); err != nil {
+	if err := s.participants.UpdateProfile(r.Context(), user, field.update(value)); err != nil {
+		problem := s.formProblem(r, err, "Failed to update profile")
+		if problem.Status >= http.StatusInternalServerError {
+			http.Error(w, problem.Detail, problem.Status)
+			return
+		}
 		// Return error in the form
 		w.Header().Set("Content-Type", "text/html")
-		templates.BioEditForm(bio).Render(r.Context(), w)
-		return
-	}
-
-	// Save to repository
-	if err := s.repos.Users.UpdateProfile(&user.Profile); err != nil {
-		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		s.render(w, r, field.editName, field.editForm(value, problem.FieldErrors()[field.formKey]))
 		return
 	}
 
 	// Return updated display
 	w.Header().Set("Content-Type", "text/html")
-	templates.BioDisplay(bio).Render(r.Context(), w)
-}
-
-// handleProfileCancelFirstName cancels first name editing
-func (s *Server) handleProfileCancelFirstName(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html")
-	templates.FirstNameDisplay(user.Profile.FirstName).Render(r.Context(), w)
+	s.render(w, r, field.displayName, field.display(*field.value(&user.Profile)))
 }
 
-// handleProfileCancelLastName cancels last name editing
-func (s *Server) handleProfileCancelLastName(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+// handleProfileCancel cancels editing a profile field
+func (s *Server) handleProfileCancel(w http.ResponseWriter, r *http.Request) {
+	field, ok := lookupProfileField(w, r)
+	if !ok {
 		return
 	}
+	user := userFromContext(r.Context())
 
 	w.Header().Set("Content-Type", "text/html")
-	templates.LastNameDisplay(user.Profile.LastName).Render(r.Context(), w)
-}
-
-// handleProfileCancelBio cancels bio editing
-func (s *Server) handleProfileCancelBio(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html")
-	templates.BioDisplay(user.Profile.Bio).Render(r.Context(), w)
+	s.render(w, r, field.displayName, field.display(*field.value(&user.Profile)))
 }
 
 // handleRegistrationStatus renders the registration status section
 func (s *Server) handleRegistrationStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Get user's registrations
-	registrations, err := s.repos.Registrations.GetByUserID(user.ID)
-	if err != nil {
-		registrations = []*models.Registration{}
-	}
+	user := userFromContext(r.Context())
 
 	// For MVP, show the most recent registration (if any)
-	var registration *models.Registration
-	if len(registrations) > 0 {
-		registration = registrations[0]
-		for _, reg := range registrations {
-			if reg.RegisteredAt.After(registration.RegisteredAt) {
-				registration = reg
-			}
-		}
-	}
+	registration, _ := s.participants.LatestRegistration(r.Context(), user.ID)
 
 	w.Header().Set("Content-Type", "text/html")
-	templates.RegistrationSection(registration).Render(r.Context(), w)
+	s.render(w, r, "RegistrationSection", templates.RegistrationSection(registration))
 }
 
 // handleCreateRegistration creates a new registration for the user
 func (s *Server) handleCreateRegistration(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
+	user := userFromContext(r.Context())
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
@@ -326,66 +164,49 @@ func (s *Server) handleCreateRegistration(w http.ResponseWriter, r *http.Request
 	competitionID := r.FormValue("competition_id")
 	if competitionID == "" {
 		// For MVP, use a default competition ID
-		competitionID = "compify-2024"
+		competitionID = models.DefaultCompetitionID
 	}
 
-	// Check if user already has a registration for this competition
-	existing, err := s.repos.Registrations.GetByUserAndCompetition(user.ID, competitionID)
-	if err == nil && existing != nil {
-		// User already registered, return current status
-		w.Header().Set("Content-Type", "text/html")
-		templates.RegistrationSection(existing).Render(r.Context(), w)
-		return
-	}
-
-	// Create new registration
-	registration := models.NewRegistration(user.ID, competitionID, map[string]interface{}{
-		"registration_type": "individual",
-		"team_name":        "",
-	})
-
-	if err := s.repos.Registrations.Create(registration); err != nil {
-		http.Error(w, "Failed to create registration", http.StatusInternalServerError)
+	// An existing registration is returned along with the error, so the
+	// current status is shown
+	registration, err := s.participants.Register(r.Context(), user.ID, competitionID)
+	if err != nil && !errors.Is(err, models.ErrRegistrationExists) {
+		s.rejectRequest(w, r, err)
 		return
 	}
 
 	// Return updated registration section
 	w.Header().Set("Content-Type", "text/html")
-	templates.RegistrationSection(registration).Render(r.Context(), w)
+	s.render(w, r, "RegistrationSection", templates.RegistrationSection(registration))
 }
 
 // handleAnnouncementsRefresh refreshes the announcements section
 func (s *Server) handleAnnouncementsRefresh(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Authentication not strictly required for announcements, but let's check anyway
-	_, err := s.getAuthenticatedUser(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
 	// Get announcements
-	announcements, err := s.repos.Announcements.GetPublished()
+	announcements, err := s.participants.PublishedAnnouncements(r.Context())
 	if err != nil {
-		announcements = []*models.Announcement{}
-	}
-
-	// Convert to slice of values instead of pointers
-	announcementValues := make([]models.Announcement, len(announcements))
-	for i, a := range announcements {
-		announcementValues[i] = *a
+		announcements = []models.Announcement{}
 	}
 
 	w.Header().Set("Content-Type", "text/html")
-	templates.AnnouncementsSection(announcementValues).Render(r.Context(), w)
+	s.render(w, r, "AnnouncementsSection", templates.AnnouncementsSection(announcements))
 }
 
-// initializeSampleData creates some sample announcements for demonstration
+// initializeSampleData creates the default competition and some sample
+// announcements for demonstration
 func (s *Server) initializeSampleData() {
+	// Create the competition the dashboard registers users for
+	if _, err := s.repos.Competitions.GetByID(models.DefaultCompetitionID); err != nil {
+		s.repos.Competitions.Create(&models.Competition{
+			ID:          models.DefaultCompetitionID,
+			Name:        "Compify 2024",
+			Description: "The annual Compify programming competition.",
+			StartsAt:    time.Date(2024, time.April, 1, 9, 0, 0, 0, time.UTC),
+			EndsAt:      time.Date(2024, time.April, 3, 17, 0, 0, 0, time.UTC),
+			OpenSignup:  true,
+		})
+	}
+
 	// Create sample announcements if none exist
 	existing, _ := s.repos.Announcements.GetPublished()
 	if len(existing) == 0 {
@@ -423,13 +244,19 @@ func (s *Server) getAuthenticatedUser(r *http.Request) (*models.User, error) {
 		return nil, http.ErrNoCookie
 	}
 
-	return s.auth.GetUserFromSession(sessionToken)
+	user, err := s.auth.GetUserFromSession(r.Context(), sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.SetUserID(r.Context(), user.ID)
+	return user, nil
 }
 
 // getDashboardData assembles all data needed for the dashboard
-func (s *Server) getDashboardData(user *models.User) (*models.DashboardData, error) {
+func (s *Server) getDashboardData(ctx context.Context, user *models.User, sessionToken string) (*models.DashboardData, error) {
 	// Get user's registrations
-	registrations, err := s.repos.Registrations.GetByUserID(user.ID)
+	registrations, err := s.repos.WithContext(ctx).Registrations.GetByUserID(user.ID)
 	if err != nil {
 		// Log error but don't fail - just show no registrations
 		registrations = []*models.Registration{}
@@ -448,25 +275,27 @@ func (s *Server) getDashboardData(user *models.User) (*models.DashboardData, err
 	}
 	
 	// Get announcements
-	announcements, err := s.repos.Announcements.GetPublished()
+	announcements, err := s.participants.PublishedAnnouncements(ctx)
 	if err != nil {
 		// Log error but don't fail - just show empty announcements
-		announcements = []*models.Announcement{}
-	}
-
-	// Convert to slice of values instead of pointers
-	announcementValues := make([]models.Announcement, len(announcements))
-	for i, a := range announcements {
-		announcementValues[i] = *a
+		announcements = []models.Announcement{}
 	}
 
 	// Get user stats
 	stats := models.NewUserStats(*user, len(registrations), time.Now())
 
+	// Get active sessions
+	sessionValues, currentSessionID := s.getSessionsData(ctx, user.ID, sessionToken)
+
 	return &models.DashboardData{
-		User:          *user,
-		Registration:  registration,
-		Announcements: announcementValues,
-		Stats:         stats,
+		User:             *user,
+		Registration:     registration,
+		Announcements:    announcements,
+		Stats:            stats,
+		Sessions:         sessionValues,
+		CurrentSessionID: currentSessionID,
+		AccessTokens:     s.getAccessTokensData(ctx, user.ID),
+		Identities:       s.getIdentitiesData(ctx, user.ID),
+		Providers:        s.oidcProviderNames(),
 	}, nil
 }
\ No newline at end of file
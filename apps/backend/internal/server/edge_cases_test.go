@@ -4,9 +4,11 @@ import (
 	"compify-backend/internal/auth"
 	"compify-backend/internal/models"
 	"compify-backend/internal/repository"
+	"compify-backend/internal/templates"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -35,7 +37,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 		body           string
 		contentType    string
 		expectedStatus int
-		expectedError  string
+		expectedCode   string // Problem code, or the code of one of its field errors
 	}{
 		{
 			name:           "Registration with invalid JSON",
@@ -44,7 +46,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "test@example.com", "password": "password123"`, // Missing closing brace
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Invalid request body",
+			expectedCode:   "body_invalid",
 		},
 		{
 			name:           "Registration with missing email",
@@ -52,8 +54,8 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			method:         "POST",
 			body:           `{"username": "testuser", "password": "password123", "confirm_password": "password123"}`,
 			contentType:    "application/json",
-			expectedStatus: http.StatusInternalServerError, // Server returns 500 for validation errors
-			expectedError:  "Registration failed",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "email_required",
 		},
 		{
 			name:           "Registration with empty password",
@@ -61,8 +63,8 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			method:         "POST",
 			body:           `{"email": "test@example.com", "username": "testuser", "password": "", "confirm_password": ""}`,
 			contentType:    "application/json",
-			expectedStatus: http.StatusInternalServerError, // Server returns 500 for validation errors
-			expectedError:  "Registration failed",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "password_required",
 		},
 		{
 			name:           "Registration with short password",
@@ -71,7 +73,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "test@example.com", "username": "testuser", "password": "123", "confirm_password": "123"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Password too short",
+			expectedCode:   "password_too_short",
 		},
 		{
 			name:           "Registration with mismatched passwords",
@@ -80,7 +82,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "test@example.com", "username": "testuser", "password": "password123", "confirm_password": "different123"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Passwords do not match",
+			expectedCode:   "passwords_do_not_match",
 		},
 		{
 			name:           "Login with invalid JSON",
@@ -89,7 +91,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "test@example.com"`, // Missing closing brace
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Invalid request body",
+			expectedCode:   "body_invalid",
 		},
 		{
 			name:           "Login with missing email",
@@ -98,7 +100,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"password": "password123"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Missing required fields",
+			expectedCode:   "email_required",
 		},
 		{
 			name:           "Login with missing password",
@@ -107,7 +109,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "test@example.com"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Missing required fields",
+			expectedCode:   "password_required",
 		},
 		{
 			name:           "Login with non-existent user",
@@ -116,7 +118,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "nonexistent@example.com", "password": "password123"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "Invalid credentials",
+			expectedCode:   "invalid_credentials",
 		},
 		{
 			name:           "Registration with malformed email",
@@ -125,7 +127,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "not-an-email", "username": "testuser", "password": "password123", "confirm_password": "password123"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Invalid email format",
+			expectedCode:   "email_invalid",
 		},
 		{
 			name:           "Registration with SQL injection attempt",
@@ -134,7 +136,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "test'; DROP TABLE users; --@example.com", "username": "testuser", "password": "password123", "confirm_password": "password123"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Invalid email format",
+			expectedCode:   "email_invalid",
 		},
 		{
 			name:           "Registration with XSS attempt in username",
@@ -143,7 +145,7 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 			body:           `{"email": "test@example.com", "username": "<script>alert('xss')</script>", "password": "password123", "confirm_password": "password123"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedError:  "Invalid username format",
+			expectedCode:   "username_invalid",
 		},
 	}
 
@@ -159,17 +161,21 @@ func TestAuthenticationMalformedInputs(t *testing.T) {
 				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
 			}
 
-			var response map[string]interface{}
-			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			if contentType := rec.Header().Get("Content-Type"); contentType != problemContentType {
+				t.Errorf("Expected Content-Type %s, got %s", problemContentType, contentType)
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
 				t.Fatalf("Failed to parse response JSON: %v", err)
 			}
 
-			if errorMsg, ok := response["error"].(string); ok {
-				if !strings.Contains(errorMsg, tt.expectedError) {
-					t.Errorf("Expected error containing '%s', got '%s'", tt.expectedError, errorMsg)
-				}
-			} else {
-				t.Errorf("Expected error field in response, got: %v", response)
+			codes := []string{problem.Code}
+			for _, field := range problem.Errors {
+				codes = append(codes, field.Code)
+			}
+			if !slices.Contains(codes, tt.expectedCode) {
+				t.Errorf("Expected code '%s', got %v", tt.expectedCode, codes)
 			}
 		})
 	}
@@ -318,6 +324,9 @@ func TestHTMXPartialUpdateFailures(t *testing.T) {
 			if tt.sessionToken != "" {
 				req.AddCookie(&http.Cookie{Name: "session_token", Value: tt.sessionToken})
 			}
+			if tt.sessionToken == session.Token {
+				req.Header.Set(templates.CSRFHeader, server.csrfToken(session.ID))
+			}
 			rec := httptest.NewRecorder()
 
 			server.router.ServeHTTP(rec, req)
@@ -2,19 +2,23 @@ package server
 
 import (
 	"compify-backend/internal/auth"
+	"compify-backend/internal/config"
+	"compify-backend/internal/health"
+	"compify-backend/internal/logging"
+	"compify-backend/internal/models"
+	"compify-backend/internal/templates"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"runtime"
-	"strings"
 	"time"
 )
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status    string    `json:"status"`
-	Service   string    `json:"service"`
-	Timestamp time.Time `json:"timestamp"`
+	Status    string            `json:"status"`
+	Service   string            `json:"service"`
+	Timestamp time.Time         `json:"timestamp"`
+	Checks    map[string]string `json:"checks,omitempty"`
 }
 
 // StatusResponse represents the detailed status response
@@ -33,29 +37,39 @@ var startTime = time.Now()
 
 // handleHealth handles the health check endpoint
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	response := HealthResponse{
 		Status:    "ok",
 		Service:   "compify-backend",
 		Timestamp: time.Now(),
 	}
+	statusCode := http.StatusOK
+
+	// Include dependency checks; only critical failures make this unhealthy
+	if s.health != nil {
+		report := s.health.Readiness(r.Context())
+		response.Checks = report.Summary()
+		switch report.Status {
+		case health.StatusDegraded:
+			response.Status = string(health.StatusDegraded)
+		case health.StatusDown:
+			response.Status = string(health.StatusDown)
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+
+	// Report unavailable while draining so load balancers stop routing here
+	if s.Draining() {
+		response.Status = "draining"
+		statusCode = http.StatusServiceUnavailable
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
 }
 
 // handleStatus handles the detailed status endpoint
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	uptime := time.Since(startTime)
 	
 	response := StatusResponse{
@@ -66,9 +80,10 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		Uptime:      uptime.String(),
 		GoVersion:   runtime.Version(),
 		Timestamp:   time.Now(),
+		// Only a fingerprint, to compare instances; use --print-config to
+		// inspect the values
 		Config: map[string]string{
-			"port":      s.config.Port,
-			"log_level": s.config.LogLevel,
+			"fingerprint": s.config.Fingerprint(),
 		},
 	}
 
@@ -95,54 +110,7 @@ func (s *Server) handle404(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusNotFound)
 	
-	staticSiteURL := s.getStaticSiteURL()
-	sandboxURL := s.getSandboxURL()
-	
-	html := fmt.Sprintf(`
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1">
-    <title>404 - Page Not Found | Compify</title>
-    <style>
-        body { 
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
-            margin: 0; padding: 2rem; background: #f9fafb; color: #374151;
-            display: flex; align-items: center; justify-content: center; min-height: 100vh;
-        }
-        .container { 
-            max-width: 500px; text-align: center; background: white; 
-            padding: 3rem; border-radius: 0.5rem; box-shadow: 0 1px 3px rgba(0,0,0,0.1);
-        }
-        h1 { color: #1e40af; margin-bottom: 1rem; font-size: 2rem; }
-        p { margin-bottom: 2rem; line-height: 1.6; }
-        .links { display: flex; gap: 1rem; justify-content: center; flex-wrap: wrap; }
-        .btn { 
-            padding: 0.75rem 1.5rem; border-radius: 0.375rem; text-decoration: none;
-            font-weight: 500; transition: all 0.2s ease; display: inline-block;
-        }
-        .btn-primary { background: #1e40af; color: white; }
-        .btn-primary:hover { background: #1d4ed8; }
-        .btn-outline { color: #1e40af; border: 1px solid #1e40af; background: transparent; }
-        .btn-outline:hover { background: #1e40af; color: white; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>404 - Page Not Found</h1>
-        <p>The page you're looking for doesn't exist on the backend server. You might be looking for one of these:</p>
-        <div class="links">
-            <a href="%s" class="btn btn-primary">Go to Home</a>
-            <a href="/login" class="btn btn-outline">Login</a>
-            <a href="/dashboard" class="btn btn-outline">Dashboard</a>
-            <a href="%s" class="btn btn-outline">Play Games</a>
-        </div>
-    </div>
-</body>
-</html>`, staticSiteURL, sandboxURL)
-	
-	fmt.Fprint(w, html)
+	s.render(w, r, "NotFoundPage", templates.NotFoundPage(s.getStaticSiteURL(), s.getSandboxURL()))
 }
 
 // handleStaticRedirect handles redirects to static site pages
@@ -166,30 +134,20 @@ func (s *Server) handleSandboxRedirect(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, sandboxURL, http.StatusTemporaryRedirect)
 }
 
-// getStaticSiteURL returns the static site base URL based on environment
+// getStaticSiteURL returns the static site base URL, defaulting by environment
 func (s *Server) getStaticSiteURL() string {
-	if s.config.Environment == "production" {
-		// In production, static site is served from CDN
-		return getEnv("STATIC_SITE_URL", "https://compify.com")
+	if s.config.StaticSiteURL != "" {
+		return s.config.StaticSiteURL
 	}
-	// In development, static site runs on different port
-	return getEnv("STATIC_SITE_URL", "http://localhost:4321")
+	return config.DefaultStaticSiteURL(s.config.Environment)
 }
 
-// getSandboxURL returns the sandbox base URL based on environment
+// getSandboxURL returns the sandbox base URL, defaulting by environment
 func (s *Server) getSandboxURL() string {
-	if s.config.Environment == "production" {
-		// In production, sandbox is served from CDN
-		return getEnv("SANDBOX_URL", "https://sandbox.compify.com")
+	if s.config.SandboxURL != "" {
+		return s.config.SandboxURL
 	}
-	// In development, sandbox runs on different port
-	return getEnv("SANDBOX_URL", "http://localhost:5173")
-}
-
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+	return config.DefaultSandboxURL(s.config.Environment)
 }
 
 // SuccessResponse represents a success response
@@ -201,15 +159,10 @@ type SuccessResponse struct {
 
 // handleRegister handles user registration
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		s.writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", "")
-		return
-	}
-
 	// Parse request body
 	var req auth.RegistrationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", "")
+		s.writeError(w, r, errInvalidBody)
 		return
 	}
 
@@ -218,34 +171,14 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	userAgent := r.UserAgent()
 
 	// Register user
-	user, session, err := s.auth.Register(&req, ipAddress, userAgent)
+	user, session, err := s.auth.Register(r.Context(), &req, ipAddress, userAgent)
 	if err != nil {
-		// Handle specific errors
-		switch err {
-		case auth.ErrUserAlreadyExists:
-			s.writeErrorResponse(w, http.StatusConflict, "User already exists", "")
-		case auth.ErrPasswordTooShort:
-			s.writeErrorResponse(w, http.StatusBadRequest, "Password too short", "Password must be at least 8 characters long")
-		case auth.ErrPasswordsDoNotMatch:
-			s.writeErrorResponse(w, http.StatusBadRequest, "Passwords do not match", "")
-		default:
-			if strings.Contains(err.Error(), "email already exists") {
-				s.writeErrorResponse(w, http.StatusConflict, "Email already exists", "")
-			} else if strings.Contains(err.Error(), "username already exists") {
-				s.writeErrorResponse(w, http.StatusConflict, "Username already exists", "")
-			} else if strings.Contains(err.Error(), "invalid email") {
-				s.writeErrorResponse(w, http.StatusBadRequest, "Invalid email format", "")
-			} else if strings.Contains(err.Error(), "invalid username") {
-				s.writeErrorResponse(w, http.StatusBadRequest, "Invalid username format", "Username must be 3-30 characters and contain only letters, numbers, underscores, and hyphens")
-			} else {
-				s.writeErrorResponse(w, http.StatusInternalServerError, "Registration failed", "")
-			}
-		}
+		s.writeError(w, r, err)
 		return
 	}
 
-	// Set session cookie
-	s.setSessionCookie(w, session.Token)
+	// Set session cookie, replacing any session the request carried
+	s.startSession(w, r, session)
 
 	// Return success response
 	response := SuccessResponse{
@@ -268,15 +201,10 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 // handleLogin handles user login
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		s.writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", "")
-		return
-	}
-
 	// Parse request body
 	var req auth.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", "")
+		s.writeError(w, r, errInvalidBody)
 		return
 	}
 
@@ -285,24 +213,14 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	userAgent := r.UserAgent()
 
 	// Login user
-	user, session, err := s.auth.Login(&req, ipAddress, userAgent)
+	user, session, err := s.auth.Login(r.Context(), &req, ipAddress, userAgent)
 	if err != nil {
-		// Handle specific errors
-		switch err {
-		case auth.ErrInvalidCredentials:
-			s.writeErrorResponse(w, http.StatusUnauthorized, "Invalid credentials", "")
-		default:
-			if strings.Contains(err.Error(), "email is required") || strings.Contains(err.Error(), "password is required") {
-				s.writeErrorResponse(w, http.StatusBadRequest, "Missing required fields", "")
-			} else {
-				s.writeErrorResponse(w, http.StatusInternalServerError, "Login failed", "")
-			}
-		}
+		s.writeError(w, r, err)
 		return
 	}
 
-	// Set session cookie
-	s.setSessionCookie(w, session.Token)
+	// Set session cookie, replacing any session the request carried
+	s.startSession(w, r, session)
 
 	// Return success response
 	response := SuccessResponse{
@@ -325,16 +243,11 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 // handleLogout handles user logout
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		s.writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", "")
-		return
-	}
-
 	// Get session token
 	sessionToken := s.auth.GetSessionFromRequest(r)
 
 	// Logout user
-	if err := s.auth.Logout(sessionToken); err != nil {
+	if err := s.auth.Logout(r.Context(), sessionToken); err != nil {
 		// Log error but don't fail the request
 		// Logout should be idempotent
 	}
@@ -355,25 +268,26 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
-// writeErrorResponse writes a JSON error response
-func (s *Server) writeErrorResponse(w http.ResponseWriter, statusCode int, error, message string) {
-	response := ErrorResponse{
-		Error:   error,
-		Message: message,
+// startSession sets the cookie for a freshly issued session and revokes any
+// session the request was already carrying, so a token planted before login
+// can never be used afterwards
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, session *models.Session) {
+	if previous := s.auth.GetSessionFromRequest(r); previous != "" && previous != session.Token {
+		s.auth.Logout(r.Context(), previous)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(response)
+	logging.SetUserID(r.Context(), session.UserID)
+	s.setSessionCookie(w, session)
 }
 
-// setSessionCookie sets a secure session cookie
-func (s *Server) setSessionCookie(w http.ResponseWriter, token string) {
+// setSessionCookie sets a secure session cookie that lives until the session's
+// absolute expiry; the idle timeout is enforced server-side
+func (s *Server) setSessionCookie(w http.ResponseWriter, session *models.Session) {
 	cookie := &http.Cookie{
 		Name:     "session_token",
-		Value:    token,
+		Value:    session.Token,
 		Path:     "/",
-		MaxAge:   7 * 24 * 60 * 60, // 7 days
+		MaxAge:   int(time.Until(session.AbsoluteExpiresAt).Seconds()),
 		HttpOnly: true,
 		Secure:   s.config.Environment == "production",
 		SameSite: http.SameSiteLaxMode,
@@ -393,27 +307,4 @@ func (s *Server) clearSessionCookie(w http.ResponseWriter) {
 		SameSite: http.SameSiteLaxMode,
 	}
 	http.SetCookie(w, cookie)
-}
-
-// getClientIP extracts the client IP address from the request
-func (s *Server) getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (for proxies)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the list
-		if ips := strings.Split(xff, ","); len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-
-	// Fall back to RemoteAddr
-	if ip := strings.Split(r.RemoteAddr, ":"); len(ip) > 0 {
-		return ip[0]
-	}
-
-	return "unknown"
 }
\ No newline at end of file
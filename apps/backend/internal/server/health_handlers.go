@@ -0,0 +1,89 @@
+package server
+
+import (
+	"compify-backend/internal/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ProbeResponse represents a liveness or readiness probe response
+type ProbeResponse struct {
+	health.Report
+	Draining bool `json:"draining,omitempty"`
+}
+
+// registerHealthChecks registers probes for the server's dependencies
+func (s *Server) registerHealthChecks() {
+	checks := []health.Check{
+		{
+			Name:     "store",
+			Timeout:  time.Second,
+			Critical: true,
+			Liveness: true,
+			Run:      s.repos.Ping,
+		},
+		{
+			Name:    "scheduler",
+			Timeout: time.Second,
+			Run: func(ctx context.Context) error {
+				if s.jobs == nil || s.jobs.Running() || s.Draining() {
+					return nil
+				}
+				return errors.New("scheduler is not running")
+			},
+		},
+	}
+	if s.mailer != nil {
+		checks = append(checks, health.Check{
+			Name:    "mail",
+			Timeout: 5 * time.Second,
+			Run:     s.mailer.Ping,
+		})
+	}
+
+	for _, check := range checks {
+		if err := s.health.Register(check); err != nil {
+			slog.Error("Failed to register health check", "check", check.Name, "error", err)
+		}
+	}
+}
+
+// handleLivez reports whether the process is alive and should not be restarted
+func (s *Server) handleLivez(w http.ResponseWriter, r *http.Request) {
+	report := health.Report{Status: health.StatusOK, Timestamp: time.Now()}
+	if s.health != nil {
+		report = s.health.Liveness(r.Context())
+	}
+
+	// Draining is expected during shutdown and does not affect liveness
+	s.writeProbe(w, ProbeResponse{Report: report}, report.Status != health.StatusDown)
+}
+
+// handleReadyz reports whether the server should receive traffic. Degraded
+// dependencies keep the server ready; critical failures and draining do not.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := health.Report{Status: health.StatusOK, Timestamp: time.Now()}
+	if s.health != nil {
+		report = s.health.Readiness(r.Context())
+	}
+
+	response := ProbeResponse{Report: report, Draining: s.Draining()}
+	s.writeProbe(w, response, report.Status != health.StatusDown && !response.Draining)
+}
+
+// writeProbe writes a probe response with 200 when healthy and 503 otherwise
+func (s *Server) writeProbe(w http.ResponseWriter, response ProbeResponse, healthy bool) {
+	statusCode := http.StatusOK
+	if !healthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
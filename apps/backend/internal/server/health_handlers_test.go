@@ -0,0 +1,93 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/health"
+	"compify-backend/internal/jobs"
+	"compify-backend/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test the liveness, readiness and legacy health endpoints with dependency
+// checks
+func TestHealthProbes(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos:  repos,
+		auth:   auth.NewService(repos),
+		jobs:   jobs.NewScheduler(),
+		health: health.NewRegistry(),
+	}
+	server.registerHealthChecks()
+	server.setupRoutes()
+
+	get := func(path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s returned invalid JSON: %v", path, err)
+		}
+		return rec, body
+	}
+
+	// The scheduler has not been started, which degrades but does not fail
+	rec, body := get("/readyz")
+	if rec.Code != http.StatusOK || body["status"] != "degraded" {
+		t.Errorf("Expected degraded readiness with status 200, got %d %v", rec.Code, body["status"])
+	}
+	rec, body = get("/health")
+	checks, _ := body["checks"].(map[string]interface{})
+	if rec.Code != http.StatusOK || checks["store"] != "ok" || checks["scheduler"] != "scheduler is not running" {
+		t.Errorf("Expected legacy checks summary, got %d %v", rec.Code, body)
+	}
+
+	server.jobs.Start(context.Background())
+	defer server.jobs.Stop(context.Background())
+
+	rec, body = get("/readyz")
+	if rec.Code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("Expected ready, got %d %v", rec.Code, body)
+	}
+
+	// A failing critical dependency makes the server unready but still alive
+	server.health.Register(health.Check{
+		Name:     "database",
+		Critical: true,
+		Run:      func(context.Context) error { return errors.New("connection refused") },
+	})
+	rec, body = get("/readyz")
+	if rec.Code != http.StatusServiceUnavailable || body["status"] != "down" {
+		t.Errorf("Expected unready with status 503, got %d %v", rec.Code, body["status"])
+	}
+	rec, body = get("/health")
+	if rec.Code != http.StatusServiceUnavailable || body["status"] != "down" {
+		t.Errorf("Expected /health to report down, got %d %v", rec.Code, body["status"])
+	}
+	rec, body = get("/livez")
+	if rec.Code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("Expected liveness to ignore readiness-only checks, got %d %v", rec.Code, body)
+	}
+
+	// Draining withdraws readiness without failing liveness
+	server.draining.Store(true)
+	if rec, body = get("/readyz"); rec.Code != http.StatusServiceUnavailable || body["draining"] != true {
+		t.Errorf("Expected draining readiness failure, got %d %v", rec.Code, body)
+	}
+	if rec, _ = get("/livez"); rec.Code != http.StatusOK {
+		t.Errorf("Expected liveness to pass while draining, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"context"
+	"os"
+)
+
+// ImportUsers imports accounts from a JSON Lines file, one auth.ImportedUser
+// per line, skipping email addresses that are already registered. Imported
+// bcrypt hashes are upgraded to Argon2id when the users first log in.
+func (s *Server) ImportUsers(ctx context.Context, path string) (imported, skipped int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	return s.auth.ImportUsers(ctx, f)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Run listens on the configured port and serves until ctx is cancelled, then
+// shuts down gracefully
+func (s *Server) Run(ctx context.Context) error {
+	// The port already reflects host overrides such as ALWAYSDATA_HTTP_PORT
+	addr := ":" + s.config.Port
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	slog.Info("Starting Compify backend", "addr", addr, "environment", s.config.Environment)
+	return s.Serve(ctx, ln)
+}
+
+// Serve starts background jobs and serves HTTP on ln until ctx is cancelled
+// or the listener fails. On return every subsystem has been shut down.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	s.httpServer = &http.Server{
+		Handler:      s.Handler(),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start background jobs
+	if s.jobs != nil {
+		s.jobs.Start(context.Background())
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- s.httpServer.Serve(ln)
+	}()
+
+	// Serve metrics on their own address when configured
+	if s.metricsServer = s.newMetricsServer(); s.metricsServer != nil {
+		slog.Info("Serving metrics", "addr", s.metricsServer.Addr)
+		go func() {
+			serveErr <- s.metricsServer.ListenAndServe()
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	case <-ctx.Done():
+		slog.Info("Shutdown requested", "cause", context.Cause(ctx))
+	}
+
+	timeout := s.config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return errors.Join(err, s.Shutdown(shutdownCtx))
+}
+
+// Handler returns the server's routes behind the global middleware, as
+// served by Serve. Tests can serve it with httptest.
+func (s *Server) Handler() http.Handler {
+	return s.applyMiddleware(s.router)
+}
+
+// Shutdown stops the server in order: readiness is withdrawn, in-flight
+// requests drain, background jobs stop, pending spans are exported and
+// finally the stores are closed. Every step runs even if an earlier one fails.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if !s.draining.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	// Give load balancers time to observe the failing health check before
+	// we stop accepting connections
+	if s.config.ShutdownDelay > 0 {
+		slog.Info("Draining, waiting for load balancers", "delay", s.config.ShutdownDelay)
+		select {
+		case <-time.After(s.config.ShutdownDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	var errs []error
+
+	if s.httpServer != nil {
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("http server: %w", err))
+			s.httpServer.Close()
+		}
+	}
+
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("metrics server: %w", err))
+		}
+	}
+
+	if s.jobs != nil {
+		if err := s.jobs.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("background jobs: %w", err))
+		}
+	}
+
+	// Export spans recorded while draining before the process exits
+	if s.tracer != nil {
+		if err := s.tracer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("tracing: %w", err))
+		}
+	}
+
+	// Flush error reports queued by the reporter
+	if s.reporter != nil {
+		if err := s.reporter.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error reporting: %w", err))
+		}
+	}
+
+	if s.repos != nil {
+		if err := s.repos.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("repositories: %w", err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		slog.Error("Shutdown completed with errors", "error", err)
+		return err
+	}
+
+	slog.Info("Shutdown complete")
+	return nil
+}
+
+// Draining reports whether the server has begun shutting down
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
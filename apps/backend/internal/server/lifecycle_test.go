@@ -0,0 +1,112 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/jobs"
+	"compify-backend/internal/repository"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Test that shutdown withdraws readiness, drains in-flight requests and stops
+// background jobs before Serve returns
+func TestGracefulShutdown(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:            "8080",
+			Environment:     "test",
+			LogLevel:        "info",
+			ShutdownDelay:   100 * time.Millisecond,
+			ShutdownTimeout: 5 * time.Second,
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+		jobs:  jobs.NewScheduler(),
+	}
+	server.setupRoutes()
+
+	jobStopped := make(chan struct{})
+	server.jobs.Register(jobs.Job{
+		Name:     "blocking",
+		Schedule: jobs.Every(time.Hour),
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			close(jobStopped)
+			return nil
+		},
+	})
+
+	slowStarted := make(chan struct{})
+	server.router.HandleFunc("/test/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(slowStarted)
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	base := "http://" + ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, ln) }()
+
+	for !server.jobs.Running() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := server.jobs.RunNow("blocking"); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+
+	slowResult := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(base + "/test/slow")
+		if err != nil {
+			slowResult <- "error: " + err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		slowResult <- string(body)
+	}()
+	<-slowStarted
+
+	cancel()
+
+	// During the shutdown delay the health check reports unavailable
+	time.Sleep(20 * time.Millisecond)
+	resp, err := http.Get(base + "/health")
+	if err != nil {
+		t.Fatalf("Health check failed during drain: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", resp.StatusCode)
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Serve did not return after shutdown")
+	}
+
+	if got := <-slowResult; got != "done" {
+		t.Errorf("In-flight request should complete, got %q", got)
+	}
+	select {
+	case <-jobStopped:
+	default:
+		t.Error("Expected the job to be stopped before Serve returned")
+	}
+}
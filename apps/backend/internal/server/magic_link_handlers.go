@@ -0,0 +1,152 @@
+package server
+
+import (
+	"compify-backend/internal/mail"
+	"compify-backend/internal/models"
+	"compify-backend/internal/templates"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// loginLinkBrowserCookie holds the random value identifying the browser
+	// that requested a sign-in link
+	loginLinkBrowserCookie = "login_link_browser"
+
+	// loginLinkPath is where sign-in links are requested and followed
+	loginLinkPath = "/auth/magic-link"
+)
+
+// setupMail creates the mailer for sign-in links. Without an SMTP server
+// links are logged outside production and disabled in production.
+func (s *Server) setupMail() {
+	switch {
+	case s.config.SMTPURL != "":
+		mailer, err := mail.NewSMTPMailer(s.config.SMTPURL, s.config.MailFrom)
+		if err != nil {
+			slog.Error("Email sign-in disabled", "error", err)
+			return
+		}
+		s.mailer = mailer
+		slog.Info("Email sign-in enabled", "from", s.config.MailFrom)
+	case s.config.Environment != "production":
+		s.mailer = mail.LogMailer{Logger: slog.Default()}
+		slog.Info("Email sign-in enabled, logging emails instead of sending them")
+	default:
+		slog.Info("Email sign-in disabled, no SMTP server configured")
+	}
+}
+
+// handleMagicLinkRequest handles HTMX requests for an emailed sign-in link.
+// The answer is the same whether or not the address has an account.
+func (s *Server) handleMagicLinkRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		s.render(w, r, "MagicLinkForm", templates.MagicLinkForm("", "Invalid form data", nil))
+		return
+	}
+	competitionID := r.FormValue("competition_id")
+	if s.mailer == nil {
+		s.render(w, r, "MagicLinkForm", templates.MagicLinkForm(competitionID, "Sign-in links are not available. Please sign in with your password.", nil))
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	link, err := s.auth.RequestLoginLink(r.Context(), email, competitionID, s.loginLinkBrowser(w, r))
+	if err != nil {
+		problem := s.formProblem(r, err, "Requesting a sign-in link failed. Please try again.")
+		s.render(w, r, "MagicLinkForm", templates.MagicLinkForm(competitionID, problem.Detail, problem.FieldErrors()))
+		return
+	}
+
+	if link != nil {
+		if err := s.mailer.Send(r.Context(), loginLinkMessage(link, s.publicURL())); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to send sign-in link", "error", err)
+			s.render(w, r, "MagicLinkForm", templates.MagicLinkForm(competitionID, "The sign-in link could not be sent. Please try again later.", nil))
+			return
+		}
+	}
+
+	s.render(w, r, "MagicLinkSent", templates.MagicLinkSent(email))
+}
+
+// handleMagicLinkLogin signs in with an emailed link
+func (s *Server) handleMagicLinkLogin(w http.ResponseWriter, r *http.Request) {
+	var browserToken string
+	if cookie, err := r.Cookie(loginLinkBrowserCookie); err == nil {
+		browserToken = cookie.Value
+	}
+
+	_, session, err := s.auth.LoginWithLink(r.Context(), r.URL.Query().Get("token"), browserToken, s.getClientIP(r), r.UserAgent())
+	if err != nil {
+		problem := s.formProblem(r, err, "Sign-in failed. Please try again.")
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(problem.Detail), http.StatusSeeOther)
+		return
+	}
+
+	// The browser value is spent; a later request gets a fresh one
+	s.setLoginLinkBrowserCookie(w, "", -1)
+
+	// Set session cookie, replacing any session the request carried
+	s.startSession(w, r, session)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// loginLinkBrowser returns the value identifying this browser to sign-in
+// links, creating it on the first request. Requests for several addresses
+// from one browser share it.
+func (s *Server) loginLinkBrowser(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(loginLinkBrowserCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	token, err := models.GenerateSessionToken()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to generate sign-in link browser value", "error", err)
+		return ""
+	}
+	s.setLoginLinkBrowserCookie(w, token, int(models.LoginLinkLifetime.Seconds()))
+	return token
+}
+
+// setLoginLinkBrowserCookie sets the browser cookie, or clears it when
+// maxAge is negative
+func (s *Server) setLoginLinkBrowserCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginLinkBrowserCookie,
+		Value:    value,
+		Path:     loginLinkPath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   s.config.Environment == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// loginLinkMessage composes the email carrying a sign-in link
+func loginLinkMessage(link *models.LoginLink, baseURL string) mail.Message {
+	return mail.Message{
+		To:      link.Email,
+		Subject: "Your Compify sign-in link",
+		Text: fmt.Sprintf("Follow this link to sign in to Compify:\n\n%s%s?token=%s\n\n"+
+			"The link works once, for %d minutes, in the browser you requested it from.\n"+
+			"If you did not request it, you can ignore this email.\n",
+			baseURL, loginLinkPath, url.QueryEscape(link.Token), int(models.LoginLinkLifetime.Minutes())),
+	}
+}
+
+// publicURL returns the base URL users reach the server at, without a
+// trailing slash. It is never taken from the request, whose Host header the
+// client controls; without PUBLIC_URL, which configuration requires along
+// with SMTP, emails are only logged and links point at this machine.
+func (s *Server) publicURL() string {
+	if base := strings.TrimSuffix(s.config.PublicURL, "/"); base != "" {
+		return base
+	}
+	return "http://localhost:" + s.config.Port
+}
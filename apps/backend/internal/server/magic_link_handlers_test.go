@@ -0,0 +1,224 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/mail"
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test requesting sign-in links by email and signing in with them
+func TestMagicLinks(t *testing.T) {
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	outbox := &mail.Outbox{}
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+			PublicURL:   "https://compify.example/",
+		},
+		repos:  repos,
+		auth:   authService,
+		mailer: outbox,
+	}
+	server.setupRoutes()
+	createTestUser(t, repos)
+
+	now := time.Now()
+	repos.Competitions.Create(&models.Competition{ID: "open", Name: "Open", OpenSignup: true})
+	repos.Competitions.Create(&models.Competition{ID: "invite-only", Name: "Invite only"})
+	repos.Competitions.Create(&models.Competition{ID: "closed", Name: "Closed", OpenSignup: true, RegistrationClosesAt: now.Add(-time.Hour)})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+	browserCookie := func(rec *httptest.ResponseRecorder) *http.Cookie {
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == loginLinkBrowserCookie {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// request asks for a link from the browser holding browser, if any, and
+	// returns the response
+	request := func(email, competitionID string, browser *http.Cookie) *httptest.ResponseRecorder {
+		form := url.Values{"email": {email}, "competition_id": {competitionID}}
+		req := httptest.NewRequest("POST", loginLinkPath, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		if browser != nil {
+			req.AddCookie(browser)
+		}
+		return serve(req)
+	}
+	// follow opens a link in the browser holding browser, if any
+	follow := func(link string, browser *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", strings.TrimPrefix(link, "https://compify.example"), nil)
+		if browser != nil {
+			req.AddCookie(browser)
+		}
+		return serve(req)
+	}
+	linkPattern := regexp.MustCompile(`https://compify\.example/auth/magic-link\?token=\S+`)
+	sent := 0
+	// lastLink returns the link of the newest email, checking that exactly
+	// one was sent since the last call, to the given address
+	lastLink := func(to string) string {
+		t.Helper()
+		messages := outbox.Messages()
+		if len(messages) != sent+1 {
+			t.Fatalf("Expected one new email, got %d", len(messages)-sent)
+		}
+		sent = len(messages)
+		msg := messages[len(messages)-1]
+		link := linkPattern.FindString(msg.Text)
+		if msg.To != to || link == "" {
+			t.Fatalf("Expected a sign-in link for %s, got %+v", to, msg)
+		}
+		return link
+	}
+	expectNoEmail := func() {
+		t.Helper()
+		if messages := outbox.Messages(); len(messages) != sent {
+			t.Fatalf("Expected no email, got %+v", messages[sent:])
+		}
+	}
+	expectSignedIn := func(rec *httptest.ResponseRecorder, email string) {
+		t.Helper()
+		if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/dashboard" {
+			t.Fatalf("Expected a redirect to the dashboard, got %d %s", rec.Code, rec.Header().Get("Location"))
+		}
+		var token string
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == "session_token" {
+				token = c.Value
+			}
+		}
+		user, err := authService.GetUserFromSession(t.Context(), token)
+		if err != nil || user.Email != email {
+			t.Fatalf("Expected a session for %s, got %v %v", email, user, err)
+		}
+	}
+	expectRefused := func(rec *httptest.ResponseRecorder, message string) {
+		t.Helper()
+		location, _ := url.Parse(rec.Header().Get("Location"))
+		if rec.Code != http.StatusSeeOther || location.Path != "/login" || location.Query().Get("error") != message {
+			t.Fatalf("Expected a redirect to the login page with %q, got %d %s", message, rec.Code, location)
+		}
+	}
+
+	t.Run("sign in", func(t *testing.T) {
+		rec := request("Test@Example.com", "", nil)
+		browser := browserCookie(rec)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "a link is on its way") {
+			t.Fatalf("Expected the link to be sent, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if browser == nil || browser.Path != loginLinkPath || !browser.HttpOnly || browser.SameSite != http.SameSiteLaxMode {
+			t.Fatalf("Expected a HttpOnly, SameSite=Lax browser cookie scoped to %s, got %+v", loginLinkPath, browser)
+		}
+		link := lastLink("test@example.com")
+
+		// Another browser cannot use the link, and does not spoil it
+		expectRefused(follow(link, nil), models.ErrLoginLinkOtherBrowser.Message)
+		expectRefused(follow(link, &http.Cookie{Name: loginLinkBrowserCookie, Value: "other"}), models.ErrLoginLinkOtherBrowser.Message)
+
+		expectSignedIn(follow(link, browser), "test@example.com")
+
+		// Links are single-use
+		expectRefused(follow(link, browser), models.ErrLoginLinkInvalid.Message)
+	})
+
+	t.Run("forged host", func(t *testing.T) {
+		// Links point at PUBLIC_URL whatever Host the request claims
+		form := url.Values{"email": {"test@example.com"}}
+		req := httptest.NewRequest("POST", loginLinkPath, strings.NewReader(form.Encode()))
+		req.Host = "evil.example"
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		serve(req)
+
+		lastLink("test@example.com")
+		if msg := outbox.Messages()[sent-1]; strings.Contains(msg.Text, "evil.example") {
+			t.Errorf("Expected the email not to mention the forged host, got %s", msg.Text)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		link, _ := models.NewLoginLink("test@example.com", "browser", "")
+		link.ExpiresAt = now.Add(-time.Minute)
+		repos.LoginLinks.Create(link)
+
+		expectRefused(follow(loginLinkPath+"?token="+link.Token, &http.Cookie{Name: loginLinkBrowserCookie, Value: "browser"}), models.ErrLoginLinkInvalid.Message)
+	})
+
+	t.Run("unknown addresses", func(t *testing.T) {
+		// The answer does not reveal whether an address has an account
+		for _, competitionID := range []string{"", "invite-only", "closed", "missing"} {
+			email := "nobody-" + competitionID + "@example.com"
+			rec := request(email, competitionID, nil)
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "a link is on its way") {
+				t.Fatalf("Expected the same answer as for an account, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if _, err := repos.Users.GetByEmail(email); err == nil {
+				t.Errorf("Expected no account to be created for competition %q", competitionID)
+			}
+		}
+		expectNoEmail()
+	})
+
+	t.Run("signup", func(t *testing.T) {
+		rec := request("grace@example.com", "open", nil)
+		link := lastLink("grace@example.com")
+		expectSignedIn(follow(link, browserCookie(rec)), "grace@example.com")
+	})
+
+	t.Run("throttled", func(t *testing.T) {
+		var browser *http.Cookie
+		for i := 0; i < auth.DefaultLoginLinkPolicy.MaxRequests; i++ {
+			rec := request("ada@example.com", "open", browser)
+			if c := browserCookie(rec); c != nil {
+				browser = c
+			}
+			lastLink("ada@example.com")
+		}
+
+		rec := request("ADA@example.com", "open", browser)
+		if !strings.Contains(rec.Body.String(), models.ErrLoginLinkThrottled.Message) {
+			t.Fatalf("Expected the request to be throttled, got %d: %s", rec.Code, rec.Body.String())
+		}
+		expectNoEmail()
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		rec := request("not an address", "", nil)
+		if !strings.Contains(rec.Body.String(), models.ErrInvalidEmail.Message) {
+			t.Fatalf("Expected the email to be refused, got %d: %s", rec.Code, rec.Body.String())
+		}
+		expectNoEmail()
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		server.mailer = nil
+		defer func() { server.mailer = outbox }()
+
+		rec := request("test@example.com", "", nil)
+		if !strings.Contains(rec.Body.String(), "Sign-in links are not available") {
+			t.Fatalf("Expected sign-in links to be unavailable, got %d: %s", rec.Code, rec.Body.String())
+		}
+		expectNoEmail()
+	})
+}
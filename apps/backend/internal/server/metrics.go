@@ -0,0 +1,153 @@
+package server
+
+import (
+	"compify-backend/internal/metrics"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpMetrics records request counts, latencies and concurrency
+type httpMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.GaugeVec
+}
+
+// registerMetrics creates the metrics registry with HTTP, auth, job and
+// business metrics
+func (s *Server) registerMetrics() {
+	s.metrics = metrics.NewRegistry()
+	s.httpMetrics = &httpMetrics{
+		requests: metrics.NewCounterVec("compify_http_requests_total", "HTTP requests by method, route pattern and status code.", "method", "route", "status"),
+		duration: metrics.NewHistogramVec("compify_http_request_duration_seconds", "HTTP request latency by method and route pattern.", metrics.DefaultBuckets, "method", "route"),
+		inFlight: metrics.NewGaugeVec("compify_http_requests_in_flight", "HTTP requests currently being served."),
+	}
+	s.metrics.MustRegister(s.httpMetrics.requests, s.httpMetrics.duration, s.httpMetrics.inFlight)
+	s.metrics.MustRegister(s.auth.Metrics().Collectors()...)
+
+	// Business gauges are read from the repositories at scrape time
+	s.metrics.MustRegister(
+		metrics.NewGaugeFunc("compify_registrations", "Registrations by competition and status.", []string{"competition", "status"},
+			func(emit func(float64, ...string)) {
+				counts, err := s.repos.Registrations.CountByCompetitionAndStatus()
+				if err != nil {
+					slog.Error("Failed to count registrations for metrics", "error", err)
+					return
+				}
+				for _, c := range counts {
+					emit(float64(c.Count), c.CompetitionID, string(c.Status))
+				}
+			}),
+		metrics.NewGaugeFunc("compify_active_sessions", "Sessions that have not expired.", nil,
+			func(emit func(float64, ...string)) {
+				if count, err := s.repos.Sessions.CountActive(); err == nil {
+					emit(float64(count))
+				}
+			}),
+		metrics.NewGaugeFunc("compify_published_announcements", "Published announcements.", nil,
+			func(emit func(float64, ...string)) {
+				if announcements, err := s.repos.Announcements.GetPublished(); err == nil {
+					emit(float64(len(announcements)))
+				}
+			}),
+	)
+
+	if s.jobs != nil {
+		s.metrics.MustRegister(
+			metrics.NewCounterFunc("compify_job_runs_total", "Completed background job runs.", []string{"job"},
+				func(emit func(float64, ...string)) {
+					for _, status := range s.jobs.Status() {
+						emit(float64(status.Runs), status.Name)
+					}
+				}),
+			metrics.NewCounterFunc("compify_job_failures_total", "Failed background job runs, including panics.", []string{"job"},
+				func(emit func(float64, ...string)) {
+					for _, status := range s.jobs.Status() {
+						emit(float64(status.Failures), status.Name)
+					}
+				}),
+			metrics.NewCounterFunc("compify_job_skipped_total", "Job runs skipped because the previous run was still in progress.", []string{"job"},
+				func(emit func(float64, ...string)) {
+					for _, status := range s.jobs.Status() {
+						emit(float64(status.Skipped), status.Name)
+					}
+				}),
+		)
+	}
+}
+
+// metricsMiddleware records request metrics labelled by the matched route
+// pattern, which keeps label cardinality bounded
+func (s *Server) metricsMiddleware(next http.Handler) http.Handler {
+	if s.httpMetrics == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		s.httpMetrics.inFlight.Inc()
+		defer s.httpMetrics.inFlight.Dec()
+
+		route := s.routeName(r)
+
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapped, r)
+
+		s.httpMetrics.requests.Inc(r.Method, route, strconv.Itoa(wrapped.statusCode))
+		s.httpMetrics.duration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
+
+// handleMetrics serves metrics in the Prometheus text format on the main
+// router. When a token is configured scrapers must present it as a bearer
+// token; without one the endpoint is only served outside production.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.serveMetrics(w, r, s.config.Environment != "production")
+}
+
+// serveMetrics serves the registry, allowing requests without a token only
+// when allowAnonymous is set
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request, allowAnonymous bool) {
+	if !s.metricsAuthorized(r, allowAnonymous) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.metrics.ServeHTTP(w, r)
+}
+
+// metricsAuthorized checks the scrape request against the metrics token
+func (s *Server) metricsAuthorized(r *http.Request, allowAnonymous bool) bool {
+	token := s.config.MetricsToken
+	if token == "" {
+		return allowAnonymous
+	}
+
+	presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// newMetricsServer returns a server for the dedicated metrics address, or nil
+// when metrics are served on the main router. The address is expected to be
+// reachable only from the internal network, so a token is optional.
+func (s *Server) newMetricsServer() *http.Server {
+	if s.metrics == nil || s.config.MetricsAddr == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		s.serveMetrics(w, r, true)
+	})
+	return &http.Server{
+		Addr:         s.config.MetricsAddr,
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+}
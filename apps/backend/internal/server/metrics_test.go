@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bufio"
+	"compify-backend/internal/auth"
+	"compify-backend/internal/jobs"
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sampleLine matches a sample line of the Prometheus text format
+var sampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{(?:[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\]|\\.)*",?)*\})? (\S+)$`)
+
+// parseMetrics parses a scrape into samples keyed by name and labels, failing
+// the test on malformed lines or samples without a TYPE
+func parseMetrics(t *testing.T, body string) map[string]float64 {
+	t.Helper()
+
+	samples := make(map[string]float64)
+	types := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			types[fields[2]] = fields[3]
+			continue
+		}
+		if strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+
+		match := sampleLine.FindStringSubmatch(line)
+		if match == nil {
+			t.Fatalf("Malformed metrics line: %q", line)
+		}
+		family := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(match[1], "_bucket"), "_sum"), "_count")
+		if types[match[1]] == "" && types[family] == "" {
+			t.Errorf("Sample %s has no TYPE line", match[1])
+		}
+		value, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			t.Fatalf("Invalid sample value in %q: %v", line, err)
+		}
+		samples[match[1]+match[2]] = value
+	}
+
+	return samples
+}
+
+// Test scraping HTTP, auth and business metrics
+func TestMetricsEndpoint(t *testing.T) {
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:              "8080",
+			Environment:       "test",
+			LogLevel:          "info",
+			RateLimitRequests: 4,
+			RateLimitWindow:   60,
+		},
+		repos: repos,
+		auth:  authService,
+		jobs:  jobs.NewScheduler(),
+	}
+	server.registerJobs()
+	server.registerMetrics()
+	server.setupRoutes()
+	handler := server.applyMiddleware(server.router)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if _, _, err := authService.Register(context.Background(), &auth.RegistrationRequest{
+		Email:           "metrics@example.com",
+		Username:        "metrics",
+		Password:        "password123",
+		ConfirmPassword: "password123",
+	}, "127.0.0.1", "test-agent"); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+
+	user := createTestUser(t, repos)
+	for _, status := range []models.RegistrationStatus{models.RegistrationStatusConfirmed, models.RegistrationStatusPending} {
+		registration := models.NewRegistration(user.ID, "comp-"+string(status), nil)
+		registration.Status = status
+		if err := repos.Registrations.Create(registration); err != nil {
+			t.Fatalf("Failed to create registration: %v", err)
+		}
+	}
+
+	do("GET", "/health", "")
+	do("GET", "/health", "")
+	do("POST", "/health", "")
+	do("POST", "/api/auth/login", `{"email": "metrics@example.com", "password": "password123"}`)
+
+	for i := 0; i < 3; i++ {
+		do("POST", "/api/auth/login", `{"email": "metrics@example.com", "password": "wrong-password"}`)
+	}
+
+	// Sign-in requests beyond the rate limit count as lockouts
+	if rec := do("POST", "/api/auth/login", `{"email": "metrics@example.com", "password": "password123"}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 beyond the rate limit, got %d", rec.Code)
+	}
+
+	rec := do("GET", "/metrics", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	samples := parseMetrics(t, rec.Body.String())
+
+	expected := map[string]float64{
+		`compify_http_requests_total{method="GET",route="/health",status="200"}`:               2,
+		`compify_http_requests_total{method="POST",route="/health",status="405"}`:              1,
+		`compify_http_requests_total{method="POST",route="/api/auth/login",status="401"}`:      3,
+		`compify_http_requests_total{method="POST",route="/api/auth/login",status="429"}`:      1,
+		`compify_http_request_duration_seconds_count{method="GET",route="/health"}`:            2,
+		`compify_http_request_duration_seconds_bucket{method="GET",route="/health",le="+Inf"}`: 2,
+		`compify_http_requests_in_flight`:                                                      1, // The scrape itself
+		`compify_auth_login_attempts_total{result="success"}`:                                  1,
+		`compify_auth_login_attempts_total{result="failure"}`:                                  3,
+		`compify_auth_lockouts_total`:                                                          1,
+		`compify_auth_registrations_total`:                                                     1,
+		`compify_registrations{competition="comp-confirmed",status="confirmed"}`:               1,
+		`compify_registrations{competition="comp-pending",status="pending"}`:                   1,
+		`compify_active_sessions`:                                                              2,
+		`compify_job_runs_total{job="session-cleanup"}`:                                        0,
+	}
+	for key, want := range expected {
+		got, exists := samples[key]
+		if !exists {
+			t.Errorf("Missing sample %s", key)
+		} else if got != want {
+			t.Errorf("Sample %s = %v, want %v", key, got, want)
+		}
+	}
+
+	t.Run("requires token when configured", func(t *testing.T) {
+		server.config.MetricsToken = "scrape-secret"
+		defer func() { server.config.MetricsToken = "" }()
+
+		if rec := do("GET", "/metrics", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 without token, got %d", rec.Code)
+		}
+
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer scrape-secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with token, got %d", rec.Code)
+		}
+	})
+
+	t.Run("closed in production without token", func(t *testing.T) {
+		server.config.Environment = "production"
+		defer func() { server.config.Environment = "test" }()
+
+		if rec := do("GET", "/metrics", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 in production without token, got %d", rec.Code)
+		}
+	})
+}
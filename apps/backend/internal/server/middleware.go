@@ -1,51 +1,70 @@
 package server
 
 import (
-	"fmt"
-	"log"
+	"compify-backend/internal/logging"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/a-h/templ"
 )
 
+// requestIDMiddleware propagates the client's X-Request-ID, or generates
+// one, and stores it in the request context for logging
+func (s *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !logging.ValidRequestID(requestID) {
+			requestID = logging.NewRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+		ctx := logging.WithRequestInfo(r.Context(), &logging.RequestInfo{RequestID: requestID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // loggingMiddleware logs HTTP requests
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		
-		// Create a response writer wrapper to capture status code
+		// Create a response writer wrapper to capture status code and size
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		
 		// Call the next handler
 		next.ServeHTTP(wrapped, r)
 		
-		// Log the request
-		duration := time.Since(start)
-		log.Printf("%s %s %d %v %s", 
-			r.Method, 
-			r.URL.Path, 
-			wrapped.statusCode, 
-			duration,
-			r.RemoteAddr,
-		)
-	})
-}
-
-// corsMiddleware handles CORS headers
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		w.Header().Set("Access-Control-Max-Age", "86400")
+		// Server errors are logged as errors, client errors as warnings
+		level := slog.LevelInfo
+		switch {
+		case wrapped.statusCode >= 500:
+			level = slog.LevelError
+		case wrapped.statusCode >= 400:
+			level = slog.LevelWarn
+		}
 		
-		// Handle preflight requests
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", wrapped.statusCode),
+			slog.Int64("bytes", wrapped.bytesWritten),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("client_ip", s.getClientIP(r)),
+			slog.String("user_agent", r.UserAgent()),
 		}
 		
-		next.ServeHTTP(w, r)
+		// Headers are only logged when debugging, with credentials redacted
+		ctx := r.Context()
+		if slog.Default().Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs,
+				logging.Headers("request_headers", r.Header),
+				logging.Headers("response_headers", wrapped.Header()),
+			)
+		}
+		
+		slog.LogAttrs(ctx, level, "HTTP request", attrs...)
 	})
 }
 
@@ -58,67 +77,68 @@ func (s *Server) securityHeadersMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("X-XSS-Protection", "1; mode=block")
 		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
 		
-		// Content Security Policy for development
-		if s.config.Environment == "development" {
-			w.Header().Set("Content-Security-Policy", "default-src 'self' 'unsafe-inline'; script-src 'self' 'unsafe-inline' 'unsafe-eval'")
-		} else {
-			w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'")
+		// Content Security Policy with a per-response nonce, which templ
+		// components read from the context to tag inline scripts and styles
+		nonce := newCSPNonce()
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy(nonce))
+		w.Header().Set("Reporting-Endpoints", `csp-endpoint="`+cspReportPath+`"`)
+		
+		// Production is only served over HTTPS
+		if s.config.Environment == "production" {
+			w.Header().Set("Strict-Transport-Security", hstsPolicy)
+			w.Header().Set("Permissions-Policy", permissionsPolicy)
 		}
 		
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(templ.WithNonce(r.Context(), nonce)))
 	})
 }
 
-// cachingMiddleware adds appropriate cache headers based on content type and route
+// cachingMiddleware sets cache headers by route and answers conditional
+// requests. Authenticated, personalized and mutation responses are never
+// cached; other GET responses get an ETag computed from their body.
 func (s *Server) cachingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
+		var cacheHeaders func(http.Header)
 		
-		// Determine cache strategy based on route
+		// Determine cache strategy based on method, credentials and route
 		switch {
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			// Mutations: never cached
+			setNoStore(w)
+			next.ServeHTTP(w, r)
+			return
+			
 		case isStaticAsset(path):
-			// Static assets: long-term caching with immutable flag
+			// Static assets: long-term caching with immutable flag, the same
+			// for every user
 			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
 			w.Header().Set("Expires", time.Now().Add(365*24*time.Hour).Format(http.TimeFormat))
 			
 		case isHealthEndpoint(path):
-			// Health endpoints: short-term caching
+			// Health endpoints: short-term caching, no validation
 			w.Header().Set("Cache-Control", "public, max-age=60")
+			next.ServeHTTP(w, r)
+			return
 			
-		case isAPIEndpoint(path):
-			// API endpoints: no caching for dynamic data
-			w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-			w.Header().Set("Pragma", "no-cache")
-			w.Header().Set("Expires", "0")
-			
-		case isDashboardEndpoint(path):
-			// Dashboard pages: private caching with short TTL
-			w.Header().Set("Cache-Control", "private, max-age=300")
-			
-		case isAuthEndpoint(path):
-			// Authentication pages: no caching
-			w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-			w.Header().Set("Pragma", "no-cache")
-			w.Header().Set("Expires", "0")
+		case isAuthenticatedRequest(r), isAPIEndpoint(path), isDashboardEndpoint(path), isAuthEndpoint(path):
+			// Responses to signed-in users, API data, dashboard fragments and
+			// authentication pages are personalized or dynamic: no caching
+			setNoStore(w)
+			next.ServeHTTP(w, r)
+			return
 			
 		default:
-			// Default: moderate caching for dynamic content
-			w.Header().Set("Cache-Control", "public, max-age=300, s-maxage=600")
+			// Default: moderate caching for public content, which differs
+			// once a session cookie is sent. HTML pages are not cached, which
+			// depends on the content type the handler sets.
+			cacheHeaders = setPublicCache
 		}
 		
-		// Add ETag for conditional requests on dynamic content (but not for health endpoints)
-		if !isStaticAsset(path) && !isAPIEndpoint(path) && !isHealthEndpoint(path) {
-			etag := generateETag(r)
-			w.Header().Set("ETag", etag)
-			
-			// Check If-None-Match header for 304 responses
-			if match := r.Header.Get("If-None-Match"); match == etag {
-				w.WriteHeader(http.StatusNotModified)
-				return
-			}
-		}
-		
-		next.ServeHTTP(w, r)
+		// Buffer the response to validate it against conditional headers
+		buffered := &etagWriter{ResponseWriter: w, cacheHeaders: cacheHeaders}
+		next.ServeHTTP(buffered, r)
+		buffered.finish(r)
 	})
 }
 
@@ -149,22 +169,34 @@ func isAuthEndpoint(path string) bool {
 	return path == "/login" || path == "/register" || (len(path) > 5 && path[:5] == "/auth")
 }
 
-// generateETag creates a simple ETag based on request path and current time
-func generateETag(r *http.Request) string {
-	// Simple ETag generation - in production, this should be more sophisticated
-	// based on actual content hash or last-modified time
-	hash := fmt.Sprintf("%x", time.Now().Unix())
-	return `"` + hash + `"`
-}
-
-// responseWriter wraps http.ResponseWriter to capture status code
+// responseWriter wraps http.ResponseWriter to capture status code and size
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int64
 }
 
 // WriteHeader captures the status code
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write counts the bytes written to the response body
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client, as required by streaming responses
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
\ No newline at end of file
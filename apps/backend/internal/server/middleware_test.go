@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"compify-backend/internal/auth"
+	"compify-backend/internal/logging"
+	"compify-backend/internal/repository"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test that access logs are structured and carry request ID, user ID and
+// response size without leaking credentials
+func TestStructuredRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, "debug", logging.FormatJSON))
+	defer slog.SetDefault(previous)
+
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "debug",
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+	}
+	server.setupRoutes()
+	handler := server.applyMiddleware(server.router)
+
+	user := createTestUser(t, repos)
+	session := createTestSession(t, repos, user.ID)
+
+	// lastRecord returns the most recent access log record
+	lastRecord := func() map[string]interface{} {
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		for i := len(lines) - 1; i >= 0; i-- {
+			var record map[string]interface{}
+			if json.Unmarshal([]byte(lines[i]), &record) == nil && record["msg"] == "HTTP request" {
+				return record
+			}
+		}
+		t.Fatalf("No access log record in: %s", buf.String())
+		return nil
+	}
+
+	t.Run("propagates client request ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/health", nil)
+		req.Header.Set("X-Request-ID", "client-id-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Header().Get("X-Request-ID") != "client-id-1" {
+			t.Errorf("Expected request ID to be echoed, got %q", rec.Header().Get("X-Request-ID"))
+		}
+		record := lastRecord()
+		if record["request_id"] != "client-id-1" {
+			t.Errorf("Expected request_id in log, got %v", record["request_id"])
+		}
+		if record["bytes"] != float64(rec.Body.Len()) {
+			t.Errorf("Expected bytes %d, got %v", rec.Body.Len(), record["bytes"])
+		}
+	})
+
+	t.Run("replaces invalid request IDs", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/health", nil)
+		req.Header.Set("X-Request-ID", "bad id\r\ninjected")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		id := rec.Header().Get("X-Request-ID")
+		if id == "" || strings.Contains(id, "injected") {
+			t.Errorf("Expected a generated request ID, got %q", id)
+		}
+	})
+
+	t.Run("includes user ID and redacts cookies", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest("GET", "/dashboard/sessions", nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		req.Header.Set("Authorization", "Bearer very-secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		record := lastRecord()
+		if record["user_id"] != user.ID {
+			t.Errorf("Expected user_id %s in log, got %v", user.ID, record["user_id"])
+		}
+		if strings.Contains(buf.String(), session.Token) || strings.Contains(buf.String(), "very-secret") {
+			t.Errorf("Credentials leaked into logs: %s", buf.String())
+		}
+	})
+}
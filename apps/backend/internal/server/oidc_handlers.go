@@ -0,0 +1,328 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/models"
+	"compify-backend/internal/oidc"
+	"compify-backend/internal/templates"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// oidcFlowCookie holds the state of a sign-in at a provider until it
+// redirects back. It is scoped to the callback so that it is not sent with
+// any other request.
+const (
+	oidcFlowCookie   = "oidc_flow"
+	oidcFlowPath     = "/auth/oidc/"
+	oidcFlowLifetime = 10 * time.Minute
+)
+
+// errUnknownProvider is returned for provider names that are not configured
+var errUnknownProvider = &models.Error{Kind: models.KindNotFound, Code: "provider_not_found", Message: "Unknown sign-in provider"}
+
+// oidcFlow is the signed content of the flow cookie. UserID is set when a
+// signed in user links an identity rather than signs in with it.
+type oidcFlow struct {
+	Provider  string           `json:"provider"`
+	Request   oidc.AuthRequest `json:"request"`
+	UserID    string           `json:"user_id,omitempty"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+// setupOIDC creates the OpenID Connect providers users can sign in with.
+// The configuration has been validated, so invalid providers are only
+// logged.
+func (s *Server) setupOIDC() {
+	s.oidcProviders = make(map[string]*oidc.Provider)
+	for _, spec := range s.config.OIDCProviders {
+		config, err := oidc.ParseConfig(spec)
+		if err != nil {
+			slog.Error("OIDC provider disabled", "error", err)
+			continue
+		}
+		s.oidcProviders[config.Name] = oidc.NewProvider(config, nil)
+		slog.Info("OIDC provider enabled", "provider", config.Name, "issuer", config.Issuer)
+	}
+}
+
+// oidcProviderNames returns the names of the configured providers, sorted
+func (s *Server) oidcProviderNames() []string {
+	names := make([]string, 0, len(s.oidcProviders))
+	for name := range s.oidcProviders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// handleOIDCLogin starts signing in with a provider
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := s.oidcProviders[r.PathValue("provider")]
+	if !ok {
+		s.rejectRequest(w, r, errUnknownProvider)
+		return
+	}
+
+	authURL, err := s.startOIDCFlow(w, r, provider, "")
+	if err != nil {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(s.formProblem(r, err, "Sign-in is unavailable. Please try again.").Detail), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+// handleIdentityLink starts linking an identity at a provider to the signed
+// in user. It is an HTMX POST, so that another site cannot link its own
+// account to the user's without their CSRF token, answered with HX-Redirect
+// since the page's form-action policy keeps forms from leaving the site.
+func (s *Server) handleIdentityLink(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	provider, ok := s.oidcProviders[r.PathValue("provider")]
+	if !ok {
+		s.rejectRequest(w, r, errUnknownProvider)
+		return
+	}
+
+	authURL, err := s.startOIDCFlow(w, r, provider, user.ID)
+	if err != nil {
+		s.renderIdentitiesSection(w, r, user.ID, s.formProblem(r, err, "Linking is unavailable. Please try again.").Detail)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", authURL)
+	w.WriteHeader(http.StatusOK)
+}
+
+// startOIDCFlow stores a new flow with the provider in the flow cookie and
+// returns the URL to send the user to
+func (s *Server) startOIDCFlow(w http.ResponseWriter, r *http.Request, provider *oidc.Provider, userID string) (string, error) {
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		return "", err
+	}
+	authURL, err := provider.AuthCodeURL(r.Context(), req, s.oidcRedirectURI(provider.Name()))
+	if err != nil {
+		return "", err
+	}
+
+	s.setOIDCFlowCookie(w, oidcFlow{
+		Provider:  provider.Name(),
+		Request:   req,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(oidcFlowLifetime),
+	})
+	return authURL, nil
+}
+
+// handleOIDCCallback completes a flow when the provider redirects back,
+// signing the user in or linking the identity
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := s.oidcProviders[r.PathValue("provider")]
+	if !ok {
+		s.rejectRequest(w, r, errUnknownProvider)
+		return
+	}
+
+	flow, ok := s.readOIDCFlowCookie(r)
+	s.clearOIDCFlowCookie(w)
+	query := r.URL.Query()
+	if !ok || flow.Provider != provider.Name() || subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(flow.Request.State)) != 1 {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Your sign-in expired or was started elsewhere. Please try again."), http.StatusSeeOther)
+		return
+	}
+
+	// fail returns to where the flow started with a message for the user
+	fail := func(message string) {
+		if flow.UserID != "" {
+			s.redirectIdentityError(w, r, message)
+			return
+		}
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(message), http.StatusSeeOther)
+	}
+
+	if query.Get("error") != "" {
+		slog.InfoContext(r.Context(), "OIDC sign-in refused by provider", "provider", provider.Name(), "error", query.Get("error"))
+		fail("Sign-in was cancelled at " + provider.Name() + ".")
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), query.Get("code"), flow.Request, s.oidcRedirectURI(provider.Name()))
+	if err != nil {
+		slog.WarnContext(r.Context(), "OIDC sign-in failed", "provider", provider.Name(), "error", err)
+		fail("Sign-in with " + provider.Name() + " failed. Please try again.")
+		return
+	}
+	identity := &auth.ExternalIdentity{
+		Provider:      provider.Name(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+	}
+
+	if flow.UserID != "" {
+		// The user who started linking must still be the one signed in
+		user, err := s.getAuthenticatedUser(r)
+		if err != nil || user.ID != flow.UserID {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if _, err := s.auth.LinkIdentity(r.Context(), user.ID, identity); err != nil {
+			fail(s.formProblem(r, err, "Linking failed. Please try again.").Detail)
+			return
+		}
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	_, session, err := s.auth.LoginWithIdentity(r.Context(), identity, s.getClientIP(r), r.UserAgent())
+	if err != nil {
+		fail(s.formProblem(r, err, "Sign-in failed. Please try again.").Detail)
+		return
+	}
+
+	// Set session cookie, replacing any session the request carried
+	s.startSession(w, r, session)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// handleIdentitiesList renders the linked accounts section
+func (s *Server) handleIdentitiesList(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	s.renderIdentitiesSection(w, r, user.ID, "")
+}
+
+// handleIdentityUnlink unlinks one of the user's identities
+func (s *Server) handleIdentityUnlink(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	identityID := r.FormValue("identity_id")
+	if identityID == "" {
+		http.Error(w, "Missing identity ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.auth.UnlinkIdentity(r.Context(), user.ID, identityID); err != nil {
+		if errors.Is(err, models.ErrIdentityNotFound) {
+			http.Error(w, "Linked account not found", http.StatusNotFound)
+			return
+		}
+		s.renderIdentitiesSection(w, r, user.ID, s.formProblem(r, err, "Failed to unlink account").Detail)
+		return
+	}
+
+	s.renderIdentitiesSection(w, r, user.ID, "")
+}
+
+// renderIdentitiesSection renders the linked accounts section for the user,
+// with an error message, if any
+func (s *Server) renderIdentitiesSection(w http.ResponseWriter, r *http.Request, userID, errorMessage string) {
+	identities := s.getIdentitiesData(r.Context(), userID)
+
+	w.Header().Set("Content-Type", "text/html")
+	s.render(w, r, "IdentitiesSection", templates.IdentitiesSection(identities, s.oidcProviderNames(), errorMessage))
+}
+
+// getIdentitiesData returns the user's linked identities
+func (s *Server) getIdentitiesData(ctx context.Context, userID string) []models.Identity {
+	identities, err := s.auth.ListIdentities(ctx, userID)
+	if err != nil {
+		// Log error but don't fail - just show no identities
+		identities = []*models.Identity{}
+	}
+
+	// Convert to slice of values instead of pointers
+	identityValues := make([]models.Identity, len(identities))
+	for i, identity := range identities {
+		identityValues[i] = *identity
+	}
+
+	return identityValues
+}
+
+// redirectIdentityError returns to the dashboard, which shows the message in
+// the linked accounts section
+func (s *Server) redirectIdentityError(w http.ResponseWriter, r *http.Request, message string) {
+	http.Redirect(w, r, "/dashboard?identity_error="+url.QueryEscape(message), http.StatusSeeOther)
+}
+
+// oidcRedirectURI returns the callback URL registered with providers
+func (s *Server) oidcRedirectURI(provider string) string {
+	return s.publicURL() + oidcFlowPath + provider + "/callback"
+}
+
+// setOIDCFlowCookie stores a flow in a signed cookie
+func (s *Server) setOIDCFlowCookie(w http.ResponseWriter, flow oidcFlow) {
+	payload, _ := json.Marshal(flow)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    encoded + "." + s.signOIDCFlow(encoded),
+		Path:     oidcFlowPath,
+		MaxAge:   int(oidcFlowLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   s.config.Environment == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// readOIDCFlowCookie returns the flow in the request's flow cookie, if it
+// is present, correctly signed and not expired
+func (s *Server) readOIDCFlowCookie(r *http.Request) (oidcFlow, bool) {
+	var flow oidcFlow
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		return flow, false
+	}
+	encoded, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signOIDCFlow(encoded))) {
+		return flow, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(payload, &flow) != nil || time.Now().After(flow.ExpiresAt) {
+		return flow, false
+	}
+	return flow, true
+}
+
+// clearOIDCFlowCookie clears the flow cookie, so that a flow completes once
+func (s *Server) clearOIDCFlowCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    "",
+		Path:     oidcFlowPath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.config.Environment == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// signOIDCFlow computes the signature of an encoded flow
+func (s *Server) signOIDCFlow(encoded string) string {
+	mac := hmac.New(sha256.New, s.signingSecret())
+	mac.Write([]byte("oidc-flow:" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
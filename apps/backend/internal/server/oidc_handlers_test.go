@@ -0,0 +1,286 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/models"
+	"compify-backend/internal/oidc/oidctest"
+	"compify-backend/internal/repository"
+	"compify-backend/internal/templates"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test signing in with an OpenID Connect provider, against the mock provider,
+// and linking and unlinking identities from the dashboard
+func TestOIDC(t *testing.T) {
+	mock := oidctest.NewProvider(oidctest.User{Subject: "ada-1", Email: "ada@example.com", EmailVerified: true, GivenName: "Ada", FamilyName: "Lovelace"})
+	defer mock.Close()
+
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:          "8080",
+			Environment:   "test",
+			LogLevel:      "info",
+			OIDCProviders: []string{mock.Spec("mock")},
+		},
+		repos: repos,
+		auth:  authService,
+	}
+	server.setupOIDC()
+	server.setupRoutes()
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+	cookie := func(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// complete follows a flow started by start through the mock provider and
+	// returns the response to the callback
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	complete := func(start *httptest.ResponseRecorder, session *models.Session) *httptest.ResponseRecorder {
+		t.Helper()
+		flow := cookie(start, oidcFlowCookie)
+		authURL := start.Header().Get("Location")
+		if start.Header().Get("HX-Redirect") != "" {
+			authURL = start.Header().Get("HX-Redirect")
+		}
+		if !strings.HasPrefix(authURL, mock.URL) || flow == nil {
+			t.Fatalf("Expected a redirect to the provider with the flow cookie, got %d: %s", start.Code, start.Body.String())
+		}
+		if flow.Path != oidcFlowPath || !flow.HttpOnly || flow.SameSite != http.SameSiteLaxMode {
+			t.Errorf("Expected a HttpOnly, SameSite=Lax flow cookie scoped to %s, got %+v", oidcFlowPath, flow)
+		}
+
+		resp, err := noRedirects.Get(authURL)
+		if err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+		resp.Body.Close()
+		callback, _ := url.Parse(resp.Header.Get("Location"))
+		if callback.Path != "/auth/oidc/mock/callback" {
+			t.Fatalf("Expected the provider to redirect to the callback, got %d %s", resp.StatusCode, callback)
+		}
+
+		req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+		req.AddCookie(flow)
+		if session != nil {
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		}
+		return serve(req)
+	}
+	login := func() *httptest.ResponseRecorder {
+		return complete(serve(httptest.NewRequest("GET", "/auth/oidc/mock", nil)), nil)
+	}
+	link := func(session *models.Session) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/dashboard/identities/mock/link", nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		req.Header.Set(templates.CSRFHeader, server.csrfToken(session.ID))
+		req.Header.Set("HX-Request", "true")
+		return complete(serve(req), session)
+	}
+	dashboard := func(session *models.Session, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		req.Header.Set(templates.CSRFHeader, server.csrfToken(session.ID))
+		return serve(req)
+	}
+	loginError := func(rec *httptest.ResponseRecorder) string {
+		location, _ := url.Parse(rec.Header().Get("Location"))
+		return location.Query().Get("error")
+	}
+
+	t.Run("login page lists providers", func(t *testing.T) {
+		rec := serve(httptest.NewRequest("GET", "/login", nil))
+		if !strings.Contains(rec.Body.String(), `href="/auth/oidc/mock"`) {
+			t.Error("Expected a link to sign in with the provider")
+		}
+		if rec := serve(httptest.NewRequest("GET", "/auth/oidc/unknown", nil)); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown provider, got %d", rec.Code)
+		}
+	})
+
+	t.Run("first sign-in creates an account", func(t *testing.T) {
+		rec := login()
+		if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/dashboard" || cookie(rec, "session_token") == nil {
+			t.Fatalf("Expected to be signed in, got %d %s", rec.Code, rec.Header().Get("Location"))
+		}
+		user, err := repos.Users.GetByEmail("ada@example.com")
+		if err != nil {
+			t.Fatalf("Expected a user to be created: %v", err)
+		}
+		if user.Username != "ada" || user.PasswordHash != "" || user.Profile.FirstName != "Ada" {
+			t.Errorf("Unexpected user %+v", user)
+		}
+
+		// Signing in again uses the same account
+		if rec := login(); cookie(rec, "session_token") == nil {
+			t.Fatalf("Expected to sign in again, got %s", rec.Header().Get("Location"))
+		}
+		identities, _ := repos.Identities.GetByUserID(user.ID)
+		if len(identities) != 1 || identities[0].Subject != "ada-1" || identities[0].LastLoginAt.IsZero() {
+			t.Errorf("Expected one identity used to sign in, got %+v", identities)
+		}
+
+		// The only way to sign in cannot be unlinked
+		session := createTestSession(t, repos, user.ID)
+		rec = dashboard(session, "POST", "/dashboard/identities/unlink", "identity_id="+identities[0].ID)
+		if !strings.Contains(rec.Body.String(), models.ErrLastSignInMethod.Message) {
+			t.Errorf("Expected the last sign-in method to be kept, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("verified email links an existing user", func(t *testing.T) {
+		user := createTestUser(t, repos)
+		mock.SetUser(oidctest.User{Subject: "test-1", Email: "Test@Example.com", EmailVerified: true})
+		if rec := login(); cookie(rec, "session_token") == nil {
+			t.Fatalf("Expected to be signed in, got %s", rec.Header().Get("Location"))
+		}
+		if identity, err := repos.Identities.GetByProviderSubject("mock", "test-1"); err != nil || identity.UserID != user.ID {
+			t.Errorf("Expected the identity to be linked to the existing user, got %+v %v", identity, err)
+		}
+	})
+
+	t.Run("pre-registered account is claimed", func(t *testing.T) {
+		// Someone registers the victim's address with their own password
+		ctx := context.Background()
+		squatter, session, err := authService.Register(ctx, &auth.RegistrationRequest{Email: "hopper@example.com", Username: "hopper", Password: "squatter-password", ConfirmPassword: "squatter-password"}, "127.0.0.1", "test")
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		token, err := authService.CreateAccessToken(ctx, squatter.ID, "script", []models.Scope{models.ScopeReadProfile}, time.Time{})
+		if err != nil {
+			t.Fatalf("CreateAccessToken failed: %v", err)
+		}
+
+		// The owner of the address signs in with a verified provider email
+		mock.SetUser(oidctest.User{Subject: "hopper-1", Email: "hopper@example.com", EmailVerified: true})
+		if rec := login(); cookie(rec, "session_token") == nil {
+			t.Fatalf("Expected to be signed in, got %s", rec.Header().Get("Location"))
+		}
+
+		if _, _, err := authService.Login(ctx, &auth.LoginRequest{Email: "hopper@example.com", Password: "squatter-password"}, "127.0.0.1", "test"); err == nil {
+			t.Error("Expected the squatter's password to be cleared")
+		}
+		if _, err := authService.GetUserFromSession(ctx, session.Token); err == nil {
+			t.Error("Expected the squatter's session to be revoked")
+		}
+		if _, err := repos.AccessTokens.GetByToken(token.Token); err == nil {
+			t.Error("Expected the squatter's access token to be revoked")
+		}
+		if identity, err := repos.Identities.GetByProviderSubject("mock", "hopper-1"); err != nil || identity.UserID != squatter.ID {
+			t.Errorf("Expected the identity to be linked to the claimed user, got %+v %v", identity, err)
+		}
+	})
+
+	t.Run("unverified email is refused", func(t *testing.T) {
+		mock.SetUser(oidctest.User{Subject: "mallory-1", Email: "test@example.com", EmailVerified: false})
+		rec := login()
+		if cookie(rec, "session_token") != nil || loginError(rec) != models.ErrEmailNotVerified.Message {
+			t.Errorf("Expected the unverified email to be refused, got %s", rec.Header().Get("Location"))
+		}
+		if _, err := repos.Identities.GetByProviderSubject("mock", "mallory-1"); err == nil {
+			t.Error("Expected no identity to be linked")
+		}
+	})
+
+	t.Run("callback without the flow is rejected", func(t *testing.T) {
+		mock.SetUser(oidctest.User{Subject: "ada-1", Email: "ada@example.com", EmailVerified: true})
+		start := serve(httptest.NewRequest("GET", "/auth/oidc/mock", nil))
+		flow := cookie(start, oidcFlowCookie)
+		resp, err := noRedirects.Get(start.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+		resp.Body.Close()
+		callback, _ := url.Parse(resp.Header.Get("Location"))
+
+		// Another browser's flow, a tampered state and a forged cookie all fail
+		other := cookie(serve(httptest.NewRequest("GET", "/auth/oidc/mock", nil)), oidcFlowCookie)
+		tamperedState := callback.Query()
+		tamperedState.Set("state", "attacker-state")
+		forged := *flow
+		forged.Value = strings.Replace(forged.Value, ".", "x.", 1)
+		for name, req := range map[string]*http.Request{
+			"no cookie":      httptest.NewRequest("GET", callback.RequestURI(), nil),
+			"other flow":     httptest.NewRequest("GET", callback.RequestURI(), nil),
+			"tampered state": httptest.NewRequest("GET", callback.Path+"?"+tamperedState.Encode(), nil),
+			"forged cookie":  httptest.NewRequest("GET", callback.RequestURI(), nil),
+		} {
+			switch name {
+			case "other flow":
+				req.AddCookie(other)
+			case "tampered state":
+				req.AddCookie(flow)
+			case "forged cookie":
+				req.AddCookie(&forged)
+			}
+			if rec := serve(req); cookie(rec, "session_token") != nil || !strings.HasPrefix(rec.Header().Get("Location"), "/login?error=") {
+				t.Errorf("%s: expected the callback to be rejected, got %s", name, rec.Header().Get("Location"))
+			}
+		}
+	})
+
+	t.Run("link and unlink from the dashboard", func(t *testing.T) {
+		user := &models.User{Email: "grace@example.com", Username: "grace", PasswordHash: "hash"}
+		if err := repos.Users.Create(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		session := createTestSession(t, repos, user.ID)
+
+		// The provider account's email need not match the user's
+		mock.SetUser(oidctest.User{Subject: "grace-1", Email: "grace@work.example", EmailVerified: false})
+		if rec := link(session); rec.Header().Get("Location") != "/dashboard" {
+			t.Fatalf("Expected the identity to be linked, got %s", rec.Header().Get("Location"))
+		}
+		rec := dashboard(session, "GET", "/dashboard", "")
+		if !strings.Contains(rec.Body.String(), "grace@work.example") {
+			t.Error("Expected the dashboard to list the linked account")
+		}
+
+		// An account linked to another user cannot be linked again
+		mock.SetUser(oidctest.User{Subject: "ada-1", Email: "ada@example.com", EmailVerified: true})
+		other := createTestSession(t, repos, user.ID)
+		rec = link(other)
+		location, _ := url.Parse(rec.Header().Get("Location"))
+		if location.Path != "/dashboard" || location.Query().Get("identity_error") != models.ErrIdentityLinked.Message {
+			t.Errorf("Expected linking another user's account to fail, got %s", location)
+		}
+
+		// Linking requires the CSRF token
+		req := httptest.NewRequest("POST", "/dashboard/identities/mock/link", nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		if rec := serve(req); rec.Code != http.StatusForbidden {
+			t.Errorf("Expected linking without a CSRF token to be refused, got %d", rec.Code)
+		}
+
+		// A user with a password can unlink their only identity
+		identity, _ := repos.Identities.GetByProviderSubject("mock", "grace-1")
+		rec = dashboard(session, "POST", "/dashboard/identities/unlink", "identity_id="+identity.ID)
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "grace@work.example") {
+			t.Errorf("Expected the identity to be unlinked, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec := dashboard(session, "POST", "/dashboard/identities/unlink", "identity_id="+identity.ID); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected unlinking again to be not found, got %d", rec.Code)
+		}
+	})
+}
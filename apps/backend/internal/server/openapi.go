@@ -0,0 +1,194 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/models"
+	"compify-backend/internal/openapi"
+	"compify-backend/internal/participant"
+	"compify-backend/internal/templates"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// apiOperation describes an API route for the OpenAPI document. Everything
+// else about the operation, such as its path parameters, whether it needs a
+// session and the errors its middleware can answer with, comes from the
+// route table.
+type apiOperation struct {
+	id          string
+	tag         string
+	request     any    // Request body, nil when there is none
+	response    any    // Response body; /api/v1 wraps it in APIResponse's data
+	status      int    // Success status, 200 when zero
+	contentType string // Response media type, application/json when empty
+	paginated   bool   // Takes the cursor and limit query parameters
+	errors      []int  // Statuses of domain errors the handler can return
+}
+
+// apiOperations describes every route under /api/, keyed by method and path
+// as in the route table. TestOpenAPI fails for routes missing from it.
+var apiOperations = map[string]apiOperation{
+	"POST /api/auth/register": {id: "register", tag: "auth", request: auth.RegistrationRequest{}, response: SuccessResponse{}, status: http.StatusCreated, errors: []int{http.StatusConflict}},
+	"POST /api/auth/login":    {id: "login", tag: "auth", request: auth.LoginRequest{}, response: SuccessResponse{}, errors: []int{http.StatusUnauthorized}},
+	"POST /api/auth/logout":   {id: "logout", tag: "auth", response: SuccessResponse{}},
+
+	"GET /api/v1/csrf-token":            {id: "getCSRFToken", tag: "auth", response: csrfTokenResponse{}},
+	"GET /api/v1/me":                    {id: "getMe", tag: "me", response: models.User{}},
+	"PATCH /api/v1/me":                  {id: "updateMe", tag: "me", request: participant.ProfileUpdate{}, response: models.User{}},
+	"GET /api/v1/me/registrations":      {id: "listMyRegistrations", tag: "me", response: []models.Registration{}, paginated: true},
+	"POST /api/v1/registrations":        {id: "createRegistration", tag: "registrations", request: registrationRequest{}, response: models.Registration{}, status: http.StatusCreated, errors: []int{http.StatusNotFound, http.StatusConflict}},
+	"DELETE /api/v1/registrations/{id}": {id: "cancelRegistration", tag: "registrations", response: models.Registration{}, errors: []int{http.StatusNotFound}},
+	"GET /api/v1/competitions":          {id: "listCompetitions", tag: "competitions", response: []models.Competition{}, paginated: true},
+	"GET /api/v1/announcements":         {id: "listAnnouncements", tag: "announcements", response: []models.Announcement{}, paginated: true},
+
+	"GET /api/openapi.json": {id: "getOpenAPI", tag: "docs", response: map[string]any{}},
+	"GET /api/docs":         {id: "getAPIDocs", tag: "docs", contentType: "text/html"},
+}
+
+// pathParameter matches the wildcards of a route path, except {$}
+var pathParameter = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// openAPIDocument describes the API routes of the route table
+func (s *Server) openAPIDocument() openapi.Document {
+	schemas := openapi.NewSchemas()
+	problem := schemas.For(Problem{})
+	doc := openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:   "Compify API",
+			Version: "1.0.0",
+			Description: "Errors are RFC 7807 problem details; match on their code. " +
+				"Lists are paginated by passing pagination.next_cursor as the cursor parameter.",
+		},
+		Paths: make(map[string]openapi.PathItem),
+		Components: openapi.Components{
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", Description: "Session token from POST /api/auth/login, or a personal access token created on the dashboard. Access tokens can only call operations listing one of their scopes."},
+				"cookieAuth": {Type: "apiKey", In: "cookie", Name: "session_token", Description: "Session cookie. Requests that change state must also send the " + templates.CSRFHeader + " header."},
+			},
+		},
+	}
+
+	for _, route := range s.Routes() {
+		described, ok := apiOperations[route.Method+" "+route.Path]
+		if !ok {
+			continue
+		}
+
+		operation := &openapi.Operation{
+			OperationID: described.id,
+			Summary:     route.Summary,
+			Tags:        []string{described.tag},
+			Responses:   make(map[string]openapi.Response),
+		}
+		for _, match := range pathParameter.FindAllStringSubmatch(route.Path, -1) {
+			operation.Parameters = append(operation.Parameters, openapi.Parameter{
+				Name: match[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"},
+			})
+		}
+		if described.paginated {
+			operation.Parameters = append(operation.Parameters,
+				openapi.Parameter{Name: "cursor", In: "query", Description: "pagination.next_cursor of the previous page", Schema: &openapi.Schema{Type: "string"}},
+				openapi.Parameter{Name: "limit", In: "query", Description: "Page size, at most " + strconv.Itoa(participant.MaxPageLimit), Schema: &openapi.Schema{Type: "integer"}},
+			)
+		}
+		if described.request != nil {
+			operation.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{"application/json": {Schema: schemas.For(described.request)}},
+			}
+		}
+
+		status := described.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		operation.Responses[strconv.Itoa(status)] = successResponse(schemas, route, described)
+
+		errors := slices.Clone(described.errors)
+		if described.request != nil || described.paginated {
+			errors = append(errors, http.StatusBadRequest)
+		}
+		if slices.Contains(route.Middleware, "auth") {
+			operation.Security = []openapi.SecurityRequirement{{"bearerAuth": {}}, {"cookieAuth": {}}}
+			errors = append(errors, http.StatusUnauthorized)
+		}
+		if scope, ok := routeScope(route); ok {
+			operation.Security = []openapi.SecurityRequirement{{"bearerAuth": {scope}}, {"cookieAuth": {}}}
+			errors = append(errors, http.StatusUnauthorized, http.StatusForbidden)
+		}
+		if slices.Contains(route.Middleware, "csrf") && !isSafeMethod(route.Method) {
+			errors = append(errors, http.StatusForbidden)
+		}
+		if slices.Contains(route.Middleware, "rateLimit") {
+			errors = append(errors, http.StatusTooManyRequests)
+		}
+		for _, status := range errors {
+			operation.Responses[strconv.Itoa(status)] = openapi.Response{
+				Description: http.StatusText(status),
+				Content:     map[string]openapi.MediaType{problemContentType: {Schema: problem}},
+			}
+		}
+
+		if doc.Paths[route.Path] == nil {
+			doc.Paths[route.Path] = make(openapi.PathItem)
+		}
+		doc.Paths[route.Path][strings.ToLower(route.Method)] = operation
+	}
+
+	doc.Components.Schemas = schemas.Components()
+	return doc
+}
+
+// routeScope returns the scope a personal access token needs to call a route,
+// false when access tokens cannot call it
+func routeScope(route Route) (string, bool) {
+	for _, name := range route.Middleware {
+		if scope, ok := strings.CutPrefix(name, "scope="); ok {
+			return scope, true
+		}
+	}
+	return "", false
+}
+
+// successResponse describes the response of an operation that succeeded
+func successResponse(schemas *openapi.Schemas, route Route, described apiOperation) openapi.Response {
+	response := openapi.Response{Description: route.Summary}
+	if described.contentType != "" {
+		response.Content = map[string]openapi.MediaType{described.contentType: {Schema: &openapi.Schema{Type: "string"}}}
+		return response
+	}
+
+	schema := schemas.For(described.response)
+	if strings.HasPrefix(route.Path, "/api/v1/") {
+		envelope := &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"data": schema},
+			Required:   []string{"data"},
+		}
+		if described.paginated {
+			envelope.Properties["pagination"] = schemas.For(Pagination{})
+			envelope.Required = append(envelope.Required, "pagination")
+		}
+		schema = envelope
+	}
+	response.Content = map[string]openapi.MediaType{"application/json": {Schema: schema}}
+	return response
+}
+
+// handleOpenAPI serves the OpenAPI document of the JSON API
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.openAPIDocument())
+}
+
+// handleAPIDocs renders the API documentation page, from which requests can
+// also be sent
+func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	s.render(w, r, "APIDocsPage", templates.APIDocsPage(s.openAPIDocument()))
+}
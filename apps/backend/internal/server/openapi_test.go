@@ -0,0 +1,139 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/openapi"
+	"compify-backend/internal/repository"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Test that the OpenAPI document describes every API route, and nothing
+// else
+func TestOpenAPI(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+	}
+	server.setupRoutes()
+
+	t.Run("every API route is described", func(t *testing.T) {
+		routes := make(map[string]bool)
+		for _, route := range server.Routes() {
+			if !strings.HasPrefix(route.Path, "/api/") {
+				continue
+			}
+			key := route.Method + " " + route.Path
+			routes[key] = true
+			if _, ok := apiOperations[key]; !ok {
+				t.Errorf("%s is not described in apiOperations", key)
+			}
+		}
+		for key := range apiOperations {
+			if !routes[key] {
+				t.Errorf("apiOperations describes %s, which is not a route", key)
+			}
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected the JSON document, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid document: %v", err)
+	}
+
+	t.Run("operations are derived from the routes", func(t *testing.T) {
+		if doc.OpenAPI != "3.1.0" || len(doc.Paths) == 0 {
+			t.Fatalf("Unexpected document %+v", doc.Info)
+		}
+
+		ids := make(map[string]bool)
+		for path, item := range doc.Paths {
+			for method, operation := range item {
+				if ids[operation.OperationID] {
+					t.Errorf("Duplicate operation ID %s", operation.OperationID)
+				}
+				ids[operation.OperationID] = true
+				if operation.Summary == "" || len(operation.Responses) == 0 {
+					t.Errorf("%s %s: missing summary or responses", method, path)
+				}
+			}
+		}
+
+		cancel := doc.Paths["/api/v1/registrations/{id}"]["delete"]
+		if cancel == nil || len(cancel.Parameters) != 1 || cancel.Parameters[0].Name != "id" || cancel.Parameters[0].In != "path" {
+			t.Fatalf("Expected the id path parameter, got %+v", cancel)
+		}
+		if len(cancel.Security) == 0 || cancel.Responses["401"].Content[problemContentType].Schema == nil || cancel.Responses["403"].Description == "" {
+			t.Errorf("Expected a session, CSRF and problem responses, got %+v", cancel.Responses)
+		}
+		if scopes := cancel.Security[0]["bearerAuth"]; !slices.Equal(scopes, []string{"write:registrations"}) {
+			t.Errorf("Expected access tokens to need write:registrations, got %v", scopes)
+		}
+		if competitions := doc.Paths["/api/v1/competitions"]["get"]; len(competitions.Security) != 0 || len(competitions.Parameters) != 2 {
+			t.Errorf("Expected a public, paginated operation, got %+v", competitions)
+		}
+
+		register := doc.Paths["/api/auth/register"]["post"]
+		body := doc.Components.Schemas[register.RequestBody.Content["application/json"].Schema.RefName()]
+		if body == nil || body.Properties["confirm_password"] == nil || register.Responses["201"].Content == nil || register.Responses["409"].Content == nil {
+			t.Errorf("Expected RegistrationRequest in, SuccessResponse or a conflict out, got %+v", register)
+		}
+	})
+
+	t.Run("references resolve", func(t *testing.T) {
+		var check func(where string, schema *openapi.Schema)
+		check = func(where string, schema *openapi.Schema) {
+			if schema == nil {
+				return
+			}
+			if schema.Ref != "" && doc.Components.Schemas[schema.RefName()] == nil {
+				t.Errorf("%s: unresolved %s", where, schema.Ref)
+			}
+			for _, property := range schema.Properties {
+				check(where, property)
+			}
+			check(where, schema.Items)
+			check(where, schema.AdditionalProperties)
+		}
+		for path, item := range doc.Paths {
+			for _, operation := range item {
+				if operation.RequestBody != nil {
+					check(path, operation.RequestBody.Content["application/json"].Schema)
+				}
+				for _, response := range operation.Responses {
+					for _, media := range response.Content {
+						check(path, media.Schema)
+					}
+				}
+			}
+		}
+		for name, schema := range doc.Components.Schemas {
+			check(name, schema)
+		}
+	})
+
+	t.Run("docs page lists the operations", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/docs", nil))
+		page := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(page, `id="createRegistration"`) || !strings.Contains(page, `data-path="/api/v1/registrations/{id}"`) {
+			t.Errorf("Expected the docs page, got %d", rec.Code)
+		}
+	})
+}
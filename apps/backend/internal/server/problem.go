@@ -0,0 +1,141 @@
+package server
+
+import (
+	"compify-backend/internal/logging"
+	"compify-backend/internal/models"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, the body of every JSON API
+// error. Code is a stable machine-readable error code; Errors lists the
+// request fields at fault.
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	Code      string         `json:"code"`
+	RequestID string         `json:"request_id,omitempty"`
+	Errors    []FieldProblem `json:"errors,omitempty"`
+}
+
+// FieldProblem is a problem with one request field
+type FieldProblem struct {
+	Field  string `json:"field,omitempty"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// Errors raised by the server itself rather than the domain
+var (
+	errInvalidBody       = &models.Error{Kind: models.KindInvalid, Code: "body_invalid", Message: "Request body is not valid JSON or has unknown fields"}
+	errUnauthorized      = &models.Error{Kind: models.KindUnauthorized, Code: "unauthorized", Message: "Unauthorized"}
+	errForbidden         = &models.Error{Kind: models.KindForbidden, Code: "forbidden", Message: "Forbidden"}
+	errInsufficientScope = &models.Error{Kind: models.KindForbidden, Code: "insufficient_scope", Message: "The access token does not have the scope this request needs"}
+	errInvalidCSRF       = &models.Error{Kind: models.KindForbidden, Code: "csrf_token_invalid", Message: "Invalid CSRF token"}
+	errInternal          = &models.Error{Kind: models.KindInternal, Code: "internal_error", Message: "Internal server error"}
+)
+
+// kindStatus maps error kinds to HTTP statuses
+var kindStatus = map[models.ErrorKind]int{
+	models.KindInternal:     http.StatusInternalServerError,
+	models.KindInvalid:      http.StatusBadRequest,
+	models.KindUnauthorized: http.StatusUnauthorized,
+	models.KindForbidden:    http.StatusForbidden,
+	models.KindNotFound:     http.StatusNotFound,
+	models.KindConflict:     http.StatusConflict,
+	models.KindRateLimited:  http.StatusTooManyRequests,
+}
+
+// problemFor maps an error to its problem. Domain errors keep their code and
+// message; any other error is an internal error whose details stay in the
+// logs.
+func problemFor(err error) Problem {
+	var validation *models.ValidationError
+	if errors.As(err, &validation) {
+		problem := Problem{Status: http.StatusBadRequest, Code: "validation_failed", Detail: "Some fields are invalid"}
+		for _, e := range validation.Errors {
+			problem.Errors = append(problem.Errors, FieldProblem{Field: e.Field, Code: e.Code, Detail: e.Message})
+		}
+		return problem
+	}
+
+	var e *models.Error
+	if !errors.As(err, &e) {
+		e = errInternal
+	}
+	problem := Problem{Status: kindStatus[e.Kind], Code: e.Code, Detail: e.Message}
+	if e.Field != "" {
+		problem.Errors = []FieldProblem{{Field: e.Field, Code: e.Code, Detail: e.Message}}
+	}
+	return problem
+}
+
+// FieldErrors returns the message for each field at fault, for forms that
+// show them inline
+func (p Problem) FieldErrors() map[string]string {
+	fields := make(map[string]string, len(p.Errors))
+	for _, e := range p.Errors {
+		if e.Field != "" && fields[e.Field] == "" {
+			fields[e.Field] = e.Detail
+		}
+	}
+	return fields
+}
+
+// writeError writes the problem for err. Internal errors are logged, since
+// their details are not disclosed.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := problemFor(err)
+	if problem.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "Request failed", "path", r.URL.Path, "error", err)
+	}
+	s.writeProblem(w, r, problem)
+}
+
+// writeProblem writes a problem as application/problem+json, filling in the
+// fields that describe the request
+func (s *Server) writeProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	problem.Instance = r.URL.Path
+	problem.RequestID = logging.RequestID(r.Context())
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// formProblem maps an error to its problem for an HTMX form, which shows it
+// inline. Internal errors are logged and shown as fallback instead.
+func (s *Server) formProblem(r *http.Request, err error, fallback string) Problem {
+	problem := problemFor(err)
+	if problem.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "Form submission failed", "path", r.URL.Path, "error", err)
+		problem.Detail = fallback
+	}
+	return problem
+}
+
+// rejectRequest answers a request refused by middleware, with a problem for
+// the API and plain text otherwise
+func (s *Server) rejectRequest(w http.ResponseWriter, r *http.Request, err error) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		s.writeError(w, r, err)
+		return
+	}
+	problem := problemFor(err)
+	http.Error(w, problem.Detail, problem.Status)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"compify-backend/internal/templates"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test the mapping of errors to problems, and the same mapping rendered
+// inline in HTMX forms
+func TestProblems(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+	}
+	server.setupRoutes()
+
+	user := createTestUser(t, repos)
+	session := createTestSession(t, repos, user.ID)
+
+	t.Run("errors map to statuses and codes", func(t *testing.T) {
+		tests := []struct {
+			err    error
+			status int
+			code   string
+			fields []string
+		}{
+			{models.ErrEmailTaken, http.StatusConflict, "email_taken", []string{"email"}},
+			{fmt.Errorf("creating user: %w", models.ErrUsernameTaken), http.StatusConflict, "username_taken", []string{"username"}},
+			{auth.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials", nil},
+			{models.ErrLoginLinkThrottled, http.StatusTooManyRequests, "login_link_throttled", nil},
+			{models.ErrCompetitionNotFound, http.StatusNotFound, "competition_not_found", nil},
+			{models.NewValidationError(models.ErrInvalidUsername, auth.ErrPasswordTooShort), http.StatusBadRequest, "validation_failed", []string{"username", "password"}},
+			{errors.New("connection refused"), http.StatusInternalServerError, "internal_error", nil},
+		}
+		for _, tt := range tests {
+			problem := problemFor(tt.err)
+			if problem.Status != tt.status || problem.Code != tt.code || len(problem.Errors) != len(tt.fields) {
+				t.Errorf("%v: unexpected problem %+v", tt.err, problem)
+				continue
+			}
+			for i, field := range tt.fields {
+				if problem.Errors[i].Field != field {
+					t.Errorf("%v: expected field %s, got %+v", tt.err, field, problem.Errors[i])
+				}
+			}
+		}
+
+		if problem := problemFor(errors.New("connection refused")); strings.Contains(problem.Detail, "refused") {
+			t.Error("Internal errors must not be disclosed")
+		}
+		if !errors.Is(models.ErrNameTooLong.WithField("first_name"), models.ErrNameTooLong) {
+			t.Error("Expected a copy with a field to match its sentinel")
+		}
+	})
+
+	t.Run("API registration conflicts are problems", func(t *testing.T) {
+		body := `{"email": "TEST@example.com", "username": "someone", "password": "password123", "confirm_password": "password123"}`
+		req := httptest.NewRequest("POST", "/api/auth/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		var problem Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Invalid JSON body %q: %v", rec.Body.String(), err)
+		}
+		if rec.Code != http.StatusConflict || rec.Header().Get("Content-Type") != problemContentType {
+			t.Errorf("Expected a 409 problem, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if problem.Code != "email_taken" || problem.Type != "about:blank" || problem.Title != "Conflict" || problem.Instance != "/api/auth/register" {
+			t.Errorf("Unexpected problem %+v", problem)
+		}
+		if fields := problem.FieldErrors(); fields["email"] == "" {
+			t.Errorf("Expected an email field error, got %+v", problem.Errors)
+		}
+	})
+
+	t.Run("HTMX forms show field errors inline", func(t *testing.T) {
+		body := "email=new@example.com&username=&password=short&confirm_password=short"
+		req := httptest.NewRequest("POST", "/auth/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		page := rec.Body.String()
+		if rec.Code != http.StatusOK || strings.Count(page, `class="form-error"`) != 2 {
+			t.Fatalf("Expected two inline field errors, got %d %q", rec.Code, page)
+		}
+		for _, message := range []string{models.ErrUsernameRequired.Message, auth.ErrPasswordTooShort.Message} {
+			if !strings.Contains(page, html.EscapeString(message)) {
+				t.Errorf("Expected %q inline", message)
+			}
+		}
+	})
+
+	t.Run("profile edit forms show the rejected update's error", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/dashboard/profile/update/bio", strings.NewReader("bio="+strings.Repeat("b", models.MaxBioLength+1)))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+		req.Header.Set(templates.CSRFHeader, server.csrfToken(session.ID))
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		page := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(page, `class="form-error"`) || !strings.Contains(page, models.ErrBioTooLong.Message) {
+			t.Errorf("Expected the edit form with the bio error, got %d %q", rec.Code, page)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"compify-backend/internal/models"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter counts requests per key in fixed windows
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mutex   sync.Mutex
+	windows map[string]*rateWindow
+	pruned  time.Time
+}
+
+// rateWindow is one key's current window
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, windows: make(map[string]*rateWindow)}
+}
+
+// allow records a request for key and reports whether it is within the
+// limit, and otherwise how long until the window resets
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Drop expired windows once per window so idle clients are forgotten
+	if now.Sub(l.pruned) >= l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.pruned = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// rateLimit returns a middleware limiting requests per client IP, with its
+// own counters so that each group using it is limited separately. It is a
+// no-op when rate limiting is disabled.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return s.limitRequests(next, nil)
+}
+
+// signInRateLimit is rateLimit for the sign-in routes, counting refused
+// requests as lockouts in the authentication metrics
+func (s *Server) signInRateLimit(next http.Handler) http.Handler {
+	return s.limitRequests(next, func() { s.auth.Metrics().Lockouts.Inc() })
+}
+
+// limitRequests limits requests per client IP, calling limited, if set,
+// for every refused request
+func (s *Server) limitRequests(next http.Handler, limited func()) http.Handler {
+	if s.config.RateLimitRequests <= 0 || s.config.RateLimitWindow <= 0 {
+		return next
+	}
+
+	limiter := newRateLimiter(s.config.RateLimitRequests, time.Duration(s.config.RateLimitWindow)*time.Second)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, retryAfter := limiter.allow(s.getClientIP(r), time.Now())
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if limited != nil {
+			limited()
+		}
+		seconds := int((retryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		s.rejectRequest(w, r, &models.Error{
+			Kind:    models.KindRateLimited,
+			Code:    "rate_limited",
+			Message: "Too many requests, try again in " + strconv.Itoa(seconds) + " seconds",
+		})
+	})
+}
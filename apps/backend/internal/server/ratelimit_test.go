@@ -0,0 +1,82 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test per-client fixed-window rate limiting
+func TestRateLimit(t *testing.T) {
+	t.Run("limiter resets after the window", func(t *testing.T) {
+		limiter := newRateLimiter(2, time.Minute)
+		now := time.Now()
+		for i := 0; i < 2; i++ {
+			if ok, _ := limiter.allow("a", now); !ok {
+				t.Fatalf("Request %d should be allowed", i+1)
+			}
+		}
+		ok, retryAfter := limiter.allow("a", now.Add(15*time.Second))
+		if ok || retryAfter != 45*time.Second {
+			t.Errorf("Expected rejection with 45s to wait, got %v %s", ok, retryAfter)
+		}
+		if ok, _ := limiter.allow("b", now); !ok {
+			t.Error("Clients must be limited separately")
+		}
+		if ok, _ := limiter.allow("a", now.Add(time.Minute)); !ok {
+			t.Error("Expected a new window to allow requests")
+		}
+		if len(limiter.windows) != 1 {
+			t.Errorf("Expected expired windows to be pruned, got %d", len(limiter.windows))
+		}
+	})
+
+	t.Run("sign-in routes answer 429", func(t *testing.T) {
+		repos := repository.NewRepositories()
+		server := &Server{
+			router: http.NewServeMux(),
+			config: &Config{
+				Port:              "8080",
+				Environment:       "test",
+				LogLevel:          "info",
+				RateLimitRequests: 2,
+				RateLimitWindow:   60,
+			},
+			repos: repos,
+			auth:  auth.NewService(repos),
+		}
+		server.setupRoutes()
+
+		login := func(remoteAddr string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"email": "nobody@example.com", "password": "password123"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = remoteAddr
+			rec := httptest.NewRecorder()
+			server.router.ServeHTTP(rec, req)
+			return rec
+		}
+
+		for i := 0; i < 2; i++ {
+			if rec := login("192.0.2.1:1234"); rec.Code == http.StatusTooManyRequests {
+				t.Fatalf("Request %d should not be limited", i+1)
+			}
+		}
+		rec := login("192.0.2.1:1234")
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("Expected 429 with Retry-After 60, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+		}
+		if rec := login("192.0.2.2:1234"); rec.Code == http.StatusTooManyRequests {
+			t.Error("Other clients must not be limited")
+		}
+
+		rec = httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+		if rec.Code == http.StatusTooManyRequests {
+			t.Error("Routes outside rate-limited groups must not be limited")
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"bytes"
+	"compify-backend/internal/logging"
+	"compify-backend/internal/reporting"
+	"compify-backend/internal/templates"
+	"compify-backend/internal/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+// SetErrorReporter sets the sink that receives recovered panics, such as a
+// client for an error tracking service. It replaces any reporter configured
+// by ErrorReportFile.
+func (s *Server) SetErrorReporter(reporter reporting.ErrorReporter) {
+	s.reporter = reporter
+}
+
+// setupErrorReporting opens the local error report file when configured
+func (s *Server) setupErrorReporting() {
+	if s.config.ErrorReportFile == "" {
+		return
+	}
+
+	f, err := os.OpenFile(s.config.ErrorReportFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		slog.Error("Error reporting disabled", "error", err)
+		return
+	}
+	s.reporter = reporting.NewFileReporter(f)
+	slog.Info("Error reporting enabled", "file", s.config.ErrorReportFile)
+}
+
+// recoveryWriter records whether any part of the response has been sent,
+// after which an error page can no longer replace it
+type recoveryWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (rw *recoveryWriter) WriteHeader(code int) {
+	rw.started = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.started = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher so that streaming handlers keep working
+func (rw *recoveryWriter) Flush() {
+	rw.started = true
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// recoveryMiddleware turns a panic in a handler or inner middleware into a
+// 500 response, after logging the stack and reporting it. API clients get a
+// JSON error, HTMX requests an error fragment and browsers an error page. A
+// panic after the response has started aborts the connection instead, so
+// the client never mistakes a truncated body for a complete one.
+func (s *Server) recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// Deliberate abort, which net/http handles silently
+				panic(recovered)
+			}
+
+			ctx := r.Context()
+			stack := string(debug.Stack())
+			slog.ErrorContext(ctx, "Panic serving request",
+				"panic", fmt.Sprint(recovered),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"stack", stack,
+			)
+			tracing.SpanFromContext(ctx).RecordError(fmt.Errorf("panic: %v", recovered))
+			if s.reporter != nil {
+				event := reporting.NewEvent(ctx, recovered)
+				event.Panic = true
+				event.Stack = stack
+				event.Method = r.Method
+				event.Path = r.URL.Path
+				s.reporter.Report(ctx, event)
+			}
+
+			if rw.started {
+				panic(http.ErrAbortHandler)
+			}
+			s.writePanicResponse(w, r)
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// writePanicResponse replaces whatever the failed handler prepared with a
+// 500 response suited to the client
+func (s *Server) writePanicResponse(w http.ResponseWriter, r *http.Request) {
+	for _, header := range []string{"Content-Length", "Content-Encoding", "Content-Type", "ETag", "Last-Modified"} {
+		w.Header().Del(header)
+	}
+	setNoStore(w)
+
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		s.writeProblem(w, r, problemFor(errInternal))
+		return
+	}
+
+	requestID := logging.RequestID(r.Context())
+
+	// Inner middleware may not have run, so the page gets its own nonce
+	nonce := newCSPNonce()
+	w.Header().Set("Content-Security-Policy", contentSecurityPolicy(nonce))
+	ctx := templ.WithNonce(r.Context(), nonce)
+
+	component := templates.ServerErrorPage(requestID)
+	if r.Header.Get("HX-Request") == "true" {
+		component = templates.ServerErrorContent(requestID)
+	}
+
+	var buf bytes.Buffer
+	if err := renderSafely(ctx, component, &buf); err != nil {
+		slog.ErrorContext(ctx, "Failed to render error page", "error", err)
+		http.Error(w, "Internal server error (request ID "+requestID+")", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write(buf.Bytes())
+}
+
+// renderSafely renders a component, turning a panic while rendering into an
+// error so that a broken error page cannot fail the recovery itself
+func renderSafely(ctx context.Context, component templ.Component, buf *bytes.Buffer) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic rendering: %v", recovered)
+		}
+	}()
+	return component.Render(ctx, buf)
+}
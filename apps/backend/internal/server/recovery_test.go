@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"compify-backend/internal/auth"
+	"compify-backend/internal/reporting"
+	"compify-backend/internal/repository"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test that panics become 500 responses and are reported
+func TestPanicRecovery(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+	}
+	var reports bytes.Buffer
+	server.SetErrorReporter(reporting.NewWriterReporter(&reports))
+	server.setupRoutes()
+	server.router.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		panic("boom")
+	})
+	server.router.HandleFunc("/api/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("api boom")
+	})
+	server.router.HandleFunc("/partial", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial body"))
+		w.(http.Flusher).Flush()
+		panic("after write")
+	})
+	handler := server.applyMiddleware(server.router)
+
+	do := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Request-ID", "req-123")
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("browsers get the error page", func(t *testing.T) {
+		rec := do("/boom", map[string]string{"Accept-Encoding": "gzip"})
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected 500, got %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Errorf("Expected HTML, got %q", ct)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, "<html") || !strings.Contains(body, "req-123") {
+			t.Errorf("Expected a full page with the request ID, got %q", body)
+		}
+		if rec.Header().Get("Cache-Control") != "no-cache, no-store, must-revalidate" {
+			t.Error("Error pages must not be cached")
+		}
+	})
+
+	t.Run("HTMX requests get a fragment", func(t *testing.T) {
+		rec := do("/boom", map[string]string{"HX-Request": "true"})
+		body := rec.Body.String()
+		if rec.Code != http.StatusInternalServerError || strings.Contains(body, "<html") || !strings.Contains(body, "req-123") {
+			t.Errorf("Expected a 500 fragment with the request ID, got %d %q", rec.Code, body)
+		}
+	})
+
+	t.Run("API requests get a problem", func(t *testing.T) {
+		rec := do("/api/boom", nil)
+		var problem Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Expected JSON, got %q", rec.Body.String())
+		}
+		if rec.Code != http.StatusInternalServerError || problem.Code != "internal_error" || problem.RequestID != "req-123" {
+			t.Errorf("Unexpected response %d %+v", rec.Code, problem)
+		}
+	})
+
+	t.Run("panics after the response started abort it", func(t *testing.T) {
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Errorf("Expected http.ErrAbortHandler, got %v", recovered)
+			}
+		}()
+		do("/partial", nil)
+		t.Error("Expected the handler to abort")
+	})
+
+	t.Run("panics are reported with the request", func(t *testing.T) {
+		reports.Reset()
+		do("/api/boom", nil)
+
+		var event reporting.Event
+		if err := json.Unmarshal(reports.Bytes(), &event); err != nil {
+			t.Fatalf("Expected one JSON report, got %q", reports.String())
+		}
+		if !event.Panic || event.Message != "api boom" || event.RequestID != "req-123" || event.Path != "/api/boom" {
+			t.Errorf("Unexpected report %+v", event)
+		}
+		if !strings.Contains(event.Stack, "recovery_test.go") {
+			t.Errorf("Expected the stack to include the panicking handler, got %q", event.Stack)
+		}
+	})
+
+	t.Run("unknown pages render the 404 page", func(t *testing.T) {
+		rec := do("/no-such-page", nil)
+		body := rec.Body.String()
+		if rec.Code != http.StatusNotFound || !strings.Contains(body, "404 - Page Not Found") || !strings.Contains(body, "Play Games") {
+			t.Errorf("Unexpected 404 response %d %q", rec.Code, body)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// routeMethods are the methods probed to build the Allow header of a 405
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// fallbackPattern catches every request no route matches. Since it matches
+// any method, ServeMux never answers 405 itself; handleUnmatched does.
+const fallbackPattern = "/"
+
+// Route describes a registered route, for the route table
+type Route struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Group      string   `json:"group"`
+	Middleware []string `json:"middleware,omitempty"`
+	Summary    string   `json:"summary"`
+}
+
+// groupMiddleware is a middleware attached to a route group
+type groupMiddleware struct {
+	name string
+	wrap func(http.Handler) http.Handler
+}
+
+// use names a middleware for attaching to a route group
+func use(name string, wrap func(http.Handler) http.Handler) groupMiddleware {
+	return groupMiddleware{name: name, wrap: wrap}
+}
+
+// routeGroup registers routes sharing a path prefix and middleware
+type routeGroup struct {
+	server     *Server
+	name       string
+	prefix     string
+	middleware []groupMiddleware
+}
+
+// group starts a route group. Its middleware runs in the order given, after
+// the global middleware and only for the group's routes.
+func (s *Server) group(name, prefix string, middleware ...groupMiddleware) *routeGroup {
+	return &routeGroup{server: s, name: name, prefix: prefix, middleware: middleware}
+}
+
+// handle registers a handler for a method and a path relative to the
+// group's prefix, which may contain ServeMux wildcards such as {field}
+func (g *routeGroup) handle(method, path, summary string, handler http.HandlerFunc) {
+	s := g.server
+	var h http.Handler = handler
+	names := make([]string, len(g.middleware))
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		h = g.middleware[i].wrap(h)
+		names[i] = g.middleware[i].name
+	}
+
+	path = g.prefix + path
+	s.router.Handle(method+" "+path, h)
+	s.routes = append(s.routes, Route{
+		Method:     method,
+		Path:       path,
+		Group:      g.name,
+		Middleware: names,
+		Summary:    summary,
+	})
+}
+
+// Routes returns the route table, sorted by path and method
+func (s *Server) Routes() []Route {
+	routes := slices.Clone(s.routes)
+	slices.SortStableFunc(routes, func(a, b Route) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return routes
+}
+
+// WriteRouteTable writes routes as an aligned text table
+func WriteRouteTable(w io.Writer, routes []Route) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tGROUP\tMIDDLEWARE\tSUMMARY")
+	for _, route := range routes {
+		middleware := strings.Join(route.Middleware, ",")
+		if middleware == "" {
+			middleware = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", route.Method, route.Path, route.Group, middleware, route.Summary)
+	}
+	return tw.Flush()
+}
+
+// matchRoute returns the path pattern of the route matching the request, or
+// "" when none does. For a path that only exists under other methods it also
+// returns the methods that are allowed.
+func (s *Server) matchRoute(r *http.Request) (string, []string) {
+	if _, pattern := s.router.Handler(r); pattern != "" && pattern != fallbackPattern {
+		return routePath(pattern), nil
+	}
+
+	var path string
+	var allowed []string
+	for _, method := range routeMethods {
+		probe := r.WithContext(r.Context())
+		probe.Method = method
+		if _, pattern := s.router.Handler(probe); pattern != "" && pattern != fallbackPattern {
+			path = routePath(pattern)
+			allowed = append(allowed, method)
+			if method == http.MethodGet {
+				allowed = append(allowed, http.MethodHead)
+			}
+		}
+	}
+	return path, allowed
+}
+
+// routeName names the matched route for metrics and spans, keeping
+// cardinality bounded
+func (s *Server) routeName(r *http.Request) string {
+	if path, _ := s.matchRoute(r); path != "" {
+		return path
+	}
+	return "unmatched"
+}
+
+// routePath strips the method from a ServeMux pattern
+func routePath(pattern string) string {
+	if _, path, found := strings.Cut(pattern, " "); found {
+		return path
+	}
+	return pattern
+}
+
+// handleUnmatched answers requests that no route matches: 405 with an Allow
+// header when the path exists under other methods, and the 404 page
+// otherwise
+func (s *Server) handleUnmatched(w http.ResponseWriter, r *http.Request) {
+	_, allowed := s.matchRoute(r)
+	if len(allowed) == 0 {
+		s.handle404(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		s.writeProblem(w, r, Problem{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Detail: "Method not allowed"})
+		return
+	}
+	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+}
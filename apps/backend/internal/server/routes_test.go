@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bytes"
+	"compify-backend/internal/auth"
+	"compify-backend/internal/repository"
+	"compify-backend/internal/templates"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Test method-aware routing, wildcard routes, group middleware and the route
+// table
+func TestRouting(t *testing.T) {
+	repos := repository.NewRepositories()
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  auth.NewService(repos),
+	}
+	server.setupRoutes()
+
+	user := createTestUser(t, repos)
+	session := createTestSession(t, repos, user.ID)
+
+	do := func(method, path, body string, authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if authenticated {
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+			req.Header.Set(templates.CSRFHeader, server.csrfToken(session.ID))
+		}
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("wrong methods get 405 with Allow", func(t *testing.T) {
+		for path, allow := range map[string]string{
+			"/health":                              "GET, HEAD",
+			"/dashboard/profile/update/first-name": "POST",
+			"/":                                    "GET, HEAD",
+		} {
+			rec := do("DELETE", path, "", false)
+			if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != allow {
+				t.Errorf("DELETE %s: expected 405 with Allow %q, got %d %q", path, allow, rec.Code, rec.Header().Get("Allow"))
+			}
+		}
+
+		rec := do("GET", "/api/auth/login", "", false)
+		var problem Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || rec.Code != http.StatusMethodNotAllowed || problem.Code != "method_not_allowed" {
+			t.Errorf("Expected a 405 problem for the API, got %d %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("unknown paths get the 404 page", func(t *testing.T) {
+		rec := do("GET", "/no/such/page", "", false)
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "404 - Page Not Found") {
+			t.Errorf("Expected the 404 page, got %d", rec.Code)
+		}
+	})
+
+	t.Run("profile routes take the field from the path", func(t *testing.T) {
+		rec := do("GET", "/dashboard/profile/edit/last-name", "", true)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `name="last_name"`) {
+			t.Errorf("Expected the last name form, got %d %q", rec.Code, rec.Body.String())
+		}
+
+		rec = do("POST", "/dashboard/profile/update/bio", "bio=Hello there", true)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Hello there") {
+			t.Errorf("Expected the updated bio, got %d %q", rec.Code, rec.Body.String())
+		}
+		if updated, _ := repos.Users.GetByID(user.ID); updated.Profile.Bio != "Hello there" {
+			t.Errorf("Expected the bio to be saved, got %q", updated.Profile.Bio)
+		}
+
+		if rec := do("GET", "/dashboard/profile/edit/password", "", true); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown field, got %d", rec.Code)
+		}
+	})
+
+	t.Run("group middleware guards its routes", func(t *testing.T) {
+		if rec := do("GET", "/dashboard/profile/edit/bio", "", false); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for a fragment without a session, got %d", rec.Code)
+		}
+		if rec := do("GET", "/dashboard", "", false); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login" {
+			t.Errorf("Expected pages to redirect to login, got %d", rec.Code)
+		}
+		if rec := do("GET", "/admin/jobs", "", true); rec.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a non-admin, got %d", rec.Code)
+		}
+	})
+
+	t.Run("route table lists every route with its group", func(t *testing.T) {
+		routes := server.Routes()
+		index := slices.IndexFunc(routes, func(r Route) bool {
+			return r.Method == "POST" && r.Path == "/dashboard/profile/update/{field}"
+		})
+		if index < 0 {
+			t.Fatal("Expected the profile update route in the table")
+		}
+		if route := routes[index]; route.Group != "dashboard" || strings.Join(route.Middleware, ",") != "csrf,auth" {
+			t.Errorf("Unexpected route %+v", route)
+		}
+
+		var out bytes.Buffer
+		if err := WriteRouteTable(&out, routes); err != nil {
+			t.Fatalf("WriteRouteTable failed: %v", err)
+		}
+		if lines := strings.Count(out.String(), "\n"); lines != len(routes)+1 {
+			t.Errorf("Expected a header and %d rows, got %d lines", len(routes), lines)
+		}
+	})
+}
@@ -2,11 +2,23 @@ package server
 
 import (
 	"compify-backend/internal/auth"
+	"compify-backend/internal/config"
+	"compify-backend/internal/health"
+	"compify-backend/internal/jobs"
+	"compify-backend/internal/logging"
+	"compify-backend/internal/mail"
+	"compify-backend/internal/metrics"
+	"compify-backend/internal/models"
+	"compify-backend/internal/oidc"
+	"compify-backend/internal/participant"
+	"compify-backend/internal/reporting"
 	"compify-backend/internal/repository"
-	"log"
+	"compify-backend/internal/tracing"
+	"context"
+	"log/slog"
 	"net/http"
 	"os"
-	"time"
+	"sync/atomic"
 )
 
 // Server represents the HTTP server with its dependencies
@@ -15,139 +27,232 @@ type Server struct {
 	config *Config
 	repos  *repository.Repositories
 	auth   *auth.Service
-}
+	jobs   *jobs.Scheduler
+	health *health.Registry
+
+	// Participant operations shared by the dashboard and the JSON API, set
+	// by setupRoutes
+	participants *participant.Service
+
+	// Metrics, nil when not registered
+	metrics     *metrics.Registry
+	httpMetrics *httpMetrics
+
+	// OpenID Connect providers users can sign in with, by name
+	oidcProviders map[string]*oidc.Provider
+
+	// Mailer for sign-in links, nil when email sign-in is disabled
+	mailer mail.Mailer
 
-// Config holds server configuration
-type Config struct {
-	Port        string
-	Environment string
-	LogLevel    string
+	// Route table, filled by setupRoutes
+	routes []Route
+
+	// Tracer, nil when tracing is disabled
+	tracer *tracing.Tracer
+
+	// Error reporter for recovered panics, nil when none is configured
+	reporter reporting.ErrorReporter
+
+	// Lifecycle state, set by Serve
+	httpServer    *http.Server
+	metricsServer *http.Server
+	draining      atomic.Bool
 }
 
-// NewServer creates a new server instance with configuration
+// Config holds server configuration, loaded and validated by the config
+// package
+type Config = config.Config
+
+// NewServer creates a server configured from the environment alone.
+// Invalid settings are logged; use config.Load and New to refuse them.
 func NewServer() *Server {
-	config := &Config{
-		Port:        getEnv("PORT", "8080"),
-		Environment: getEnv("ENVIRONMENT", "development"),
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
+	cfg, _, err := config.Load(nil, os.LookupEnv)
+	server := New(cfg)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
 	}
+	return server
+}
+
+// New creates a server with a loaded configuration
+func New(config *Config) *Server {
+	// Route all logging, including the standard log package, through slog
+	slog.SetDefault(logging.New(os.Stderr, config.LogLevel, config.LogFormat))
 
 	// Initialize repositories
 	repos := repository.NewRepositories()
 
 	// Initialize auth service
 	authService := auth.NewService(repos)
+	authService.SetSessionPolicy(models.SessionPolicy{
+		IdleTimeout:      config.SessionIdleTimeout,
+		AbsoluteLifetime: config.SessionAbsoluteLifetime,
+	})
+	authService.SetAdminEmails(config.AdminEmails)
+	authService.SetPasswordPolicy(config.PasswordPolicy())
 
 	server := &Server{
 		router: http.NewServeMux(),
 		config: config,
 		repos:  repos,
 		auth:   authService,
+		jobs:   jobs.NewScheduler(),
+		health: health.NewRegistry(),
 	}
 
+	server.setupTracing()
+	server.setupErrorReporting()
+	server.checkCSRFSecret()
+	server.setupOIDC()
+	server.setupMail()
+	server.registerJobs()
+	server.registerHealthChecks()
+	server.registerMetrics()
 	server.setupRoutes()
 	server.initializeSampleData() // Initialize sample data for demonstration
 	return server
 }
 
-// setupRoutes configures the server routes
+// setupRoutes configures the server routes. Routes are registered in groups
+// that attach middleware to just their routes; Routes lists them all.
 func (s *Server) setupRoutes() {
-	// Health check endpoint
-	s.router.HandleFunc("/health", s.handleHealth)
-	s.router.HandleFunc("/status", s.handleStatus)
-	
-	// Static site routing - redirect to static site URLs
-	s.router.HandleFunc("/home", s.handleStaticRedirect)
-	s.router.HandleFunc("/about", s.handleStaticRedirect)
-	s.router.HandleFunc("/rules", s.handleStaticRedirect)
-	s.router.HandleFunc("/timeline", s.handleStaticRedirect)
-	s.router.HandleFunc("/sponsors", s.handleStaticRedirect)
-	s.router.HandleFunc("/faq", s.handleStaticRedirect)
-	
-	// Sandbox routing - redirect to sandbox URLs
-	s.router.HandleFunc("/sandbox", s.handleSandboxRedirect)
-	s.router.HandleFunc("/games", s.handleSandboxRedirect)
-	s.router.HandleFunc("/play", s.handleSandboxRedirect)
-	
+	csrf := use("csrf", s.csrfMiddleware)
+	tokenOrCSRF := use("csrf", s.csrfUnlessAccessToken)
+	rateLimit := use("rateLimit", s.rateLimit)
+	signInRateLimit := use("rateLimit", s.signInRateLimit)
+	requireLogin := use("login", s.requireLogin)
+	requireUser := use("auth", s.requireUser)
+	requireAdmin := use("admin", s.requireAdmin)
+	requireScope := func(scope models.Scope) groupMiddleware {
+		return use("scope="+string(scope), s.requireScope(scope))
+	}
+
+	s.participants = participant.NewService(s.repos)
+
+	// Health check endpoints
+	health := s.group("health", "")
+	health.handle("GET", "/health", "Service health and dependency checks", s.handleHealth)
+	health.handle("GET", "/status", "Build, runtime and configuration status", s.handleStatus)
+	health.handle("GET", "/livez", "Liveness probe", s.handleLivez)
+	health.handle("GET", "/readyz", "Readiness probe", s.handleReadyz)
+	if s.metrics != nil && s.config.MetricsAddr == "" {
+		health.handle("GET", "/metrics", "Prometheus metrics", s.handleMetrics)
+	}
+
+	// Content Security Policy violation reports
+	reports := s.group("reports", "", rateLimit)
+	reports.handle("POST", cspReportPath, "Content Security Policy violation report", s.handleCSPReport)
+
+	// Static site and sandbox routing - redirect to their URLs
+	redirects := s.group("redirects", "")
+	redirects.handle("GET", "/{$}", "Redirect to the static site home page", s.handleRoot)
+	for _, path := range []string{"/home", "/about", "/rules", "/timeline", "/sponsors", "/faq"} {
+		redirects.handle("GET", path, "Redirect to the static site page", s.handleStaticRedirect)
+	}
+	for _, path := range []string{"/sandbox", "/games", "/play"} {
+		redirects.handle("GET", path, "Redirect to the sandbox", s.handleSandboxRedirect)
+	}
+
 	// Template-based authentication pages
-	s.router.HandleFunc("/login", s.handleLoginPage)
-	s.router.HandleFunc("/register", s.handleRegisterPage)
-	
+	pages := s.group("pages", "", csrf)
+	pages.handle("GET", "/login", "Login page", s.handleLoginPage)
+	pages.handle("GET", "/register", "Registration page", s.handleRegisterPage)
+
+	// HTMX and JSON API sign-in endpoints. They take credentials rather than
+	// act on a session, so they need no CSRF token.
+	signIn := s.group("signIn", "", signInRateLimit)
+	signIn.handle("POST", "/auth/login", "Log in from the login form", s.handleLoginForm)
+	signIn.handle("POST", "/auth/register", "Register from the registration form", s.handleRegisterForm)
+	signIn.handle("POST", "/api/auth/register", "Register a user", s.handleRegister)
+	signIn.handle("POST", "/api/auth/login", "Log in and start a session", s.handleLogin)
+	signIn.handle("POST", "/auth/magic-link", "Email a sign-in link", s.handleMagicLinkRequest)
+	signIn.handle("GET", "/auth/magic-link", "Sign in with an emailed link", s.handleMagicLinkLogin)
+	signIn.handle("GET", "/auth/oidc/{provider}", "Sign in with an OpenID Connect provider", s.handleOIDCLogin)
+	signIn.handle("GET", "/auth/oidc/{provider}/callback", "OpenID Connect provider callback", s.handleOIDCCallback)
+
+	// Logout from the dashboard and the JSON API
+	signOut := s.group("signOut", "", csrf)
+	signOut.handle("POST", "/auth/logout", "Log out and end the session", s.handleLogoutForm)
+	signOut.handle("POST", "/api/auth/logout", "End the current session", s.handleLogout)
+
 	// Dashboard page (protected)
-	s.router.HandleFunc("/dashboard", s.handleDashboard)
-	s.router.HandleFunc("/dashboard/", s.handleDashboard)
-	
-	// HTMX authentication endpoints
-	s.router.HandleFunc("/auth/login", s.handleLoginForm)
-	s.router.HandleFunc("/auth/register", s.handleRegisterForm)
-	s.router.HandleFunc("/auth/logout", s.handleLogoutForm)
-	
-	// HTMX dashboard profile endpoints
-	s.router.HandleFunc("/dashboard/profile/edit/first-name", s.handleProfileEditFirstName)
-	s.router.HandleFunc("/dashboard/profile/edit/last-name", s.handleProfileEditLastName)
-	s.router.HandleFunc("/dashboard/profile/edit/bio", s.handleProfileEditBio)
-	s.router.HandleFunc("/dashboard/profile/update/first-name", s.handleProfileUpdateFirstName)
-	s.router.HandleFunc("/dashboard/profile/update/last-name", s.handleProfileUpdateLastName)
-	s.router.HandleFunc("/dashboard/profile/update/bio", s.handleProfileUpdateBio)
-	s.router.HandleFunc("/dashboard/profile/cancel/first-name", s.handleProfileCancelFirstName)
-	s.router.HandleFunc("/dashboard/profile/cancel/last-name", s.handleProfileCancelLastName)
-	s.router.HandleFunc("/dashboard/profile/cancel/bio", s.handleProfileCancelBio)
-	
-	// HTMX dashboard registration endpoints
-	s.router.HandleFunc("/dashboard/registration/status", s.handleRegistrationStatus)
-	s.router.HandleFunc("/dashboard/registration/create", s.handleCreateRegistration)
-	
-	// HTMX dashboard announcements endpoints
-	s.router.HandleFunc("/dashboard/announcements/refresh", s.handleAnnouncementsRefresh)
-	
-	// JSON API authentication endpoints (for backward compatibility)
-	s.router.HandleFunc("/api/auth/register", s.handleRegister)
-	s.router.HandleFunc("/api/auth/login", s.handleLogin)
-	s.router.HandleFunc("/api/auth/logout", s.handleLogout)
-	
-	// Root endpoint - redirect to static site home
-	s.router.HandleFunc("/", s.handleRoot)
+	dashboardPages := s.group("dashboard", "/dashboard", csrf, requireLogin)
+	dashboardPages.handle("GET", "", "Dashboard page", s.handleDashboard)
+	dashboardPages.handle("GET", "/{$}", "Dashboard page", s.handleDashboard)
+
+	// HTMX dashboard fragments (protected)
+	dashboard := s.group("dashboard", "/dashboard", csrf, requireUser)
+	dashboard.handle("GET", "/profile/edit/{field}", "Profile field edit form", s.handleProfileEdit)
+	dashboard.handle("POST", "/profile/update/{field}", "Update a profile field", s.handleProfileUpdate)
+	dashboard.handle("GET", "/profile/cancel/{field}", "Cancel editing a profile field", s.handleProfileCancel)
+	dashboard.handle("GET", "/registration/status", "Registration status section", s.handleRegistrationStatus)
+	dashboard.handle("POST", "/registration/create", "Register for the competition", s.handleCreateRegistration)
+	dashboard.handle("GET", "/announcements/refresh", "Announcements section", s.handleAnnouncementsRefresh)
+	dashboard.handle("GET", "/sessions", "Active sessions section", s.handleSessionsList)
+	dashboard.handle("POST", "/sessions/revoke", "Revoke one session", s.handleSessionRevoke)
+	dashboard.handle("POST", "/sessions/revoke-others", "Revoke all other sessions", s.handleSessionRevokeOthers)
+	dashboard.handle("GET", "/tokens", "API tokens section", s.handleAccessTokensList)
+	dashboard.handle("POST", "/tokens", "Create an API token", s.handleAccessTokenCreate)
+	dashboard.handle("POST", "/tokens/revoke", "Revoke an API token", s.handleAccessTokenRevoke)
+	dashboard.handle("GET", "/identities", "Linked accounts section", s.handleIdentitiesList)
+	dashboard.handle("POST", "/identities/{provider}/link", "Link an account at an OpenID Connect provider", s.handleIdentityLink)
+	dashboard.handle("POST", "/identities/unlink", "Unlink an account", s.handleIdentityUnlink)
+
+	// Versioned JSON API. Public data needs no session; the rest takes the
+	// session cookie or a Bearer token.
+	apiPublic := s.group("apiV1", "/api/v1")
+	apiPublic.handle("GET", "/competitions", "List competitions", s.handleAPICompetitions)
+	apiPublic.handle("GET", "/announcements", "List published announcements", s.handleAPIAnnouncements)
+
+	// CSRF token for cookie-authenticated clients on other origins
+	apiSession := s.group("apiV1", "/api/v1", csrf, requireUser)
+	apiSession.handle("GET", "/csrf-token", "CSRF token of the current session", s.handleAPICSRFToken)
+
+	// Authenticated API routes, grouped by the scope a personal access token
+	// needs to call them
+	readProfile := s.group("apiV1", "/api/v1", tokenOrCSRF, requireScope(models.ScopeReadProfile))
+	readProfile.handle("GET", "/me", "Current user and profile", s.handleAPIMe)
+	readProfile.handle("GET", "/me/registrations", "List the current user's registrations", s.handleAPIMyRegistrations)
+
+	writeProfile := s.group("apiV1", "/api/v1", tokenOrCSRF, requireScope(models.ScopeWriteProfile))
+	writeProfile.handle("PATCH", "/me", "Update the current user's profile", s.handleAPIUpdateMe)
+
+	writeRegistrations := s.group("apiV1", "/api/v1", tokenOrCSRF, requireScope(models.ScopeWriteRegistrations))
+	writeRegistrations.handle("POST", "/registrations", "Register for a competition", s.handleAPICreateRegistration)
+	writeRegistrations.handle("DELETE", "/registrations/{id}", "Cancel a registration", s.handleAPICancelRegistration)
+
+	// API description, generated from the routes above
+	docs := s.group("docs", "/api", csrf)
+	docs.handle("GET", "/openapi.json", "OpenAPI description of the JSON API", s.handleOpenAPI)
+	docs.handle("GET", "/docs", "API documentation", s.handleAPIDocs)
+
+	// Admin pages
+	admin := s.group("admin", "/admin", csrf, requireLogin, requireAdmin)
+	admin.handle("GET", "/jobs", "Background job status", s.handleAdminJobs)
+
+	// Everything else: 404 page, or 405 for known paths
+	s.router.HandleFunc(fallbackPattern, s.handleUnmatched)
 }
 
-// Start starts the HTTP server with middleware
+// Start starts the HTTP server with middleware and blocks until it stops.
+// Use Run to control the server's lifetime with a context.
 func (s *Server) Start() error {
-	// Apply middleware chain
-	handler := s.applyMiddleware(s.router)
-	
-	// Get host from environment (required for AlwaysData and other hosts)
-	port := os.Getenv("ALWAYSDATA_HTTP_PORT")
-	if port == "" {
-		port = s.config.Port
-	}
-
-	addr := ":" + port
-	log.Printf("Starting Compify backend on %s (env: %s)", addr, s.config.Environment)
-	
-	server := &http.Server{
-		Addr:         addr,
-		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
-	
-	return server.ListenAndServe()
+	return s.Run(context.Background())
 }
 
 // applyMiddleware applies the middleware chain to the handler
 func (s *Server) applyMiddleware(handler http.Handler) http.Handler {
 	// Apply middleware in reverse order (last applied = first executed)
-	handler = s.securityHeadersMiddleware(handler)
-	handler = s.cachingMiddleware(handler)
-	handler = s.corsMiddleware(handler)
-	handler = s.loggingMiddleware(handler)
+	handler = s.traceHandler(handler)
+	handler = s.traceMiddleware("securityHeaders", s.securityHeadersMiddleware, handler)
+	handler = s.traceMiddleware("caching", s.cachingMiddleware, handler)
+	handler = s.traceMiddleware("cors", s.corsMiddleware, handler)
+	handler = s.traceMiddleware("compression", s.compressionMiddleware, handler)
+	handler = s.traceMiddleware("recovery", s.recoveryMiddleware, handler)
+	handler = s.traceMiddleware("metrics", s.metricsMiddleware, handler)
+	handler = s.traceMiddleware("logging", s.loggingMiddleware, handler)
+	handler = s.tracingMiddleware(handler)
+	handler = s.clientIPMiddleware(handler)
+	handler = s.requestIDMiddleware(handler)
 	return handler
 }
-
-// getEnv gets environment variable with fallback
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return fallback
-}
\ No newline at end of file
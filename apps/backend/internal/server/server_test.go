@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -51,7 +52,7 @@ func TestServerAuthoritativeStateManagement(t *testing.T) {
 				LastName:        "User",
 			}
 			
-			user, session, err := authService.Register(regReq, "127.0.0.1", "test-agent")
+			user, session, err := authService.Register(context.Background(), regReq, "127.0.0.1", "test-agent")
 			if err != nil {
 				// Skip invalid inputs - this is expected for random data
 				return true
@@ -83,7 +84,7 @@ func TestServerAuthoritativeStateManagement(t *testing.T) {
 
 			// Test 4: Server state is authoritative source of truth
 			// Verify we can retrieve the updated state
-			finalUser, err := authService.GetUserFromSession(session.Token)
+			finalUser, err := authService.GetUserFromSession(context.Background(), session.Token)
 			if err != nil || finalUser.Profile.FirstName != "Updated" {
 				return false // Server should return updated state
 			}
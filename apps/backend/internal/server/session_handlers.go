@@ -0,0 +1,95 @@
+package server
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/templates"
+	"context"
+	"errors"
+	"net/http"
+)
+
+// handleSessionsList renders the active sessions section
+func (s *Server) handleSessionsList(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	s.renderSessionsSection(w, r, user.ID)
+}
+
+// handleSessionRevoke revokes a single session belonging to the user
+func (s *Server) handleSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	// Parse form data
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	sessionID := r.FormValue("session_id")
+	if sessionID == "" {
+		http.Error(w, "Missing session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.auth.RevokeSession(r.Context(), user.ID, sessionID); err != nil {
+		if errors.Is(err, models.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	// Revoking the current session is equivalent to logging out
+	if current, err := s.auth.GetSession(r.Context(), s.auth.GetSessionFromRequest(r)); err != nil || current.ID == sessionID {
+		s.clearSessionCookie(w)
+		w.Header().Set("HX-Redirect", "/login")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.renderSessionsSection(w, r, user.ID)
+}
+
+// handleSessionRevokeOthers revokes every session except the current one
+func (s *Server) handleSessionRevokeOthers(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	if _, err := s.auth.RevokeOtherSessions(r.Context(), user.ID, s.auth.GetSessionFromRequest(r)); err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	s.renderSessionsSection(w, r, user.ID)
+}
+
+// renderSessionsSection renders the sessions section for the user
+func (s *Server) renderSessionsSection(w http.ResponseWriter, r *http.Request, userID string) {
+	sessions, currentSessionID := s.getSessionsData(r.Context(), userID, s.auth.GetSessionFromRequest(r))
+
+	w.Header().Set("Content-Type", "text/html")
+	s.render(w, r, "SessionsSection", templates.SessionsSection(sessions, currentSessionID))
+}
+
+// getSessionsData returns the user's active sessions and the ID of the session
+// identified by sessionToken
+func (s *Server) getSessionsData(ctx context.Context, userID, sessionToken string) ([]models.Session, string) {
+	sessions, err := s.auth.ListSessions(ctx, userID)
+	if err != nil {
+		// Log error but don't fail - just show no sessions
+		sessions = []*models.Session{}
+	}
+
+	// Convert to slice of values instead of pointers
+	sessionValues := make([]models.Session, len(sessions))
+	for i, session := range sessions {
+		sessionValues[i] = *session
+	}
+
+	var currentSessionID string
+	if current, err := s.auth.GetSession(ctx, sessionToken); err == nil {
+		currentSessionID = current.ID
+	}
+
+	return sessionValues, currentSessionID
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"compify-backend/internal/auth"
+	"compify-backend/internal/models"
+	"compify-backend/internal/repository"
+	"compify-backend/internal/templates"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test listing and revoking sessions from the dashboard
+func TestSessionManagement(t *testing.T) {
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  authService,
+	}
+	server.setupRoutes()
+
+	user := createTestUser(t, repos)
+	current := createTestSession(t, repos, user.ID)
+
+	phone, err := models.NewSession(user.ID, "10.0.0.2", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repos.Sessions.Create(phone); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	laptop := createTestSession(t, repos, user.ID)
+
+	stranger := &models.User{Email: "stranger@example.com", Username: "stranger", PasswordHash: user.PasswordHash}
+	if err := repos.Users.Create(stranger); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	strangerSession := createTestSession(t, repos, stranger.ID)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: current.Token})
+		req.Header.Set(templates.CSRFHeader, server.csrfToken(current.ID))
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("lists sessions with parsed device", func(t *testing.T) {
+		rec := do("GET", "/dashboard/sessions", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, "Safari on iOS") {
+			t.Errorf("Expected parsed device in response, got: %s", body)
+		}
+		if !strings.Contains(body, "This device") {
+			t.Errorf("Expected current session marker, got: %s", body)
+		}
+	})
+
+	t.Run("cannot revoke another user's session", func(t *testing.T) {
+		rec := do("POST", "/dashboard/sessions/revoke", "session_id="+strangerSession.ID)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rec.Code)
+		}
+		if _, err := repos.Sessions.GetByToken(strangerSession.Token); err != nil {
+			t.Errorf("Stranger session should still exist: %v", err)
+		}
+	})
+
+	t.Run("revokes a single session", func(t *testing.T) {
+		rec := do("POST", "/dashboard/sessions/revoke", "session_id="+phone.ID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		if _, err := repos.Sessions.GetByToken(phone.Token); err != models.ErrSessionNotFound {
+			t.Errorf("Expected revoked session to be gone, got: %v", err)
+		}
+		if _, err := repos.Sessions.GetByToken(laptop.Token); err != nil {
+			t.Errorf("Other sessions should be untouched: %v", err)
+		}
+	})
+
+	t.Run("revokes all other sessions", func(t *testing.T) {
+		rec := do("POST", "/dashboard/sessions/revoke-others", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		sessions, _ := repos.Sessions.GetByUserID(user.ID)
+		if len(sessions) != 1 || sessions[0].ID != current.ID {
+			t.Errorf("Expected only the current session to remain, got %d sessions", len(sessions))
+		}
+		if _, err := repos.Sessions.GetByToken(strangerSession.Token); err != nil {
+			t.Errorf("Other users' sessions must not be revoked: %v", err)
+		}
+	})
+
+	t.Run("revoking the current session logs out", func(t *testing.T) {
+		rec := do("POST", "/dashboard/sessions/revoke", "session_id="+current.ID)
+		if rec.Header().Get("HX-Redirect") != "/login" {
+			t.Errorf("Expected HX-Redirect to /login, got %q", rec.Header().Get("HX-Redirect"))
+		}
+	})
+}
+
+// Test that session activity is recorded at LastSeenResolution granularity
+func TestSessionTouch(t *testing.T) {
+	repos := repository.NewRepositories()
+	user := createTestUser(t, repos)
+	session := createTestSession(t, repos, user.ID)
+
+	soon := session.LastSeenAt.Add(models.LastSeenResolution / 2)
+	if err := repos.Sessions.Touch(session.Token, soon); err != nil {
+		t.Fatalf("Touch failed: %v", err)
+	}
+	stored, _ := repos.Sessions.GetByToken(session.Token)
+	if !stored.LastSeenAt.Equal(session.LastSeenAt) {
+		t.Errorf("LastSeenAt should not change within the resolution window")
+	}
+
+	later := session.LastSeenAt.Add(2 * models.LastSeenResolution)
+	if err := repos.Sessions.Touch(session.Token, later); err != nil {
+		t.Fatalf("Touch failed: %v", err)
+	}
+	stored, _ = repos.Sessions.GetByToken(session.Token)
+	if !stored.LastSeenAt.Equal(later) {
+		t.Errorf("Expected LastSeenAt %v, got %v", later, stored.LastSeenAt)
+	}
+
+	if err := repos.Sessions.Touch("missing", time.Now()); err != models.ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+// Test that only token hashes are stored and that idle expiry slides up to the
+// absolute lifetime
+func TestSessionExpiryAndHashedStorage(t *testing.T) {
+	repos := repository.NewRepositories()
+	user := createTestUser(t, repos)
+
+	policy := models.SessionPolicy{IdleTimeout: time.Hour, AbsoluteLifetime: 3 * time.Hour}
+	session, err := models.NewSessionWithPolicy(user.ID, "127.0.0.1", "test-agent", policy)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repos.Sessions.Create(session); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	stored, err := repos.Sessions.GetByToken(session.Token)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if stored.Token != "" {
+		t.Errorf("Plaintext token must not be stored, got %q", stored.Token)
+	}
+	if stored.TokenHash != models.HashSessionToken(session.Token) || stored.TokenHash == session.Token {
+		t.Errorf("Expected SHA-256 token hash, got %q", stored.TokenHash)
+	}
+	if _, err := repos.Sessions.GetByToken(stored.TokenHash); err != models.ErrSessionNotFound {
+		t.Errorf("Looking up by the stored hash must not succeed, got %v", err)
+	}
+
+	// Activity slides the idle expiry forward
+	activity := session.CreatedAt.Add(30 * time.Minute)
+	if err := repos.Sessions.Touch(session.Token, activity); err != nil {
+		t.Fatalf("Touch failed: %v", err)
+	}
+	stored, _ = repos.Sessions.GetByToken(session.Token)
+	if want := activity.Add(time.Hour); !stored.ExpiresAt.Equal(want) {
+		t.Errorf("Expected idle expiry %v, got %v", want, stored.ExpiresAt)
+	}
+
+	// ...but never past the absolute lifetime
+	stored.RecordActivity(session.CreatedAt.Add(150 * time.Minute))
+	if !stored.ExpiresAt.Equal(session.AbsoluteExpiresAt) {
+		t.Errorf("Expected expiry capped at %v, got %v", session.AbsoluteExpiresAt, stored.ExpiresAt)
+	}
+}
+
+// Test that tokens rotate on login and on demand
+func TestSessionTokenRotation(t *testing.T) {
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos: repos,
+		auth:  authService,
+	}
+	server.setupRoutes()
+
+	regReq := &auth.RegistrationRequest{
+		Email:           "rotate@example.com",
+		Username:        "rotator",
+		Password:        "password123",
+		ConfirmPassword: "password123",
+	}
+	user, planted, err := authService.Register(context.Background(), regReq, "127.0.0.1", "test-agent")
+	if err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+
+	t.Run("login replaces the session presented by the request", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"email": "rotate@example.com", "password": "password123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: planted.Token})
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		if _, err := repos.Sessions.GetByToken(planted.Token); err != models.ErrSessionNotFound {
+			t.Errorf("Pre-login session should be revoked, got %v", err)
+		}
+
+		var issued string
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == "session_token" {
+				issued = cookie.Value
+			}
+		}
+		if issued == "" || issued == planted.Token {
+			t.Errorf("Expected a fresh session token, got %q", issued)
+		}
+	})
+
+	t.Run("RotateSession invalidates the old token", func(t *testing.T) {
+		_, session, err := authService.Login(context.Background(), &auth.LoginRequest{Email: "rotate@example.com", Password: "password123"}, "127.0.0.1", "test-agent")
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+
+		rotated, err := authService.RotateSession(context.Background(), session.Token)
+		if err != nil {
+			t.Fatalf("RotateSession failed: %v", err)
+		}
+		if rotated.ID != session.ID || rotated.Token == session.Token {
+			t.Errorf("Expected same session with a new token")
+		}
+		if _, err := authService.GetUserFromSession(context.Background(), session.Token); err == nil {
+			t.Errorf("Old token should no longer authenticate")
+		}
+		if got, err := authService.GetUserFromSession(context.Background(), rotated.Token); err != nil || got.ID != user.ID {
+			t.Errorf("New token should authenticate, got %v", err)
+		}
+	})
+
+	t.Run("role changes revoke existing sessions", func(t *testing.T) {
+		_, session, err := authService.Login(context.Background(), &auth.LoginRequest{Email: "rotate@example.com", Password: "password123"}, "127.0.0.1", "test-agent")
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+
+		if _, err := authService.SetRole(context.Background(), user.ID, models.RoleAdmin, ""); err != nil {
+			t.Fatalf("SetRole failed: %v", err)
+		}
+		if _, err := authService.GetUserFromSession(context.Background(), session.Token); err == nil {
+			t.Errorf("Sessions issued before a privilege change should be revoked")
+		}
+	})
+
+	t.Run("role changes rotate the current session", func(t *testing.T) {
+		login := func() *models.Session {
+			t.Helper()
+			_, session, err := authService.Login(context.Background(), &auth.LoginRequest{Email: "rotate@example.com", Password: "password123"}, "127.0.0.1", "test-agent")
+			if err != nil {
+				t.Fatalf("Login failed: %v", err)
+			}
+			return session
+		}
+		current, other := login(), login()
+
+		rotated, err := authService.SetRole(context.Background(), user.ID, models.RoleParticipant, current.Token)
+		if err != nil || rotated == nil {
+			t.Fatalf("Expected the current session to be rotated, got %v %v", rotated, err)
+		}
+		if rotated.ID != current.ID || rotated.Token == current.Token {
+			t.Errorf("Expected the same session with a new token")
+		}
+		for name, token := range map[string]string{"old": current.Token, "other": other.Token} {
+			if _, err := authService.GetUserFromSession(context.Background(), token); err == nil {
+				t.Errorf("The %s token should no longer authenticate", name)
+			}
+		}
+		if got, err := authService.GetUserFromSession(context.Background(), rotated.Token); err != nil || got.Role != models.RoleParticipant {
+			t.Errorf("Expected the rotated token to authenticate with the new role, got %v %v", got, err)
+		}
+	})
+}
@@ -9,14 +9,9 @@ import (
 
 // handleLoginPage renders the login page
 func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Check if user is already authenticated
 	if sessionToken := s.auth.GetSessionFromRequest(r); sessionToken != "" {
-		if _, err := s.auth.GetUserFromSession(sessionToken); err == nil {
+		if _, err := s.auth.GetUserFromSession(r.Context(), sessionToken); err == nil {
 			http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 			return
 		}
@@ -24,21 +19,21 @@ func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
 
 	// Get error message from query params if any
 	errorMessage := r.URL.Query().Get("error")
+	options := templates.LoginOptions{
+		Providers:     s.oidcProviderNames(),
+		MagicLinks:    s.mailer != nil,
+		CompetitionID: r.URL.Query().Get("competition"),
+	}
 
 	w.Header().Set("Content-Type", "text/html")
-	templates.LoginPage(errorMessage).Render(r.Context(), w)
+	s.render(w, r, "LoginPage", templates.LoginPage(errorMessage, options))
 }
 
 // handleRegisterPage renders the registration page
 func (s *Server) handleRegisterPage(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Check if user is already authenticated
 	if sessionToken := s.auth.GetSessionFromRequest(r); sessionToken != "" {
-		if _, err := s.auth.GetUserFromSession(sessionToken); err == nil {
+		if _, err := s.auth.GetUserFromSession(r.Context(), sessionToken); err == nil {
 			http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 			return
 		}
@@ -48,20 +43,15 @@ func (s *Server) handleRegisterPage(w http.ResponseWriter, r *http.Request) {
 	errorMessage := r.URL.Query().Get("error")
 
 	w.Header().Set("Content-Type", "text/html")
-	templates.RegisterPage(errorMessage).Render(r.Context(), w)
+	s.render(w, r, "RegisterPage", templates.RegisterPage(errorMessage))
 }
 
 // handleLoginForm handles HTMX login form submission
 func (s *Server) handleLoginForm(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
 		w.Header().Set("Content-Type", "text/html")
-		templates.LoginFormError("Invalid form data").Render(r.Context(), w)
+		s.render(w, r, "LoginFormError", templates.LoginFormError("Invalid form data", nil))
 		return
 	}
 
@@ -76,44 +66,28 @@ func (s *Server) handleLoginForm(w http.ResponseWriter, r *http.Request) {
 	userAgent := r.UserAgent()
 
 	// Login user
-	_, session, err := s.auth.Login(req, ipAddress, userAgent)
+	_, session, err := s.auth.Login(r.Context(), req, ipAddress, userAgent)
 	if err != nil {
-		var errorMessage string
-		switch err {
-		case auth.ErrInvalidCredentials:
-			errorMessage = "Invalid email or password"
-		default:
-			if strings.Contains(err.Error(), "email is required") || strings.Contains(err.Error(), "password is required") {
-				errorMessage = "Please fill in all required fields"
-			} else {
-				errorMessage = "Login failed. Please try again."
-			}
-		}
-
+		problem := s.formProblem(r, err, "Login failed. Please try again.")
 		w.Header().Set("Content-Type", "text/html")
-		templates.LoginFormError(errorMessage).Render(r.Context(), w)
+		s.render(w, r, "LoginFormError", templates.LoginFormError(problem.Detail, problem.FieldErrors()))
 		return
 	}
 
-	// Set session cookie
-	s.setSessionCookie(w, session.Token)
+	// Set session cookie, replacing any session the request carried
+	s.startSession(w, r, session)
 
 	// Return success response
 	w.Header().Set("Content-Type", "text/html")
-	templates.LoginSuccess().Render(r.Context(), w)
+	s.render(w, r, "LoginSuccess", templates.LoginSuccess())
 }
 
 // handleRegisterForm handles HTMX registration form submission
 func (s *Server) handleRegisterForm(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
 		w.Header().Set("Content-Type", "text/html")
-		templates.RegisterFormError("Invalid form data").Render(r.Context(), w)
+		s.render(w, r, "RegisterFormError", templates.RegisterFormError("Invalid form data", nil))
 		return
 	}
 
@@ -130,55 +104,29 @@ func (s *Server) handleRegisterForm(w http.ResponseWriter, r *http.Request) {
 	userAgent := r.UserAgent()
 
 	// Register user
-	_, session, err := s.auth.Register(req, ipAddress, userAgent)
+	_, session, err := s.auth.Register(r.Context(), req, ipAddress, userAgent)
 	if err != nil {
-		var errorMessage string
-		switch err {
-		case auth.ErrUserAlreadyExists:
-			errorMessage = "User already exists"
-		case auth.ErrPasswordTooShort:
-			errorMessage = "Password must be at least 8 characters long"
-		case auth.ErrPasswordsDoNotMatch:
-			errorMessage = "Passwords do not match"
-		default:
-			if strings.Contains(err.Error(), "email already exists") {
-				errorMessage = "Email is already registered"
-			} else if strings.Contains(err.Error(), "username already exists") {
-				errorMessage = "Username is already taken"
-			} else if strings.Contains(err.Error(), "invalid email") {
-				errorMessage = "Please enter a valid email address"
-			} else if strings.Contains(err.Error(), "invalid username") {
-				errorMessage = "Username must be 3-30 characters and contain only letters, numbers, underscores, and hyphens"
-			} else {
-				errorMessage = "Registration failed. Please try again."
-			}
-		}
-
+		problem := s.formProblem(r, err, "Registration failed. Please try again.")
 		w.Header().Set("Content-Type", "text/html")
-		templates.RegisterFormError(errorMessage).Render(r.Context(), w)
+		s.render(w, r, "RegisterFormError", templates.RegisterFormError(problem.Detail, problem.FieldErrors()))
 		return
 	}
 
-	// Set session cookie
-	s.setSessionCookie(w, session.Token)
+	// Set session cookie, replacing any session the request carried
+	s.startSession(w, r, session)
 
 	// Return success response
 	w.Header().Set("Content-Type", "text/html")
-	templates.RegisterSuccess().Render(r.Context(), w)
+	s.render(w, r, "RegisterSuccess", templates.RegisterSuccess())
 }
 
 // handleLogoutForm handles logout (can be called via HTMX or regular form)
 func (s *Server) handleLogoutForm(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Get session token
 	sessionToken := s.auth.GetSessionFromRequest(r)
 
 	// Logout user
-	if err := s.auth.Logout(sessionToken); err != nil {
+	if err := s.auth.Logout(r.Context(), sessionToken); err != nil {
 		// Log error but don't fail the request
 		// Logout should be idempotent
 	}
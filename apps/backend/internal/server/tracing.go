@@ -0,0 +1,131 @@
+package server
+
+import (
+	"compify-backend/internal/config"
+	"compify-backend/internal/tracing"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/a-h/templ"
+)
+
+// serviceName identifies this service in traces
+const serviceName = "compify-backend"
+
+// newTracer creates the tracer for the configured exporter, or nil when
+// tracing is disabled
+func newTracer(cfg *Config) (*tracing.Tracer, error) {
+	var processor tracing.Processor
+	switch cfg.TracingExporter {
+	case "", config.TracingExporterNone:
+		return nil, nil
+	case config.TracingExporterOTLP:
+		exporter := tracing.NewOTLPExporter(cfg.TracingEndpoint, serviceName, tracing.ParseHeaders(cfg.TracingHeaders))
+		processor = tracing.NewBatchProcessor(exporter)
+	case config.TracingExporterStdout:
+		processor = tracing.NewSimpleProcessor(tracing.NewWriterExporter(os.Stdout))
+	case config.TracingExporterFile:
+		f, err := os.OpenFile(cfg.TracingFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		processor = tracing.NewBatchProcessor(tracing.NewFileExporter(f))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.TracingExporter)
+	}
+
+	return tracing.NewTracer(processor, cfg.TracingSampleRatio), nil
+}
+
+// setupTracing installs the configured tracer as the default so that auth,
+// repository and job spans are recorded. Tracing is disabled on errors
+// rather than preventing startup.
+func (s *Server) setupTracing() {
+	tracer, err := newTracer(s.config)
+	if err != nil {
+		slog.Error("Tracing disabled", "error", err)
+		return
+	}
+	if tracer == nil {
+		return
+	}
+
+	s.tracer = tracer
+	tracing.SetDefault(tracer)
+	slog.Info("Tracing enabled", "exporter", s.config.TracingExporter, "sample_ratio", s.config.TracingSampleRatio)
+}
+
+// tracingMiddleware starts a server span for each request, continuing the
+// caller's trace when a traceparent header is present. The span is named
+// after the matched route pattern to keep names low-cardinality.
+func (s *Server) tracingMiddleware(next http.Handler) http.Handler {
+	if s.tracer == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := s.routeName(r)
+
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := s.tracer.Start(ctx, r.Method+" "+route, tracing.KindServer,
+			tracing.String("http.request.method", r.Method),
+			tracing.String("http.route", route),
+			tracing.String("url.path", r.URL.Path),
+			tracing.String("client.address", s.getClientIP(r)),
+			tracing.String("network.peer.address", r.RemoteAddr),
+			tracing.String("user_agent.original", r.UserAgent()),
+		)
+		defer span.End()
+
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttributes(tracing.Int("http.response.status_code", wrapped.statusCode))
+		if wrapped.statusCode >= 500 {
+			span.RecordError(fmt.Errorf("%d %s", wrapped.statusCode, http.StatusText(wrapped.statusCode)))
+		}
+	})
+}
+
+// traceMiddleware wraps a middleware in a span covering it and everything
+// it calls
+func (s *Server) traceMiddleware(name string, middleware func(http.Handler) http.Handler, next http.Handler) http.Handler {
+	handler := middleware(next)
+	if s.tracer == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := s.tracer.Start(r.Context(), "middleware "+name, tracing.KindInternal)
+		defer span.End()
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// traceHandler wraps the router in a span for the matched handler
+func (s *Server) traceHandler(next http.Handler) http.Handler {
+	if s.tracer == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := s.routeName(r)
+
+		ctx, span := s.tracer.Start(r.Context(), "handler "+route, tracing.KindInternal)
+		defer span.End()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// render renders a templ component in a span named after the template
+func (s *Server) render(w http.ResponseWriter, r *http.Request, name string, component templ.Component) {
+	ctx, span := tracing.Start(r.Context(), "templ "+name)
+	defer span.End()
+
+	if err := component.Render(ctx, w); err != nil {
+		span.RecordError(err)
+		slog.ErrorContext(ctx, "Failed to render template", "template", name, "error", err)
+	}
+}
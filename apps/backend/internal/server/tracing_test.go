@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compify-backend/internal/auth"
+	"compify-backend/internal/repository"
+	"compify-backend/internal/tracing"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test that a request produces a single trace covering middleware, handler,
+// auth service, repository and template spans
+func TestRequestTracing(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := tracing.NewTracer(tracing.NewSimpleProcessor(tracing.NewWriterExporter(&buf)), 1)
+	previous := tracing.Default()
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(previous)
+
+	repos := repository.NewRepositories()
+	authService := auth.NewService(repos)
+	server := &Server{
+		router: http.NewServeMux(),
+		config: &Config{
+			Port:        "8080",
+			Environment: "test",
+			LogLevel:    "info",
+		},
+		repos:  repos,
+		auth:   authService,
+		tracer: tracer,
+	}
+	server.setupRoutes()
+	handler := server.applyMiddleware(server.router)
+
+	if _, _, err := authService.Register(context.Background(), &auth.RegistrationRequest{
+		Email:           "trace@example.com",
+		Username:        "trace",
+		Password:        "password123",
+		ConfirmPassword: "password123",
+	}, "127.0.0.1", "test-agent"); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+
+	// spans returns the spans exported since the last call, keyed by name
+	spans := func() map[string]map[string]any {
+		byName := make(map[string]map[string]any)
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var span map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &span); err != nil {
+				t.Fatalf("Invalid span line %q: %v", scanner.Text(), err)
+			}
+			byName[span["name"].(string)] = span
+		}
+		buf.Reset()
+		return byName
+	}
+	spans()
+
+	t.Run("login continues the caller's trace", func(t *testing.T) {
+		const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		req := httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"email": "trace@example.com", "password": "password123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+
+		got := spans()
+		for _, name := range []string{
+			"POST /api/auth/login",
+			"middleware logging",
+			"middleware securityHeaders",
+			"handler /api/auth/login",
+			"auth.Login",
+			"auth.verifyPassword",
+			"UserRepository.GetByEmail",
+			"SessionRepository.Create",
+		} {
+			span, exists := got[name]
+			if !exists {
+				t.Errorf("Missing span %q", name)
+				continue
+			}
+			if span["trace_id"] != traceID {
+				t.Errorf("Span %q has trace ID %v, want %s", name, span["trace_id"], traceID)
+			}
+		}
+
+		root := got["POST /api/auth/login"]
+		if root["parent_span_id"] != "00f067aa0ba902b7" || root["kind"] != "server" {
+			t.Errorf("Unexpected server span %v", root)
+		}
+		if attrs, _ := root["attributes"].(map[string]any); attrs["http.response.status_code"] != float64(http.StatusOK) {
+			t.Errorf("Unexpected server span attributes %v", root["attributes"])
+		}
+		if login, handler := got["auth.Login"], got["handler /api/auth/login"]; login["parent_span_id"] != handler["span_id"] {
+			t.Error("auth.Login span is not a child of the handler span")
+		}
+		if attrs, _ := got["auth.Login"]["attributes"].(map[string]any); attrs["auth.result"] != "success" {
+			t.Errorf("Unexpected auth.Login attributes %v", got["auth.Login"]["attributes"])
+		}
+	})
+
+	t.Run("template rendering", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/login", nil))
+
+		got := spans()
+		span, exists := got["templ LoginPage"]
+		if !exists {
+			t.Fatalf("Missing template span, got %v", got)
+		}
+		if root := got["GET /login"]; span["trace_id"] != root["trace_id"] {
+			t.Error("Template span is not part of the request trace")
+		}
+	})
+
+	t.Run("server errors mark the span", func(t *testing.T) {
+		server.router.HandleFunc("/test/fail", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test/fail", nil))
+
+		if span := spans()["GET /test/fail"]; span["error"] == nil {
+			t.Errorf("Expected error on server span, got %v", span)
+		}
+	})
+}
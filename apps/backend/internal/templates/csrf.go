@@ -0,0 +1,38 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// CSRFHeader is the request header HTMX sends the CSRF token in, and
+// CSRFField the form field plain form submissions use
+const (
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+)
+
+type csrfTokenKey struct{}
+
+// WithCSRFToken returns a context from which templates can read the CSRF
+// token of the current session. The token is computed on first use, so
+// responses that render no page pay nothing for it.
+func WithCSRFToken(ctx context.Context, token func() string) context.Context {
+	return context.WithValue(ctx, csrfTokenKey{}, token)
+}
+
+// CSRFToken returns the CSRF token for the current session, or "" for
+// anonymous requests
+func CSRFToken(ctx context.Context) string {
+	if token, ok := ctx.Value(csrfTokenKey{}).(func() string); ok {
+		return token()
+	}
+	return ""
+}
+
+// csrfHeaders renders the hx-headers value that makes every HTMX request
+// on the page carry the CSRF token
+func csrfHeaders(token string) string {
+	headers, _ := json.Marshal(map[string]string{CSRFHeader: token})
+	return string(headers)
+}
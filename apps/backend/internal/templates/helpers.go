@@ -0,0 +1,209 @@
+package templates
+
+import (
+	"compify-backend/internal/models"
+	"compify-backend/internal/openapi"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// formatLastSeen renders a session's last activity relative to now
+func formatLastSeen(t time.Time) string {
+	elapsed := time.Since(t)
+	switch {
+	case elapsed < 2*time.Minute:
+		return "Active now"
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		hours := int(elapsed.Hours())
+		if hours == 1 {
+			return "1 hour ago"
+		}
+		return fmt.Sprintf("%d hours ago", hours)
+	default:
+		return t.Format("Jan 2, 2006")
+	}
+}
+
+// tokenExpiryOption is a lifetime offered for new access tokens
+type tokenExpiryOption struct {
+	Value string // Days, or "never"
+	Label string
+}
+
+// tokenExpiryOptions are the lifetimes offered for new access tokens
+var tokenExpiryOptions = []tokenExpiryOption{
+	{"30", "Expires in 30 days"},
+	{"90", "Expires in 90 days"},
+	{"365", "Expires in a year"},
+	{"never", "Never expires"},
+}
+
+// defaultTokenExpiry is the lifetime selected for new access tokens
+const defaultTokenExpiry = "90"
+
+// formatScopes renders the scopes of an access token
+func formatScopes(scopes []models.Scope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatTokenExpiry renders when an access token expires
+func formatTokenExpiry(t time.Time) string {
+	switch {
+	case t.IsZero():
+		return "Never expires"
+	case time.Now().After(t):
+		return "Expired " + t.Format("Jan 2, 2006")
+	default:
+		return "Expires " + t.Format("Jan 2, 2006")
+	}
+}
+
+// formatTokenLastUsed renders an access token's last use relative to now
+func formatTokenLastUsed(t time.Time) string {
+	switch {
+	case t.IsZero():
+		return "Never used"
+	case time.Since(t) < 2*time.Minute:
+		return "Used just now"
+	default:
+		return "Last used " + formatLastSeen(t)
+	}
+}
+
+// LoginOptions are the ways of signing in offered on the login page
+// besides a password
+type LoginOptions struct {
+	Providers     []string // OpenID Connect providers, by name
+	MagicLinks    bool     // Whether sign-in links can be emailed
+	CompetitionID string   // Competition the user came from, whose settings may allow signing up by link
+}
+
+// formatLinkLifetime renders how long an emailed sign-in link can be used
+func formatLinkLifetime() string {
+	return fmt.Sprintf("%d minutes", int(models.LoginLinkLifetime.Minutes()))
+}
+
+// unlinkedProviders returns the providers the user has no identity at
+func unlinkedProviders(providers []string, identities []models.Identity) []string {
+	var unlinked []string
+	for _, provider := range providers {
+		linked := false
+		for _, identity := range identities {
+			if identity.Provider == provider {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			unlinked = append(unlinked, provider)
+		}
+	}
+	return unlinked
+}
+
+// formatJobTime renders a job timestamp, or "Never" for the zero time
+func formatJobTime(t time.Time) string {
+	if t.IsZero() {
+		return "Never"
+	}
+	return t.Format("Jan 2 15:04:05")
+}
+
+// htmxConfig renders the htmx configuration for a page. Scripts in swapped
+// fragments are given the page's CSP nonce, and htmx features that need
+// eval or inject unnonced styles are turned off.
+func htmxConfig(nonce string) string {
+	config, _ := json.Marshal(map[string]any{
+		"inlineScriptNonce":      nonce,
+		"includeIndicatorStyles": false,
+		"allowEval":              false,
+	})
+	return string(config)
+}
+
+// apiDocOperation is an operation of an API document with its route
+type apiDocOperation struct {
+	Method string
+	Path   string
+	*openapi.Operation
+}
+
+// apiDocSection lists the operations sharing a tag
+type apiDocSection struct {
+	Tag        string
+	Operations []apiDocOperation
+}
+
+// apiDocSections groups the operations of an API document by tag, sorting
+// tags by name and operations by path
+func apiDocSections(doc openapi.Document) []apiDocSection {
+	byTag := make(map[string][]apiDocOperation)
+	for _, path := range slices.Sorted(maps.Keys(doc.Paths)) {
+		for _, method := range slices.Sorted(maps.Keys(doc.Paths[path])) {
+			operation := doc.Paths[path][method]
+			tag := "other"
+			if len(operation.Tags) > 0 {
+				tag = operation.Tags[0]
+			}
+			byTag[tag] = append(byTag[tag], apiDocOperation{Method: strings.ToUpper(method), Path: path, Operation: operation})
+		}
+	}
+
+	sections := make([]apiDocSection, 0, len(byTag))
+	for _, tag := range slices.Sorted(maps.Keys(byTag)) {
+		sections = append(sections, apiDocSection{Tag: tag, Operations: byTag[tag]})
+	}
+	return sections
+}
+
+// apiDocStatuses returns the statuses an operation responds with, in order
+func apiDocStatuses(operation *openapi.Operation) []string {
+	return slices.Sorted(maps.Keys(operation.Responses))
+}
+
+// apiDocMediaTypes returns the media types of a response, in order
+func apiDocMediaTypes(response openapi.Response) []string {
+	return slices.Sorted(maps.Keys(response.Content))
+}
+
+// apiDocScopes returns the scopes a personal access token needs to call an
+// operation, none when tokens cannot call it
+func apiDocScopes(operation *openapi.Operation) []string {
+	for _, requirement := range operation.Security {
+		if scopes := requirement["bearerAuth"]; len(scopes) > 0 {
+			return scopes
+		}
+	}
+	return nil
+}
+
+// apiDocJSON renders a schema or example as indented JSON
+func apiDocJSON(v any) string {
+	text, _ := json.MarshalIndent(v, "", "  ")
+	return string(text)
+}
+
+// apiDocRequestSchema returns the schema of an operation's JSON request body,
+// or nil when it takes none
+func apiDocRequestSchema(operation *openapi.Operation) *openapi.Schema {
+	if operation.RequestBody == nil {
+		return nil
+	}
+	return operation.RequestBody.Content["application/json"].Schema
+}
+
+// apiDocSchemaNames returns the names of an API document's component
+// schemas, in order
+func apiDocSchemaNames(doc openapi.Document) []string {
+	return slices.Sorted(maps.Keys(doc.Components.Schemas))
+}
@@ -37,13 +37,62 @@ func BaseLayout(title string, content templ.Component) templ.Component {
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(title)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/layout.templ`, Line: 10, Col: 16}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout.templ`, Line: 10, Col: 16}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, " - Compify</title><meta name=\"description\" content=\"Compify - Competition Platform\"><script src=\"https://unpkg.com/htmx.org@1.9.10\" integrity=\"sha384-D1Kt99CQMDuVetoL1lrYwg5t+9QdHe7NLX/SoJYkXDFfX37iInKRy5xLSi8nO7UC\" crossorigin=\"anonymous\"></script><style>\r\n\t\t\t/* Base styles */\r\n\t\t\t* {\r\n\t\t\t\tmargin: 0;\r\n\t\t\t\tpadding: 0;\r\n\t\t\t\tbox-sizing: border-box;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\tbody {\r\n\t\t\t\tfont-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, sans-serif;\r\n\t\t\t\tline-height: 1.6;\r\n\t\t\t\tcolor: #333;\r\n\t\t\t\tbackground-color: #f8f9fa;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.container {\r\n\t\t\t\tmax-width: 1200px;\r\n\t\t\t\tmargin: 0 auto;\r\n\t\t\t\tpadding: 0 20px;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.header {\r\n\t\t\t\tbackground: #fff;\r\n\t\t\t\tborder-bottom: 1px solid #e9ecef;\r\n\t\t\t\tpadding: 1rem 0;\r\n\t\t\t\tmargin-bottom: 2rem;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.header h1 {\r\n\t\t\t\tcolor: #2c3e50;\r\n\t\t\t\tfont-size: 1.8rem;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.nav {\r\n\t\t\t\tmargin-top: 0.5rem;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.nav a {\r\n\t\t\t\tcolor: #6c757d;\r\n\t\t\t\ttext-decoration: none;\r\n\t\t\t\tmargin-right: 1rem;\r\n\t\t\t\tpadding: 0.5rem 0;\r\n\t\t\t\tborder-bottom: 2px solid transparent;\r\n\t\t\t\ttransition: all 0.2s;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.nav a:hover {\r\n\t\t\t\tcolor: #2c3e50;\r\n\t\t\t\tborder-bottom-color: #007bff;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.main {\r\n\t\t\t\tmin-height: calc(100vh - 200px);\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.footer {\r\n\t\t\t\tbackground: #fff;\r\n\t\t\t\tborder-top: 1px solid #e9ecef;\r\n\t\t\t\tpadding: 2rem 0;\r\n\t\t\t\tmargin-top: 3rem;\r\n\t\t\t\ttext-align: center;\r\n\t\t\t\tcolor: #6c757d;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t/* Form styles */\r\n\t\t\t.form-container {\r\n\t\t\t\tmax-width: 400px;\r\n\t\t\t\tmargin: 2rem auto;\r\n\t\t\t\tbackground: #fff;\r\n\t\t\t\tpadding: 2rem;\r\n\t\t\t\tborder-radius: 8px;\r\n\t\t\t\tbox-shadow: 0 2px 10px rgba(0,0,0,0.1);\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.form-group {\r\n\t\t\t\tmargin-bottom: 1.5rem;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.form-label {\r\n\t\t\t\tdisplay: block;\r\n\t\t\t\tmargin-bottom: 0.5rem;\r\n\t\t\t\tfont-weight: 500;\r\n\t\t\t\tcolor: #495057;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.form-input {\r\n\t\t\t\twidth: 100%;\r\n\t\t\t\tpadding: 0.75rem;\r\n\t\t\t\tborder: 1px solid #ced4da;\r\n\t\t\t\tborder-radius: 4px;\r\n\t\t\t\tfont-size: 1rem;\r\n\t\t\t\ttransition: border-color 0.2s;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.form-input:focus {\r\n\t\t\t\toutline: none;\r\n\t\t\t\tborder-color: #007bff;\r\n\t\t\t\tbox-shadow: 0 0 0 2px rgba(0,123,255,0.25);\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.btn {\r\n\t\t\t\tdisplay: inline-block;\r\n\t\t\t\tpadding: 0.75rem 1.5rem;\r\n\t\t\t\tbackground: #007bff;\r\n\t\t\t\tcolor: #fff;\r\n\t\t\t\tborder: none;\r\n\t\t\t\tborder-radius: 4px;\r\n\t\t\t\tfont-size: 1rem;\r\n\t\t\t\tcursor: pointer;\r\n\t\t\t\ttext-decoration: none;\r\n\t\t\t\ttransition: background-color 0.2s;\r\n\t\t\t\twidth: 100%;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.btn:hover {\r\n\t\t\t\tbackground: #0056b3;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.btn:disabled {\r\n\t\t\t\tbackground: #6c757d;\r\n\t\t\t\tcursor: not-allowed;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.alert {\r\n\t\t\t\tpadding: 0.75rem 1rem;\r\n\t\t\t\tmargin-bottom: 1rem;\r\n\t\t\t\tborder: 1px solid transparent;\r\n\t\t\t\tborder-radius: 4px;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.alert-error {\r\n\t\t\t\tcolor: #721c24;\r\n\t\t\t\tbackground-color: #f8d7da;\r\n\t\t\t\tborder-color: #f5c6cb;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.alert-success {\r\n\t\t\t\tcolor: #155724;\r\n\t\t\t\tbackground-color: #d4edda;\r\n\t\t\t\tborder-color: #c3e6cb;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.text-center {\r\n\t\t\t\ttext-align: center;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.mt-2 {\r\n\t\t\t\tmargin-top: 1rem;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.link {\r\n\t\t\t\tcolor: #007bff;\r\n\t\t\t\ttext-decoration: none;\r\n\t\t\t}\r\n\t\t\t\r\n\t\t\t.link:hover {\r\n\t\t\t\ttext-decoration: underline;\r\n\t\t\t}\r\n\t\t</style></head><body><header class=\"header\"><div class=\"container\"><h1>Compify</h1><nav class=\"nav\"><a href=\"/\">Home</a> <a href=\"/login\">Login</a> <a href=\"/register\">Register</a> <a href=\"/dashboard\">Dashboard</a></nav></div></header><main class=\"main\"><div class=\"container\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, " - Compify</title><meta name=\"description\" content=\"Compify - Competition Platform\"><meta name=\"htmx-config\" content=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 string
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(htmxConfig(templ.GetNonce(ctx)))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout.templ`, Line: 12, Col: 68}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"><script nonce=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 string
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(templ.GetNonce(ctx))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout.templ`, Line: 13, Col: 37}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\" src=\"https://unpkg.com/htmx.org@1.9.10\" integrity=\"sha384-D1Kt99CQMDuVetoL1lrYwg5t+9QdHe7NLX/SoJYkXDFfX37iInKRy5xLSi8nO7UC\" crossorigin=\"anonymous\"></script><!-- Compify Design System --><link rel=\"stylesheet\" href=\"/design-system/tokens.css\"><link rel=\"stylesheet\" href=\"/design-system/base.css\"><link rel=\"stylesheet\" href=\"/design-system/components.css\"><link rel=\"stylesheet\" href=\"/design-system/layout.css\"><link rel=\"stylesheet\" href=\"/design-system/utilities.css\"></head><body")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if CSRFToken(ctx) != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, " hx-headers=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var5 string
+			templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(csrfHeaders(CSRFToken(ctx)))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout.templ`, Line: 24, Col: 43}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "><header class=\"header\"><div class=\"container\"><div class=\"header-content\"><a href=\"/\" class=\"text-2xl font-bold text-primary\">Compify</a><nav class=\"nav nav-horizontal\"><a href=\"/\" class=\"nav-link\">Home</a> <a href=\"/about/\" class=\"nav-link\">About</a> <a href=\"/sandbox/\" class=\"nav-link\">Games</a> <a href=\"/auth/dashboard\" class=\"nav-link\">Dashboard</a> <a href=\"/auth/logout\" class=\"nav-link\">Logout</a></nav></div></div></header><main class=\"main\"><div class=\"container\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -51,7 +100,7 @@ func BaseLayout(title string, content templ.Component) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</div></main><footer class=\"footer\"><div class=\"container\"><p>&copy; 2024 Compify. All rights reserved.</p></div></footer></body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</div></main><footer class=\"footer\"><div class=\"container\"><p class=\"text-center text-secondary\">&copy; 2024 Compify. All rights reserved.</p></div></footer></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -59,4 +108,108 @@ func BaseLayout(title string, content templ.Component) templ.Component {
 	})
 }
 
+// FieldError renders the message for an invalid form field, if any
+func FieldError(message string) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var6 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var6 == nil {
+			templ_7745c5c3_Var6 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		if message != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<p class=\"form-error\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var7 string
+			templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(message)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout.templ`, Line: 60, Col: 33}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		return nil
+	})
+}
+
+// CSRFInput renders the CSRF token as a hidden field for forms that may be
+// submitted without HTMX
+func CSRFInput() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var8 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var8 == nil {
+			templ_7745c5c3_Var8 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		if token := CSRFToken(ctx); token != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<input type=\"hidden\" name=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var9 string
+			templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(CSRFField)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout.templ`, Line: 68, Col: 39}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "\" value=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var10 string
+			templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(token)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/layout.templ`, Line: 68, Col: 55}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		return nil
+	})
+}
+
 var _ = templruntime.GeneratedTemplate
@@ -8,8 +8,9 @@ package templates
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-// FirstNameEditForm renders an inline edit form for first name
-func FirstNameEditForm(currentValue string) templ.Component {
+// FirstNameEditForm renders an inline edit form for first name, with the
+// error of a rejected update
+func FirstNameEditForm(currentValue, errorMessage string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -37,13 +38,21 @@ func FirstNameEditForm(currentValue string) templ.Component {
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(currentValue)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/profile_edit.templ`, Line: 15, Col: 24}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/profile_edit.templ`, Line: 16, Col: 24}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" class=\"form-input\" style=\"width: 150px; padding: 0.25rem 0.5rem; font-size: 0.9rem;\" maxlength=\"100\" required> <button type=\"submit\" class=\"btn\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\">Save</button> <button type=\"button\" class=\"btn btn-secondary\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\" hx-get=\"/dashboard/profile/cancel/first-name\" hx-target=\"#first-name-display\" hx-swap=\"outerHTML\">Cancel</button></form></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" class=\"form-input\" style=\"width: 150px; padding: 0.25rem 0.5rem; font-size: 0.9rem;\" maxlength=\"100\" required> <button type=\"submit\" class=\"btn\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\">Save</button> <button type=\"button\" class=\"btn btn-secondary\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\" hx-get=\"/dashboard/profile/cancel/first-name\" hx-target=\"#first-name-display\" hx-swap=\"outerHTML\">Cancel</button></form>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = FieldError(errorMessage).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -51,8 +60,9 @@ func FirstNameEditForm(currentValue string) templ.Component {
 	})
 }
 
-// LastNameEditForm renders an inline edit form for last name
-func LastNameEditForm(currentValue string) templ.Component {
+// LastNameEditForm renders an inline edit form for last name, with the
+// error of a rejected update
+func LastNameEditForm(currentValue, errorMessage string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -73,20 +83,28 @@ func LastNameEditForm(currentValue string) templ.Component {
 			templ_7745c5c3_Var3 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<div id=\"last-name-display\"><form hx-post=\"/dashboard/profile/update/last-name\" hx-target=\"#last-name-display\" hx-swap=\"outerHTML\" style=\"display: inline-flex; align-items: center; gap: 0.5rem;\"><input type=\"text\" name=\"last_name\" value=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<div id=\"last-name-display\"><form hx-post=\"/dashboard/profile/update/last-name\" hx-target=\"#last-name-display\" hx-swap=\"outerHTML\" style=\"display: inline-flex; align-items: center; gap: 0.5rem;\"><input type=\"text\" name=\"last_name\" value=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var4 string
 		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(currentValue)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/profile_edit.templ`, Line: 48, Col: 24}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/profile_edit.templ`, Line: 51, Col: 24}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\" class=\"form-input\" style=\"width: 150px; padding: 0.25rem 0.5rem; font-size: 0.9rem;\" maxlength=\"100\" required> <button type=\"submit\" class=\"btn\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\">Save</button> <button type=\"button\" class=\"btn btn-secondary\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\" hx-get=\"/dashboard/profile/cancel/last-name\" hx-target=\"#last-name-display\" hx-swap=\"outerHTML\">Cancel</button></form></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\" class=\"form-input\" style=\"width: 150px; padding: 0.25rem 0.5rem; font-size: 0.9rem;\" maxlength=\"100\" required> <button type=\"submit\" class=\"btn\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\">Save</button> <button type=\"button\" class=\"btn btn-secondary\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\" hx-get=\"/dashboard/profile/cancel/last-name\" hx-target=\"#last-name-display\" hx-swap=\"outerHTML\">Cancel</button></form>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = FieldError(errorMessage).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -94,8 +112,9 @@ func LastNameEditForm(currentValue string) templ.Component {
 	})
 }
 
-// BioEditForm renders an inline edit form for bio
-func BioEditForm(currentValue string) templ.Component {
+// BioEditForm renders an inline edit form for bio, with the error of a
+// rejected update
+func BioEditForm(currentValue, errorMessage string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -116,20 +135,28 @@ func BioEditForm(currentValue string) templ.Component {
 			templ_7745c5c3_Var5 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<div id=\"bio-display\"><form hx-post=\"/dashboard/profile/update/bio\" hx-target=\"#bio-display\" hx-swap=\"outerHTML\" style=\"display: block;\"><textarea name=\"bio\" class=\"form-input\" style=\"width: 100%; min-height: 80px; padding: 0.5rem; font-size: 0.9rem; resize: vertical;\" maxlength=\"1000\" placeholder=\"Tell us about yourself...\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<div id=\"bio-display\"><form hx-post=\"/dashboard/profile/update/bio\" hx-target=\"#bio-display\" hx-swap=\"outerHTML\" style=\"display: block;\"><textarea name=\"bio\" class=\"form-input\" style=\"width: 100%; min-height: 80px; padding: 0.5rem; font-size: 0.9rem; resize: vertical;\" maxlength=\"1000\" placeholder=\"Tell us about yourself...\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var6 string
 		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(currentValue)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/profile_edit.templ`, Line: 84, Col: 18}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/profile_edit.templ`, Line: 89, Col: 18}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</textarea><div style=\"margin-top: 0.5rem; display: flex; gap: 0.5rem;\"><button type=\"submit\" class=\"btn\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\">Save</button> <button type=\"button\" class=\"btn btn-secondary\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\" hx-get=\"/dashboard/profile/cancel/bio\" hx-target=\"#bio-display\" hx-swap=\"outerHTML\">Cancel</button></div></form></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</textarea><div style=\"margin-top: 0.5rem; display: flex; gap: 0.5rem;\"><button type=\"submit\" class=\"btn\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\">Save</button> <button type=\"button\" class=\"btn btn-secondary\" style=\"padding: 0.25rem 0.5rem; font-size: 0.8rem;\" hx-get=\"/dashboard/profile/cancel/bio\" hx-target=\"#bio-display\" hx-swap=\"outerHTML\">Cancel</button></div></form>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = FieldError(errorMessage).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -159,7 +186,7 @@ func FirstNameDisplay(value string) templ.Component {
 			templ_7745c5c3_Var7 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<span class=\"info-value\" id=\"first-name-display\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<span class=\"info-value\" id=\"first-name-display\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -167,19 +194,19 @@ func FirstNameDisplay(value string) templ.Component {
 			var templ_7745c5c3_Var8 string
 			templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(value)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/profile_edit.templ`, Line: 106, Col: 10}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/profile_edit.templ`, Line: 112, Col: 10}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		} else {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<em>Not set</em>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<em>Not set</em>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</span> <button class=\"edit-btn\" hx-get=\"/dashboard/profile/edit/first-name\" hx-target=\"#first-name-display\" hx-swap=\"outerHTML\">Edit</button>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</span> <button class=\"edit-btn\" hx-get=\"/dashboard/profile/edit/first-name\" hx-target=\"#first-name-display\" hx-swap=\"outerHTML\">Edit</button>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -209,7 +236,7 @@ func LastNameDisplay(value string) templ.Component {
 			templ_7745c5c3_Var9 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<span class=\"info-value\" id=\"last-name-display\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<span class=\"info-value\" id=\"last-name-display\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -217,19 +244,19 @@ func LastNameDisplay(value string) templ.Component {
 			var templ_7745c5c3_Var10 string
 			templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(value)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/profile_edit.templ`, Line: 125, Col: 10}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/profile_edit.templ`, Line: 131, Col: 10}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		} else {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<em>Not set</em>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<em>Not set</em>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</span> <button class=\"edit-btn\" hx-get=\"/dashboard/profile/edit/last-name\" hx-target=\"#last-name-display\" hx-swap=\"outerHTML\">Edit</button>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "</span> <button class=\"edit-btn\" hx-get=\"/dashboard/profile/edit/last-name\" hx-target=\"#last-name-display\" hx-swap=\"outerHTML\">Edit</button>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -259,7 +286,7 @@ func BioDisplay(value string) templ.Component {
 			templ_7745c5c3_Var11 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<span class=\"info-value\" id=\"bio-display\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "<span class=\"info-value\" id=\"bio-display\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -267,19 +294,19 @@ func BioDisplay(value string) templ.Component {
 			var templ_7745c5c3_Var12 string
 			templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(value)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/profile_edit.templ`, Line: 144, Col: 10}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/profile_edit.templ`, Line: 150, Col: 10}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		} else {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<em>Not set</em>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "<em>Not set</em>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "</span> <button class=\"edit-btn\" hx-get=\"/dashboard/profile/edit/bio\" hx-target=\"#bio-display\" hx-swap=\"outerHTML\">Edit</button>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "</span> <button class=\"edit-btn\" hx-get=\"/dashboard/profile/edit/bio\" hx-target=\"#bio-display\" hx-swap=\"outerHTML\">Edit</button>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
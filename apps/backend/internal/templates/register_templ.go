@@ -72,7 +72,7 @@ func RegisterForm(errorMessage string) templ.Component {
 			var templ_7745c5c3_Var3 string
 			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(errorMessage)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/register.templ`, Line: 15, Col: 18}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/register.templ`, Line: 15, Col: 18}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 			if templ_7745c5c3_Err != nil {
@@ -83,7 +83,20 @@ func RegisterForm(errorMessage string) templ.Component {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<form hx-post=\"/auth/register\" hx-target=\"#register-form-container\" hx-swap=\"outerHTML\" hx-indicator=\"#register-spinner\" id=\"register-form-container\"><div class=\"form-group\"><label for=\"email\" class=\"form-label\">Email</label> <input type=\"email\" id=\"email\" name=\"email\" class=\"form-input\" required autocomplete=\"email\"></div><div class=\"form-group\"><label for=\"username\" class=\"form-label\">Username</label> <input type=\"text\" id=\"username\" name=\"username\" class=\"form-input\" required autocomplete=\"username\" pattern=\"[a-zA-Z0-9_-]{3,30}\" title=\"Username must be 3-30 characters and contain only letters, numbers, underscores, and hyphens\"></div><div class=\"form-group\"><label for=\"password\" class=\"form-label\">Password</label> <input type=\"password\" id=\"password\" name=\"password\" class=\"form-input\" required autocomplete=\"new-password\" minlength=\"8\"></div><div class=\"form-group\"><label for=\"confirm_password\" class=\"form-label\">Confirm Password</label> <input type=\"password\" id=\"confirm_password\" name=\"confirm_password\" class=\"form-input\" required autocomplete=\"new-password\" minlength=\"8\"></div><div class=\"form-group\"><button type=\"submit\" class=\"btn\"><span id=\"register-spinner\" class=\"htmx-indicator\">Creating account...</span> <span class=\"htmx-indicator-hide\">Create Account</span></button></div></form><div class=\"text-center mt-2\"><p>Already have an account? <a href=\"/login\" class=\"link\">Login here</a></p></div></div><style>\r\n\t\t.htmx-indicator {\r\n\t\t\tdisplay: none;\r\n\t\t}\r\n\t\t\r\n\t\t.htmx-request .htmx-indicator {\r\n\t\t\tdisplay: inline;\r\n\t\t}\r\n\t\t\r\n\t\t.htmx-request .htmx-indicator-hide {\r\n\t\t\tdisplay: none;\r\n\t\t}\r\n\t</style>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<form hx-post=\"/auth/register\" hx-target=\"#register-form-container\" hx-swap=\"outerHTML\" hx-indicator=\"#register-spinner\" id=\"register-form-container\"><div class=\"form-group\"><label for=\"email\" class=\"form-label\">Email</label> <input type=\"email\" id=\"email\" name=\"email\" class=\"form-input\" required autocomplete=\"email\"></div><div class=\"form-group\"><label for=\"username\" class=\"form-label\">Username</label> <input type=\"text\" id=\"username\" name=\"username\" class=\"form-input\" required autocomplete=\"username\" pattern=\"[a-zA-Z0-9_-]{3,30}\" title=\"Username must be 3-30 characters and contain only letters, numbers, underscores, and hyphens\"></div><div class=\"form-group\"><label for=\"password\" class=\"form-label\">Password</label> <input type=\"password\" id=\"password\" name=\"password\" class=\"form-input\" required autocomplete=\"new-password\" minlength=\"8\"></div><div class=\"form-group\"><label for=\"confirm_password\" class=\"form-label\">Confirm Password</label> <input type=\"password\" id=\"confirm_password\" name=\"confirm_password\" class=\"form-input\" required autocomplete=\"new-password\" minlength=\"8\"></div><div class=\"form-group\"><button type=\"submit\" class=\"btn\"><span id=\"register-spinner\" class=\"htmx-indicator\">Creating account...</span> <span class=\"htmx-indicator-hide\">Create Account</span></button></div></form><div class=\"text-center mt-2\"><p>Already have an account? <a href=\"/login\" class=\"link\">Login here</a></p></div></div><style nonce=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 string
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(templ.GetNonce(ctx))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/register.templ`, Line: 91, Col: 35}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\">\n\t\t.htmx-indicator {\n\t\t\tdisplay: none;\n\t\t}\n\t\t\n\t\t.htmx-request .htmx-indicator {\n\t\t\tdisplay: inline;\n\t\t}\n\t\t\n\t\t.htmx-request .htmx-indicator-hide {\n\t\t\tdisplay: none;\n\t\t}\n\t</style>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -91,8 +104,9 @@ func RegisterForm(errorMessage string) templ.Component {
 	})
 }
 
-// RegisterFormError renders the registration form with an error message
-func RegisterFormError(errorMessage string) templ.Component {
+// RegisterFormError renders the registration form with an error message and
+// the errors of individual fields
+func RegisterFormError(errorMessage string, fieldErrors map[string]string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -108,25 +122,57 @@ func RegisterFormError(errorMessage string) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var4 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var4 == nil {
-			templ_7745c5c3_Var4 = templ.NopComponent
+		templ_7745c5c3_Var5 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var5 == nil {
+			templ_7745c5c3_Var5 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<div id=\"register-form-container\"><div class=\"alert alert-error\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<div id=\"register-form-container\"><div class=\"alert alert-error\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var6 string
+		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(errorMessage)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/register.templ`, Line: 111, Col: 17}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</div><form hx-post=\"/auth/register\" hx-target=\"#register-form-container\" hx-swap=\"outerHTML\" hx-indicator=\"#register-spinner\"><div class=\"form-group\"><label for=\"email\" class=\"form-label\">Email</label> <input type=\"email\" id=\"email\" name=\"email\" class=\"form-input\" required autocomplete=\"email\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = FieldError(fieldErrors["email"]).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</div><div class=\"form-group\"><label for=\"username\" class=\"form-label\">Username</label> <input type=\"text\" id=\"username\" name=\"username\" class=\"form-input\" required autocomplete=\"username\" pattern=\"[a-zA-Z0-9_-]{3,30}\" title=\"Username must be 3-30 characters and contain only letters, numbers, underscores, and hyphens\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = FieldError(fieldErrors["username"]).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</div><div class=\"form-group\"><label for=\"password\" class=\"form-label\">Password</label> <input type=\"password\" id=\"password\" name=\"password\" class=\"form-input\" required autocomplete=\"new-password\" minlength=\"8\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = FieldError(fieldErrors["password"]).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var5 string
-		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(errorMessage)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</div><div class=\"form-group\"><label for=\"confirm_password\" class=\"form-label\">Confirm Password</label> <input type=\"password\" id=\"confirm_password\" name=\"confirm_password\" class=\"form-input\" required autocomplete=\"new-password\" minlength=\"8\">")
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/register.templ`, Line: 110, Col: 17}
+			return templ_7745c5c3_Err
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+		templ_7745c5c3_Err = FieldError(fieldErrors["confirm_password"]).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</div><form hx-post=\"/auth/register\" hx-target=\"#register-form-container\" hx-swap=\"outerHTML\" hx-indicator=\"#register-spinner\"><div class=\"form-group\"><label for=\"email\" class=\"form-label\">Email</label> <input type=\"email\" id=\"email\" name=\"email\" class=\"form-input\" required autocomplete=\"email\"></div><div class=\"form-group\"><label for=\"username\" class=\"form-label\">Username</label> <input type=\"text\" id=\"username\" name=\"username\" class=\"form-input\" required autocomplete=\"username\" pattern=\"[a-zA-Z0-9_-]{3,30}\" title=\"Username must be 3-30 characters and contain only letters, numbers, underscores, and hyphens\"></div><div class=\"form-group\"><label for=\"password\" class=\"form-label\">Password</label> <input type=\"password\" id=\"password\" name=\"password\" class=\"form-input\" required autocomplete=\"new-password\" minlength=\"8\"></div><div class=\"form-group\"><label for=\"confirm_password\" class=\"form-label\">Confirm Password</label> <input type=\"password\" id=\"confirm_password\" name=\"confirm_password\" class=\"form-input\" required autocomplete=\"new-password\" minlength=\"8\"></div><div class=\"form-group\"><button type=\"submit\" class=\"btn\"><span id=\"register-spinner\" class=\"htmx-indicator\">Creating account...</span> <span class=\"htmx-indicator-hide\">Create Account</span></button></div></form><div class=\"text-center mt-2\"><p>Already have an account? <a href=\"/login\" class=\"link\">Login here</a></p></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</div><div class=\"form-group\"><button type=\"submit\" class=\"btn\"><span id=\"register-spinner\" class=\"htmx-indicator\">Creating account...</span> <span class=\"htmx-indicator-hide\">Create Account</span></button></div></form><div class=\"text-center mt-2\"><p>Already have an account? <a href=\"/login\" class=\"link\">Login here</a></p></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -151,12 +197,25 @@ func RegisterSuccess() templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var6 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var6 == nil {
-			templ_7745c5c3_Var6 = templ.NopComponent
+		templ_7745c5c3_Var7 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var7 == nil {
+			templ_7745c5c3_Var7 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<div id=\"register-form-container\"><div class=\"alert alert-success\">Registration successful! Redirecting to dashboard...</div></div><script>\r\n\t\tsetTimeout(function() {\r\n\t\t\twindow.location.href = '/dashboard';\r\n\t\t}, 1500);\r\n\t</script>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<div id=\"register-form-container\"><div class=\"alert alert-success\">Registration successful! Redirecting to dashboard...</div></div><script nonce=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var8 string
+		templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(templ.GetNonce(ctx))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/register.templ`, Line: 198, Col: 36}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "\">\n\t\tsetTimeout(function() {\n\t\t\twindow.location.href = '/dashboard';\n\t\t}, 1500);\n\t</script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,243 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter sends finished spans to a backend
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Processor receives spans as they end
+type Processor interface {
+	OnEnd(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+// SimpleProcessor exports each span synchronously as it ends. It suits
+// tests and local files; network exporters should use a BatchProcessor.
+type SimpleProcessor struct {
+	exporter Exporter
+}
+
+// NewSimpleProcessor creates a synchronous processor
+func NewSimpleProcessor(exporter Exporter) *SimpleProcessor {
+	return &SimpleProcessor{exporter: exporter}
+}
+
+// OnEnd exports the span
+func (p *SimpleProcessor) OnEnd(span SpanData) {
+	if err := p.exporter.ExportSpans(context.Background(), []SpanData{span}); err != nil {
+		slog.Warn("Failed to export span", "error", err)
+	}
+}
+
+// Shutdown stops the exporter
+func (p *SimpleProcessor) Shutdown(ctx context.Context) error {
+	return p.exporter.Shutdown(ctx)
+}
+
+// Batch processor defaults
+const (
+	DefaultBatchSize     = 512
+	DefaultQueueSize     = 2048
+	DefaultFlushInterval = 5 * time.Second
+	DefaultExportTimeout = 10 * time.Second
+)
+
+// BatchProcessor queues spans and exports them in batches from a background
+// goroutine, so request handling never waits on the exporter. Spans are
+// dropped rather than blocking when the queue is full.
+type BatchProcessor struct {
+	exporter      Exporter
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan SpanData
+	stop          chan struct{}
+	done          chan struct{}
+	stopOnce      sync.Once
+	stopped       atomic.Bool
+	dropped       atomic.Int64
+}
+
+// NewBatchProcessor creates a batch processor with the default sizes and
+// starts its export goroutine
+func NewBatchProcessor(exporter Exporter) *BatchProcessor {
+	p := &BatchProcessor{
+		exporter:      exporter,
+		batchSize:     DefaultBatchSize,
+		flushInterval: DefaultFlushInterval,
+		queue:         make(chan SpanData, DefaultQueueSize),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// OnEnd queues the span for export
+func (p *BatchProcessor) OnEnd(span SpanData) {
+	if p.stopped.Load() {
+		return
+	}
+	select {
+	case p.queue <- span:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of spans dropped because the queue was full
+func (p *BatchProcessor) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Shutdown exports queued spans and stops the exporter
+func (p *BatchProcessor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		p.stopped.Store(true)
+		close(p.stop)
+	})
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.exporter.Shutdown(ctx)
+}
+
+// run collects spans into batches until stopped
+func (p *BatchProcessor) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, p.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultExportTimeout)
+		defer cancel()
+		if err := p.exporter.ExportSpans(ctx, batch); err != nil {
+			slog.Warn("Failed to export spans", "spans", len(batch), "error", err)
+		}
+		batch = make([]SpanData, 0, p.batchSize)
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.stop:
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// WriterExporter writes spans as JSON lines, for offline testing and
+// debugging without a collector
+type WriterExporter struct {
+	mutex  sync.Mutex
+	w      io.Writer
+	closer io.Closer // Set when the exporter owns the writer
+}
+
+// NewWriterExporter creates an exporter writing to w
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+// NewFileExporter creates an exporter that owns and closes the given file
+func NewFileExporter(f io.WriteCloser) *WriterExporter {
+	return &WriterExporter{w: f, closer: f}
+}
+
+// writerSpan is the JSON line representation of a span
+type writerSpan struct {
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Name         string         `json:"name"`
+	Kind         string         `json:"kind"`
+	Start        time.Time      `json:"start"`
+	DurationMs   float64        `json:"duration_ms"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// kindNames maps span kinds to their lowercase names
+var kindNames = map[SpanKind]string{
+	KindInternal: "internal",
+	KindServer:   "server",
+	KindClient:   "client",
+}
+
+// ExportSpans writes one line per span
+func (e *WriterExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	encoder := json.NewEncoder(e.w)
+	for _, span := range spans {
+		line := writerSpan{
+			TraceID:    span.Context.TraceID.String(),
+			SpanID:     span.Context.SpanID.String(),
+			Name:       span.Name,
+			Kind:       kindNames[span.Kind],
+			Start:      span.Start.UTC(),
+			DurationMs: float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+			Error:      span.Error,
+		}
+		if span.Parent.IsValid() {
+			line.ParentSpanID = span.Parent.String()
+		}
+		if len(span.Attributes) > 0 {
+			line.Attributes = make(map[string]any, len(span.Attributes))
+			for _, attr := range span.Attributes {
+				line.Attributes[attr.Key] = attr.Value
+			}
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown closes the writer if the exporter owns it
+func (e *WriterExporter) Shutdown(ctx context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.closer == nil {
+		return nil
+	}
+	err := e.closer.Close()
+	e.closer = nil
+	e.w = io.Discard
+	return err
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Trace context header names
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// flagSampled is the sampled bit of the traceparent trace flags
+const flagSampled = 0x01
+
+// Format encodes a span context as a traceparent header value
+func Format(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// Parse decodes a traceparent header value. Unknown future versions are
+// accepted as long as their first four fields are well formed, as the
+// specification requires.
+func Parse(value string) (SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	parts := strings.Split(value, "-")
+	if len(parts) < 4 {
+		return SpanContext{}, false
+	}
+
+	version, ok := decodeHex(parts[0], 1)
+	if !ok || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	traceID, ok := decodeHex(parts[1], len(sc.TraceID))
+	if !ok {
+		return SpanContext{}, false
+	}
+	spanID, ok := decodeHex(parts[2], len(sc.SpanID))
+	if !ok {
+		return SpanContext{}, false
+	}
+	flags, ok := decodeHex(parts[3], 1)
+	if !ok {
+		return SpanContext{}, false
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&flagSampled != 0
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// decodeHex decodes a lowercase hex field of exactly n bytes
+func decodeHex(field string, n int) ([]byte, bool) {
+	if len(field) != 2*n || strings.ToLower(field) != field {
+		return nil, false
+	}
+	b, err := hex.DecodeString(field)
+	return b, err == nil
+}
+
+// Extract returns a context continuing the trace in the request headers.
+// Missing or malformed headers leave the context unchanged, so a new trace
+// is started.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, ok := Parse(header.Get(TraceparentHeader))
+	if !ok {
+		return ctx
+	}
+	return ContextWithRemoteParent(ctx, sc)
+}
+
+// Inject writes the current span into outgoing request headers
+func Inject(ctx context.Context, header http.Header) {
+	sc := parentFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(TraceparentHeader, Format(sc))
+}
@@ -0,0 +1,300 @@
+// Package tracing records request spans and propagates trace context using
+// the W3C Trace Context format. Finished spans are handed to an Exporter,
+// such as an OTLP collector or a JSON lines file.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the lowercase hex encoding
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the ID is not all zeros
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the lowercase hex encoding
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the ID is not all zeros
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext identifies a span and carries its sampling decision
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool // Extracted from an incoming request
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind describes the relationship of a span to its callers, using the
+// OpenTelemetry numbering
+type SpanKind int
+
+// Span kinds
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Attribute is a key-value pair attached to a span
+type Attribute struct {
+	Key   string
+	Value any // string, bool, int, int64 or float64
+}
+
+// String creates a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int creates an integer attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Bool creates a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData is the immutable record of a finished span passed to exporters
+type SpanData struct {
+	Name       string
+	Kind       SpanKind
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Error      string // Empty when the span succeeded
+}
+
+// Span is an operation being timed. A nil *Span is valid and does nothing,
+// so call sites never need to check whether tracing is enabled.
+type Span struct {
+	tracer *Tracer
+	data   SpanData
+	ended  atomic.Bool
+	mutex  sync.Mutex
+}
+
+// SpanContext returns the span's identity
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil || !s.data.Context.Sampled {
+		return
+	}
+	s.mutex.Lock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+	s.mutex.Unlock()
+}
+
+// RecordError marks the span as failed. Nil errors are ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.data.Error = err.Error()
+	s.mutex.Unlock()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil || !s.ended.CompareAndSwap(false, true) {
+		return
+	}
+	if !s.data.Context.Sampled || s.tracer.processor == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	s.data.End = time.Now()
+	data := s.data
+	data.Attributes = append([]Attribute(nil), s.data.Attributes...)
+	s.mutex.Unlock()
+
+	s.tracer.processor.OnEnd(data)
+}
+
+// Tracer creates spans and hands finished ones to its processor
+type Tracer struct {
+	processor   Processor
+	sampleRatio float64
+}
+
+// NewTracer creates a tracer that samples new traces with the given ratio
+// (0 to 1) and passes sampled spans to the processor. A nil processor still
+// propagates trace context but records nothing.
+func NewTracer(processor Processor, sampleRatio float64) *Tracer {
+	return &Tracer{processor: processor, sampleRatio: sampleRatio}
+}
+
+// Shutdown flushes pending spans and stops the processor
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil || t.processor == nil {
+		return nil
+	}
+	return t.processor.Shutdown(ctx)
+}
+
+// Start begins a span as a child of the span in ctx, or of a remote parent
+// set with ContextWithRemoteParent, or as the root of a new trace
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	span := &Span{tracer: t}
+	span.data.Name = name
+	span.data.Kind = kind
+	span.data.Start = time.Now()
+
+	parent := parentFromContext(ctx)
+	if parent.IsValid() {
+		span.data.Context.TraceID = parent.TraceID
+		span.data.Context.Sampled = parent.Sampled
+		span.data.Parent = parent.SpanID
+	} else {
+		span.data.Context.TraceID = newTraceID()
+		span.data.Context.Sampled = t.shouldSample(span.data.Context.TraceID)
+	}
+	span.data.Context.SpanID = newSpanID()
+
+	if span.data.Context.Sampled {
+		span.data.Attributes = attrs
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// shouldSample makes a deterministic decision from the trace ID so that
+// every service sampling with the same ratio agrees
+func (t *Tracer) shouldSample(id TraceID) bool {
+	switch {
+	case t.sampleRatio >= 1:
+		return true
+	case t.sampleRatio <= 0:
+		return false
+	}
+	bound := uint64(t.sampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
+
+// Context keys
+type spanKey struct{}
+type remoteParentKey struct{}
+
+// SpanFromContext returns the current span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent returns a context whose next span continues the
+// trace described by sc, typically one extracted from request headers
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteParentKey{}, sc)
+}
+
+// parentFromContext returns the local span or remote parent to continue
+func parentFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteParentKey{}).(SpanContext)
+	return sc
+}
+
+// newTraceID generates a random trace ID
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// newSpanID generates a random span ID
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// defaultTracer is used by the package-level Start function
+var defaultTracer atomic.Pointer[Tracer]
+
+func init() {
+	defaultTracer.Store(NewTracer(nil, 1))
+}
+
+// SetDefault sets the tracer used by Start
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+// Default returns the tracer used by Start
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// Start begins an internal span with the default tracer
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return Default().Start(ctx, name, KindInternal, attrs...)
+}
+
+// Do runs fn in an internal span, recording its error
+func Do(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, span := Start(ctx, name)
+	defer span.End()
+
+	err := fn(ctx)
+	span.RecordError(err)
+	return err
+}
+
+// Call runs fn in an internal span, recording its error and returning its
+// result. It suits calls that do not take a context, such as repository
+// methods.
+func Call[T any](ctx context.Context, name string, fn func() (T, error)) (T, error) {
+	_, span := Start(ctx, name)
+	defer span.End()
+
+	result, err := fn()
+	span.RecordError(err)
+	return result, err
+}
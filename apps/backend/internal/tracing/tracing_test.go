@@ -0,0 +1,246 @@
+package tracing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingExporter keeps exported spans in memory
+type recordingExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(ctx context.Context) error { return nil }
+
+// Test parsing and formatting traceparent headers
+func TestTraceparent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, ok := Parse(valid)
+	if !ok {
+		t.Fatal("Expected valid traceparent to parse")
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Errorf("Unexpected span context %+v", sc)
+	}
+	if got := Format(sc); got != valid {
+		t.Errorf("Format() = %q, want %q", got, valid)
+	}
+
+	// Future versions may append fields
+	if _, ok := Parse("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); !ok {
+		t.Error("Expected future version with extra fields to parse")
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	}
+	for _, value := range invalid {
+		if _, ok := Parse(value); ok {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+// Test that spans nest and continue an incoming trace
+func TestSpanPropagation(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(NewSimpleProcessor(exporter), 1)
+
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := Extract(context.Background(), header)
+
+	ctx, root := tracer.Start(ctx, "GET /", KindServer, String("http.method", "GET"))
+	childCtx, child := tracer.Start(ctx, "child", KindInternal)
+	child.RecordError(errors.New("boom"))
+
+	outgoing := http.Header{}
+	Inject(childCtx, outgoing)
+	if want := Format(child.SpanContext()); outgoing.Get(TraceparentHeader) != want {
+		t.Errorf("Injected %q, want %q", outgoing.Get(TraceparentHeader), want)
+	}
+
+	child.End()
+	child.End() // Second call is ignored
+	root.End()
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 exported spans, got %d", len(exporter.spans))
+	}
+	childData, rootData := exporter.spans[0], exporter.spans[1]
+	if rootData.Context.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Root span did not continue the incoming trace: %s", rootData.Context.TraceID)
+	}
+	if rootData.Parent.String() != "00f067aa0ba902b7" {
+		t.Errorf("Root span parent = %s, want remote span", rootData.Parent)
+	}
+	if childData.Context.TraceID != rootData.Context.TraceID || childData.Parent != rootData.Context.SpanID {
+		t.Error("Child span is not linked to the root span")
+	}
+	if childData.Error != "boom" {
+		t.Errorf("Child error = %q, want boom", childData.Error)
+	}
+	if len(rootData.Attributes) != 1 || rootData.Attributes[0].Key != "http.method" {
+		t.Errorf("Unexpected root attributes %+v", rootData.Attributes)
+	}
+}
+
+// Test that unsampled traces are propagated but not exported
+func TestSampling(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(NewSimpleProcessor(exporter), 0)
+
+	ctx, span := tracer.Start(context.Background(), "unsampled", KindServer)
+	if !span.SpanContext().IsValid() || span.SpanContext().Sampled {
+		t.Error("Expected a valid, unsampled span context")
+	}
+	header := http.Header{}
+	Inject(ctx, header)
+	if !strings.HasSuffix(header.Get(TraceparentHeader), "-00") {
+		t.Errorf("Expected unsampled flag in %q", header.Get(TraceparentHeader))
+	}
+	span.End()
+
+	// An incoming sampled decision is honoured regardless of the ratio
+	sc, _ := Parse("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, sampled := tracer.Start(ContextWithRemoteParent(context.Background(), sc), "sampled", KindServer)
+	sampled.End()
+
+	if len(exporter.spans) != 1 || exporter.spans[0].Name != "sampled" {
+		t.Errorf("Expected only the sampled span to be exported, got %+v", exporter.spans)
+	}
+}
+
+// Test the JSON lines exporter through a batch processor
+func TestWriterExporter(t *testing.T) {
+	var buf bytes.Buffer
+	processor := NewBatchProcessor(NewWriterExporter(&buf))
+	tracer := NewTracer(processor, 1)
+
+	ctx, parent := tracer.Start(context.Background(), "parent", KindServer)
+	_, child := tracer.Start(ctx, "child", KindInternal, Int("rows", 3))
+	child.End()
+	parent.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if lines[0]["name"] != "child" || lines[0]["parent_span_id"] != lines[1]["span_id"] {
+		t.Errorf("Unexpected child line %v", lines[0])
+	}
+	if attrs, _ := lines[0]["attributes"].(map[string]any); attrs["rows"] != float64(3) {
+		t.Errorf("Unexpected child attributes %v", lines[0]["attributes"])
+	}
+	if lines[1]["kind"] != "server" {
+		t.Errorf("Expected server kind, got %v", lines[1]["kind"])
+	}
+}
+
+// Test the OTLP/HTTP JSON payload sent to a collector
+func TestOTLPExporter(t *testing.T) {
+	var body []byte
+	var header http.Header
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL, "test-service", ParseHeaders("x-api-key=secret"))
+	tracer := NewTracer(NewSimpleProcessor(exporter), 1)
+	_, span := tracer.Start(context.Background(), "operation", KindClient, Bool("cached", true))
+	span.RecordError(errors.New("failed"))
+	span.End()
+
+	if header.Get("Content-Type") != "application/json" || header.Get("X-Api-Key") != "secret" {
+		t.Errorf("Unexpected export headers %v", header)
+	}
+
+	var payload struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []otlpKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID           string         `json:"traceId"`
+					SpanID            string         `json:"spanId"`
+					Name              string         `json:"name"`
+					Kind              int            `json:"kind"`
+					StartTimeUnixNano string         `json:"startTimeUnixNano"`
+					Attributes        []otlpKeyValue `json:"attributes"`
+					Status            otlpStatus     `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Invalid OTLP payload: %v", err)
+	}
+	if len(payload.ResourceSpans) != 1 || len(payload.ResourceSpans[0].ScopeSpans) != 1 || len(payload.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("Unexpected payload structure: %s", body)
+	}
+	resource := payload.ResourceSpans[0].Resource.Attributes
+	if len(resource) != 1 || resource[0].Key != "service.name" || *resource[0].Value.StringValue != "test-service" {
+		t.Errorf("Unexpected resource attributes: %s", body)
+	}
+	got := payload.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if got.TraceID != span.SpanContext().TraceID.String() || got.SpanID != span.SpanContext().SpanID.String() {
+		t.Errorf("Unexpected IDs %s/%s", got.TraceID, got.SpanID)
+	}
+	if got.Name != "operation" || got.Kind != int(KindClient) || got.StartTimeUnixNano == "" {
+		t.Errorf("Unexpected span %+v", got)
+	}
+	if len(got.Attributes) != 1 || got.Attributes[0].Value.BoolValue == nil || !*got.Attributes[0].Value.BoolValue {
+		t.Errorf("Unexpected span attributes: %s", body)
+	}
+	if got.Status.Code != otlpStatusError || got.Status.Message != "failed" {
+		t.Errorf("Unexpected status %+v", got.Status)
+	}
+}
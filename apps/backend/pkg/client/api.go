@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// envelope is the body of a successful /api/v1 response
+type envelope[T any] struct {
+	Data       T `json:"data"`
+	Pagination struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"pagination"`
+}
+
+// authResponse is the body of a successful login or registration
+type authResponse struct {
+	Data struct {
+		User User `json:"user"`
+	} `json:"data"`
+}
+
+// Register creates an account and authenticates the client as its user. The
+// user returned has no timestamps; Me returns them.
+func (c *Client) Register(ctx context.Context, request RegisterRequest) (*User, error) {
+	return c.authenticate(ctx, "/api/auth/register", request)
+}
+
+// Login authenticates the client as the user with the given credentials
+func (c *Client) Login(ctx context.Context, email, password string) (*User, error) {
+	return c.authenticate(ctx, "/api/auth/login", map[string]string{"email": email, "password": password})
+}
+
+// authenticate sends credentials and keeps the session token issued for them
+func (c *Client) authenticate(ctx context.Context, path string, credentials any) (*User, error) {
+	var response authResponse
+	resp, err := c.do(ctx, http.MethodPost, path, nil, credentials, &response)
+	if err != nil {
+		return nil, err
+	}
+	c.SetToken(sessionToken(resp))
+	return &response.Data.User, nil
+}
+
+// Logout ends the client's session. The token is forgotten even when the
+// server cannot be reached.
+func (c *Client) Logout(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/auth/logout", nil, nil, nil)
+	c.SetToken("")
+	return err
+}
+
+// Me returns the authenticated user
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var response envelope[User]
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/me", nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// UpdateProfile changes the authenticated user's profile and returns the
+// updated user
+func (c *Client) UpdateProfile(ctx context.Context, update ProfileUpdate) (*User, error) {
+	var response envelope[User]
+	if _, err := c.do(ctx, http.MethodPatch, "/api/v1/me", nil, update, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// CreateRegistration registers the authenticated user for a competition. A
+// cancelled registration is reopened.
+func (c *Client) CreateRegistration(ctx context.Context, competitionID string) (*Registration, error) {
+	var response envelope[Registration]
+	request := map[string]string{"competition_id": competitionID}
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/registrations", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// CancelRegistration cancels one of the authenticated user's registrations
+func (c *Client) CancelRegistration(ctx context.Context, id string) (*Registration, error) {
+	var response envelope[Registration]
+	if _, err := c.do(ctx, http.MethodDelete, "/api/v1/registrations/"+url.PathEscape(id), nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// ListMyRegistrations returns a page of the authenticated user's
+// registrations, newest first
+func (c *Client) ListMyRegistrations(ctx context.Context, options PageOptions) (*Page[Registration], error) {
+	return list[Registration](ctx, c, "/api/v1/me/registrations", options)
+}
+
+// MyRegistrations iterates over the authenticated user's registrations,
+// newest first, fetching pages as needed
+func (c *Client) MyRegistrations(ctx context.Context, options PageOptions) iter.Seq2[Registration, error] {
+	return all[Registration](ctx, c, "/api/v1/me/registrations", options)
+}
+
+// ListCompetitions returns a page of competitions, soonest first
+func (c *Client) ListCompetitions(ctx context.Context, options PageOptions) (*Page[Competition], error) {
+	return list[Competition](ctx, c, "/api/v1/competitions", options)
+}
+
+// Competitions iterates over competitions, soonest first, fetching pages as
+// needed
+func (c *Client) Competitions(ctx context.Context, options PageOptions) iter.Seq2[Competition, error] {
+	return all[Competition](ctx, c, "/api/v1/competitions", options)
+}
+
+// ListAnnouncements returns a page of published announcements, newest first
+func (c *Client) ListAnnouncements(ctx context.Context, options PageOptions) (*Page[Announcement], error) {
+	return list[Announcement](ctx, c, "/api/v1/announcements", options)
+}
+
+// Announcements iterates over published announcements, newest first,
+// fetching pages as needed
+func (c *Client) Announcements(ctx context.Context, options PageOptions) iter.Seq2[Announcement, error] {
+	return all[Announcement](ctx, c, "/api/v1/announcements", options)
+}
+
+// list fetches one page of a list
+func list[T any](ctx context.Context, c *Client, path string, options PageOptions) (*Page[T], error) {
+	query := url.Values{}
+	if options.Cursor != "" {
+		query.Set("cursor", options.Cursor)
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+
+	var response envelope[[]T]
+	if _, err := c.do(ctx, http.MethodGet, path, query, nil, &response); err != nil {
+		return nil, err
+	}
+	return &Page[T]{Items: response.Data, NextCursor: response.Pagination.NextCursor}, nil
+}
+
+// all iterates over a list from the page options selects. A failed page is
+// yielded as an error, which ends the iteration.
+func all[T any](ctx context.Context, c *Client, path string, options PageOptions) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			page, err := list[T](ctx, c, path, options)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			options.Cursor = page.NextCursor
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Package client is a typed Go client for the Compify JSON API.
+//
+// A Client authenticates with the session token issued by Login or Register,
+// or with a personal access token created on the dashboard and passed to
+// SetToken. Either is sent as a Bearer token, so it needs no cookies or CSRF
+// tokens. Failed requests return an *Error carrying the problem code of the
+// response, which errors.Is matches against the Err variables of this
+// package. Idempotent requests are retried when the server is briefly
+// unavailable.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for a Client's retry settings
+const (
+	DefaultMaxRetries   = 2
+	DefaultRetryBackoff = 200 * time.Millisecond
+)
+
+// maxRetryWait caps the delay a Retry-After header can ask for
+const maxRetryWait = 10 * time.Second
+
+// sessionCookie is the cookie Login and Register return the session token in
+const sessionCookie = "session_token"
+
+// Client calls the Compify API. It is safe for concurrent use.
+type Client struct {
+	baseURL *url.URL
+
+	// HTTPClient sends the requests; http.DefaultClient when nil
+	HTTPClient *http.Client
+
+	// MaxRetries is how many times an idempotent request is retried after a
+	// network error or a 429, 502, 503 or 504 response. RetryBackoff is the
+	// delay before the first retry, doubled for each one after it.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	mu    sync.RWMutex
+	token string
+}
+
+// New returns a client for the API served at baseURL, such as
+// "https://api.compify.example"
+func New(baseURL string) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	return &Client{
+		baseURL:      u,
+		MaxRetries:   DefaultMaxRetries,
+		RetryBackoff: DefaultRetryBackoff,
+	}, nil
+}
+
+// Token returns the session or access token the client authenticates with,
+// "" when it has none
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken sets the token to authenticate with: a session token saved from
+// an earlier Login, or a personal access token. Requests an access token
+// lacks the scope for fail with ErrInsufficientScope.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// do sends a request with a JSON body, when in is not nil, and decodes the
+// JSON response into out, when it is not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	retries := 0
+	if isIdempotent(method) {
+		retries = c.MaxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u.String(), body)
+		if attempt < retries && retryable(resp, err) && ctx.Err() == nil {
+			wait := c.RetryBackoff << attempt
+			if resp != nil {
+				wait = max(wait, retryAfter(resp))
+				resp.Body.Close()
+			}
+			if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return resp, responseError(resp)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return resp, fmt.Errorf("decoding %s %s response: %w", method, path, err)
+			}
+		}
+		return resp, nil
+	}
+}
+
+// send makes one attempt at a request
+func (c *Client) send(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// isIdempotent reports whether repeating a request has no further effect
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether an attempt failed in a way worth retrying
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay a response's Retry-After header asks for
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, maxRetryWait)
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sessionToken returns the session token set by a response, "" when there
+// is none
+func sessionToken(resp *http.Response) string {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookie {
+			return cookie.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"compify-backend/internal/config"
+	"compify-backend/internal/server"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for a real server
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Environment = config.EnvTest
+	ts := httptest.NewServer(server.New(cfg).Handler())
+	t.Cleanup(ts.Close)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+// Test the client against the real server: authentication, profile,
+// registrations, pagination and problem codes
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	if _, err := c.Me(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected ErrUnauthorized before logging in, got %v", err)
+	}
+
+	request := RegisterRequest{Email: "ada@example.com", Username: "ada", Password: "password123", ConfirmPassword: "password123"}
+	user, err := c.Register(ctx, request)
+	if err != nil || user.Username != "ada" || c.Token() == "" {
+		t.Fatalf("Register: %v %+v", err, user)
+	}
+
+	t.Run("problem codes are typed errors", func(t *testing.T) {
+		other, _ := New(c.baseURL.String())
+		_, err := other.Register(ctx, request)
+		var apiErr *Error
+		if !errors.Is(err, ErrEmailTaken) || !errors.As(err, &apiErr) || apiErr.Status != http.StatusConflict || apiErr.FieldErrors()["email"] == "" {
+			t.Errorf("Expected a 409 ErrEmailTaken, got %#v", err)
+		}
+
+		_, err = other.Register(ctx, RegisterRequest{Email: "bob@example.com", Username: "bob", Password: "short", ConfirmPassword: "short"})
+		if !errors.Is(err, ErrValidation) || !errors.Is(err, ErrPasswordTooShort) {
+			t.Errorf("Expected a validation error with a too short password, got %v", err)
+		}
+
+		if _, err := other.Login(ctx, "ada@example.com", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+		}
+		if other.Token() != "" {
+			t.Error("A failed login must not set a token")
+		}
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		name := "Ada"
+		updated, err := c.UpdateProfile(ctx, ProfileUpdate{FirstName: &name})
+		if err != nil || updated.Profile.FirstName != "Ada" {
+			t.Fatalf("UpdateProfile: %v %+v", err, updated)
+		}
+		me, err := c.Me(ctx)
+		if err != nil || me.ID != user.ID || me.Profile.FirstName != "Ada" || me.CreatedAt.IsZero() {
+			t.Errorf("Me: %v %+v", err, me)
+		}
+	})
+
+	t.Run("registrations", func(t *testing.T) {
+		var competitionID string
+		for competition, err := range c.Competitions(ctx, PageOptions{}) {
+			if err != nil {
+				t.Fatalf("Competitions: %v", err)
+			}
+			competitionID = competition.ID
+		}
+
+		registration, err := c.CreateRegistration(ctx, competitionID)
+		if err != nil || registration.Status != RegistrationPending {
+			t.Fatalf("CreateRegistration: %v %+v", err, registration)
+		}
+		if _, err := c.CreateRegistration(ctx, competitionID); !errors.Is(err, ErrRegistrationExists) {
+			t.Errorf("Expected ErrRegistrationExists, got %v", err)
+		}
+		if _, err := c.CreateRegistration(ctx, "missing"); !errors.Is(err, ErrCompetitionNotFound) {
+			t.Errorf("Expected ErrCompetitionNotFound, got %v", err)
+		}
+
+		page, err := c.ListMyRegistrations(ctx, PageOptions{})
+		if err != nil || len(page.Items) != 1 || page.Items[0].ID != registration.ID || page.NextCursor != "" {
+			t.Errorf("ListMyRegistrations: %v %+v", err, page)
+		}
+
+		cancelled, err := c.CancelRegistration(ctx, registration.ID)
+		if err != nil || cancelled.Status != RegistrationCancelled {
+			t.Errorf("CancelRegistration: %v %+v", err, cancelled)
+		}
+	})
+
+	t.Run("iterators fetch every page", func(t *testing.T) {
+		first, err := c.ListAnnouncements(ctx, PageOptions{Limit: 2})
+		if err != nil || len(first.Items) != 2 || first.NextCursor == "" {
+			t.Fatalf("Expected a first page of two, got %v %+v", err, first)
+		}
+
+		var titles []string
+		for announcement, err := range c.Announcements(ctx, PageOptions{Limit: 2}) {
+			if err != nil {
+				t.Fatalf("Announcements: %v", err)
+			}
+			titles = append(titles, announcement.Title)
+		}
+		if len(titles) != 3 || titles[0] != first.Items[0].Title {
+			t.Errorf("Expected all three announcements, got %v", titles)
+		}
+
+		for _, err := range c.Announcements(ctx, PageOptions{Cursor: "bogus"}) {
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Expected ErrInvalidCursor, got %v", err)
+			}
+		}
+	})
+
+	t.Run("logout forgets the session", func(t *testing.T) {
+		token := c.Token()
+		if err := c.Logout(ctx); err != nil || c.Token() != "" {
+			t.Fatalf("Logout: %v", err)
+		}
+		c.SetToken(token)
+		if _, err := c.Me(ctx); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected the old token to be revoked, got %v", err)
+		}
+
+		if _, err := c.Login(ctx, "ada@example.com", "password123"); err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		if me, err := c.Me(ctx); err != nil || me.ID != user.ID {
+			t.Errorf("Me after login: %v", err)
+		}
+	})
+}
+
+// Test that idempotent requests are retried and others are not
+func TestClientRetries(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"id": "u1"}}`))
+	}))
+	defer ts.Close()
+
+	c, _ := New(ts.URL)
+	c.RetryBackoff = time.Millisecond
+
+	if me, err := c.Me(context.Background()); err != nil || me.ID != "u1" || calls.Load() != 3 {
+		t.Errorf("Expected GET to succeed on the third attempt, got %v after %d calls", err, calls.Load())
+	}
+
+	calls.Store(0)
+	_, err := c.CreateRegistration(context.Background(), "comp")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable || apiErr.Detail != "Service Unavailable" || calls.Load() != 1 {
+		t.Errorf("Expected POST to fail without retrying, got %v after %d calls", err, calls.Load())
+	}
+
+	calls.Store(-10)
+	c.MaxRetries = 1
+	if _, err := c.Me(context.Background()); !errors.As(err, &apiErr) || calls.Load() != -8 {
+		t.Errorf("Expected GET to give up after one retry, got %v after %d calls", err, calls.Load())
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error is a failed API request, decoded from the RFC 7807 problem details
+// the API answers with. Code is stable; Detail is for people.
+type Error struct {
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Detail    string       `json:"detail"`
+	RequestID string       `json:"request_id"`
+	Fields    []FieldError `json:"errors"`
+}
+
+// FieldError is the problem with one field of a request
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	message := e.Detail
+	if message == "" {
+		message = http.StatusText(e.Status)
+	}
+	if e.Code != "" {
+		message += " (" + e.Code + ")"
+	}
+	return message
+}
+
+// Is matches errors with the same code, either of the whole problem or of
+// one of its fields, so that errors.Is(err, ErrPasswordTooShort) holds for a
+// validation error that includes a too short password
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t.Code == "" {
+		return false
+	}
+	if e.Code == t.Code {
+		return true
+	}
+	for _, field := range e.Fields {
+		if field.Code == t.Code {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldErrors returns the detail for each field at fault
+func (e *Error) FieldErrors() map[string]string {
+	fields := make(map[string]string, len(e.Fields))
+	for _, field := range e.Fields {
+		if field.Field != "" && fields[field.Field] == "" {
+			fields[field.Field] = field.Detail
+		}
+	}
+	return fields
+}
+
+// Errors the API returns, for matching with errors.Is. The list is not
+// exhaustive; any code can be matched with &Error{Code: code}.
+var (
+	ErrValidation           = &Error{Code: "validation_failed"}
+	ErrInvalidBody          = &Error{Code: "body_invalid"}
+	ErrUnauthorized         = &Error{Code: "unauthorized"}
+	ErrForbidden            = &Error{Code: "forbidden"}
+	ErrInsufficientScope    = &Error{Code: "insufficient_scope"}
+	ErrRateLimited          = &Error{Code: "rate_limited"}
+	ErrInternal             = &Error{Code: "internal_error"}
+	ErrInvalidCredentials   = &Error{Code: "invalid_credentials"}
+	ErrEmailTaken           = &Error{Code: "email_taken"}
+	ErrUsernameTaken        = &Error{Code: "username_taken"}
+	ErrInvalidEmail         = &Error{Code: "email_invalid"}
+	ErrInvalidUsername      = &Error{Code: "username_invalid"}
+	ErrPasswordTooShort     = &Error{Code: "password_too_short"}
+	ErrPasswordsDoNotMatch  = &Error{Code: "passwords_do_not_match"}
+	ErrCompetitionNotFound  = &Error{Code: "competition_not_found"}
+	ErrRegistrationExists   = &Error{Code: "registration_exists"}
+	ErrRegistrationClosed   = &Error{Code: "registration_closed"}
+	ErrRegistrationNotFound = &Error{Code: "registration_not_found"}
+	ErrInvalidCursor        = &Error{Code: "cursor_invalid"}
+)
+
+// responseError reads the error of a failed response. Responses that are not
+// problem details, such as those of a proxy in front of the API, keep their
+// status with the body as detail.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	e := &Error{}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") || json.Unmarshal(body, e) != nil {
+		e = &Error{Detail: strings.TrimSpace(string(body))}
+	}
+	e.Status = resp.StatusCode
+	return e
+}
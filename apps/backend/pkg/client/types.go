@@ -0,0 +1,93 @@
+package client
+
+import "time"
+
+// User is an account with its profile
+type User struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Profile   Profile   `json:"profile"`
+}
+
+// Profile is the public information about a user
+type Profile struct {
+	UserID    string `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Bio       string `json:"bio"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// ProfileUpdate changes the fields of a profile that are not nil
+type ProfileUpdate struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Bio       *string `json:"bio,omitempty"`
+}
+
+// RegisterRequest creates an account
+type RegisterRequest struct {
+	Email           string `json:"email"`
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirm_password"`
+	FirstName       string `json:"first_name,omitempty"`
+	LastName        string `json:"last_name,omitempty"`
+}
+
+// Registration statuses
+const (
+	RegistrationPending   = "pending"
+	RegistrationConfirmed = "confirmed"
+	RegistrationCancelled = "cancelled"
+	RegistrationWaitlist  = "waitlist"
+)
+
+// Registration is a user's registration for a competition
+type Registration struct {
+	ID            string         `json:"id"`
+	UserID        string         `json:"user_id"`
+	CompetitionID string         `json:"competition_id"`
+	Status        string         `json:"status"`
+	RegisteredAt  time.Time      `json:"registered_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	Data          map[string]any `json:"data"`
+}
+
+// Competition is a competition users can register for
+type Competition struct {
+	ID                   string    `json:"id"`
+	Name                 string    `json:"name"`
+	Description          string    `json:"description"`
+	StartsAt             time.Time `json:"starts_at"`
+	EndsAt               time.Time `json:"ends_at"`
+	RegistrationClosesAt time.Time `json:"registration_closes_at"`
+}
+
+// Announcement is a published announcement
+type Announcement struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Priority  string    `json:"priority"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Published bool      `json:"published"`
+}
+
+// PageOptions selects a page of a list. The zero value is the first page at
+// the server's default size.
+type PageOptions struct {
+	Cursor string // NextCursor of the previous page
+	Limit  int    // Page size, at most 100; the server's default when zero
+}
+
+// Page is one page of a list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"compify-backend/pkg/client"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -133,6 +136,11 @@ func verifyEndpoint(baseURL, endpoint string) VerificationResult {
 			if health.Timestamp == "" {
 				result.Issues = append(result.Issues, "Missing timestamp in health response")
 			}
+			for name, check := range health.Checks {
+				if check != "ok" {
+					result.Issues = append(result.Issues, fmt.Sprintf("Check %s failing: %s", name, check))
+				}
+			}
 		}
 	}
 	
@@ -156,6 +164,39 @@ func verifyEndpoint(baseURL, endpoint string) VerificationResult {
 	return result
 }
 
+// verifyAPI checks the JSON API through the API client
+func verifyAPI(baseURL string) VerificationResult {
+	result := VerificationResult{
+		Endpoint: "/api/v1",
+		Issues:   []string{},
+	}
+	
+	printStatus("Testing /api/v1 with the API client...")
+	
+	c, err := client.New(baseURL)
+	if err != nil {
+		result.Issues = append(result.Issues, err.Error())
+		return result
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	
+	start := time.Now()
+	if _, err := c.ListCompetitions(ctx, client.PageOptions{Limit: 1}); err != nil {
+		result.Issues = append(result.Issues, fmt.Sprintf("Listing competitions failed: %v", err))
+	}
+	if _, err := c.ListAnnouncements(ctx, client.PageOptions{Limit: 1}); err != nil {
+		result.Issues = append(result.Issues, fmt.Sprintf("Listing announcements failed: %v", err))
+	}
+	if _, err := c.Me(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		result.Issues = append(result.Issues, fmt.Sprintf("Expected an unauthorized problem without a session, got: %v", err))
+	}
+	result.ResponseTime = time.Since(start)
+	
+	result.Success = len(result.Issues) == 0
+	return result
+}
+
 func verifyBackendDeployment() bool {
 	backendURL := os.Getenv("BACKEND_URL")
 	if backendURL == "" {
@@ -169,6 +210,8 @@ func verifyBackendDeployment() bool {
 	// Test endpoints
 	endpoints := []string{
 		"/health",
+		"/livez",
+		"/readyz",
 		"/auth/login",
 		"/auth/register",
 	}
@@ -188,6 +231,15 @@ func verifyBackendDeployment() bool {
 		}
 	}
 	
+	apiResult := verifyAPI(backendURL)
+	results = append(results, apiResult)
+	if apiResult.Success {
+		printStatus(fmt.Sprintf("✓ %s - OK (%dms)", apiResult.Endpoint, apiResult.ResponseTime.Milliseconds()))
+	} else {
+		printError(fmt.Sprintf("✗ %s - %s", apiResult.Endpoint, strings.Join(apiResult.Issues, ", ")))
+		allTestsPassed = false
+	}
+	
 	// Performance check
 	fmt.Println()
 	printHeader("⚡ Performance Check")